@@ -6,7 +6,11 @@ import (
 	"image/color"
 	"image/png"
 	"os"
+	"slices"
 	"sync"
+	"time"
+
+	pxcolor "pxcli/internal/color"
 )
 
 // Error represents a canvas error with a code and message.
@@ -22,27 +26,53 @@ func (e Error) Error() string {
 	return e.Code + ": " + e.Message
 }
 
-// Canvas stores pixel data for a fixed-width, fixed-height image.
+// Canvas stores a stack of same-sized layers for a fixed-width, fixed-height image,
+// with one cel per layer for every animation frame. Drawing operations target the
+// active layer on the current frame; reads and exports use the composite.
 type Canvas struct {
-	mu     sync.RWMutex
-	width  int
-	height int
-	pixels []color.RGBA
-	dirty  bool
+	mu      sync.RWMutex
+	width   int
+	height  int
+	layers  []*layer
+	active  int
+	frames  []frame
+	frame   int
+	playing bool
+	dirty   bool
+	rec     *recording
+	// indexed canvases store palette indices in their cels; see NewIndexed.
+	indexed bool
+	palette pxcolor.Palette
+	// swatch is the index plus one of the swatch PaintWithSwatch draws with.
+	swatch int
 }
 
-// Snapshot captures a copy of the canvas pixels.
+// Snapshot captures a copy of the canvas layers, frames and selections.
 type Snapshot struct {
 	width  int
 	height int
-	pixels []color.RGBA
+	layers []*layer
+	active int
+	frames []frame
+	frame  int
+	// indexed snapshots hold palette indices, resolved with palette.
+	indexed bool
+	palette pxcolor.Palette
 }
 
 // RenderSnapshot captures a copy of the canvas in RGBA byte form for rendering.
+// Frames is only populated while animation playback is enabled.
 type RenderSnapshot struct {
 	Width  int
 	Height int
 	Pixels []byte
+	Frames []RenderFrame
+}
+
+// RenderFrame is one composited animation frame and how long it is shown.
+type RenderFrame struct {
+	Pixels   []byte
+	Duration time.Duration
 }
 
 // New creates a canvas with the provided dimensions.
@@ -50,8 +80,14 @@ func New(width, height int) (*Canvas, error) {
 	if width <= 0 || height <= 0 {
 		return nil, Error{Code: "invalid_args", Message: "canvas dimensions must be positive"}
 	}
-	pixels := make([]color.RGBA, width*height)
-	return &Canvas{width: width, height: height, pixels: pixels, dirty: true}, nil
+	base := newLayer(defaultLayerName(0), width*height, 1)
+	return &Canvas{
+		width:  width,
+		height: height,
+		layers: []*layer{base},
+		frames: []frame{{duration: DefaultFrameDuration}},
+		dirty:  true,
+	}, nil
 }
 
 // Width returns the canvas width in pixels.
@@ -68,20 +104,27 @@ func (c *Canvas) Height() int {
 	return c.height
 }
 
-// SetPixel sets a pixel to the provided color.
+// SetPixel sets a pixel on the active layer to the provided color.
 func (c *Canvas) SetPixel(x, y int, value color.RGBA) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
 	idx, err := c.index(x, y)
 	if err != nil {
 		return err
 	}
-	c.pixels[idx] = value
+	c.write(idx, value)
 	c.dirty = true
 	return nil
 }
 
-// GetPixel returns the color at the provided coordinates.
+// GetPixel returns the composite color at the provided coordinates.
 func (c *Canvas) GetPixel(x, y int) (color.RGBA, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -89,42 +132,93 @@ func (c *Canvas) GetPixel(x, y int) (color.RGBA, error) {
 	if err != nil {
 		return color.RGBA{}, err
 	}
-	return c.pixels[idx], nil
+	return c.compositeAt(c.frame, idx), nil
 }
 
-// Clear fills the entire canvas with the provided color.
-func (c *Canvas) Clear(value color.RGBA) {
+// GetLayerPixel returns the unblended color of a single layer on the current frame.
+func (c *Canvas) GetLayerPixel(ref string, x, y int) (color.RGBA, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	layerIdx, err := c.resolveLayer(ref)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	idx, err := c.index(x, y)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	return c.color(c.layers[layerIdx].cels[c.frame][idx]), nil
+}
+
+// Clear fills the entire active layer with the provided color.
+func (c *Canvas) Clear(value color.RGBA) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	for i := range c.pixels {
-		c.pixels[i] = value
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	for i := range c.pixels() {
+		c.write(i, value)
 	}
 	c.dirty = true
+	return nil
 }
 
 // Snapshot returns a copy of the current canvas state.
 func (c *Canvas) Snapshot() Snapshot {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	pixels := make([]color.RGBA, len(c.pixels))
-	copy(pixels, c.pixels)
-	return Snapshot{width: c.width, height: c.height, pixels: pixels}
+	return c.snapshot()
 }
 
-// RenderSnapshot returns a copy of the canvas as RGBA bytes and clears the dirty flag.
+// snapshot copies the canvas state; callers must hold the lock.
+func (c *Canvas) snapshot() Snapshot {
+	layers := make([]*layer, len(c.layers))
+	for i, l := range c.layers {
+		layers[i] = l.clone()
+	}
+	frames := make([]frame, len(c.frames))
+	copy(frames, c.frames)
+	snapshot := Snapshot{width: c.width, height: c.height, layers: layers, active: c.active, frames: frames, frame: c.frame}
+	if c.indexed {
+		snapshot.indexed, snapshot.palette = true, slices.Clone(c.palette)
+	}
+	return snapshot
+}
+
+// RenderSnapshot returns a copy of the current frame's composite as RGBA bytes and
+// clears the dirty flag. While playback is enabled every frame is included.
 func (c *Canvas) RenderSnapshot() RenderSnapshot {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	pixels := make([]byte, len(c.pixels)*4)
-	for i, value := range c.pixels {
+	snapshot := RenderSnapshot{Width: c.width, Height: c.height, Pixels: rgbaBytes(c.composite(c.frame))}
+	if c.playing {
+		snapshot.Frames = make([]RenderFrame, len(c.frames))
+		for i, f := range c.frames {
+			snapshot.Frames[i] = RenderFrame{
+				Pixels:   rgbaBytes(c.composite(i)),
+				Duration: time.Duration(f.duration) * time.Millisecond,
+			}
+		}
+	}
+	c.dirty = false
+	return snapshot
+}
+
+func rgbaBytes(values []color.RGBA) []byte {
+	pixels := make([]byte, len(values)*4)
+	for i, value := range values {
 		offset := i * 4
 		pixels[offset] = value.R
 		pixels[offset+1] = value.G
 		pixels[offset+2] = value.B
 		pixels[offset+3] = value.A
 	}
-	c.dirty = false
-	return RenderSnapshot{Width: c.width, Height: c.height, Pixels: pixels}
+	return pixels
 }
 
 // Dirty reports whether the canvas has changed since the last render snapshot.
@@ -134,17 +228,51 @@ func (c *Canvas) Dirty() bool {
 	return c.dirty
 }
 
-// Restore replaces the current canvas state with the snapshot.
+// Restore replaces the current canvas state with a snapshot of the same size.
 func (c *Canvas) Restore(snapshot Snapshot) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.restore(snapshot)
+}
+
+// restore validates and copies a snapshot into the canvas; callers must hold the write lock.
+// An indexed canvas only takes indexed snapshots; any canvas takes an indexed
+// snapshot's palette, becoming indexed if it was not.
+func (c *Canvas) restore(snapshot Snapshot) error {
 	if snapshot.width != c.width || snapshot.height != c.height {
 		return Error{Code: "invalid_args", Message: "snapshot dimensions do not match canvas"}
 	}
-	if len(snapshot.pixels) != len(c.pixels) {
-		return Error{Code: "invalid_args", Message: "snapshot size does not match canvas"}
+	if c.indexed && !snapshot.indexed {
+		return Error{Code: "invalid_args", Message: "snapshot is not indexed"}
+	}
+	if len(snapshot.layers) == 0 || snapshot.active < 0 || snapshot.active >= len(snapshot.layers) {
+		return Error{Code: "invalid_args", Message: "snapshot has no valid active layer"}
+	}
+	if len(snapshot.frames) == 0 || snapshot.frame < 0 || snapshot.frame >= len(snapshot.frames) {
+		return Error{Code: "invalid_args", Message: "snapshot has no valid current frame"}
+	}
+	layers := make([]*layer, len(snapshot.layers))
+	for i, l := range snapshot.layers {
+		if len(l.cels) != len(snapshot.frames) {
+			return Error{Code: "invalid_args", Message: "snapshot layer frames do not match timeline"}
+		}
+		for _, cel := range l.cels {
+			if len(cel) != c.width*c.height {
+				return Error{Code: "invalid_args", Message: "snapshot size does not match canvas"}
+			}
+		}
+		layers[i] = l.clone()
+	}
+	frames := make([]frame, len(snapshot.frames))
+	copy(frames, snapshot.frames)
+	c.recordShape()
+	if snapshot.indexed {
+		c.indexed, c.palette = true, slices.Clone(snapshot.palette)
 	}
-	copy(c.pixels, snapshot.pixels)
+	c.layers = layers
+	c.active = snapshot.active
+	c.frames = frames
+	c.frame = snapshot.frame
 	c.dirty = true
 	return nil
 }
@@ -153,6 +281,13 @@ func (c *Canvas) Restore(snapshot Snapshot) error {
 func (c *Canvas) FillRect(x, y, w, h int, value color.RGBA) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
 	if w <= 0 || h <= 0 {
 		return Error{Code: "invalid_args", Message: "rect width and height must be positive"}
 	}
@@ -166,7 +301,7 @@ func (c *Canvas) FillRect(x, y, w, h int, value color.RGBA) error {
 	for row := y; row < y+h; row++ {
 		start := row*c.width + x
 		for i := 0; i < w; i++ {
-			c.pixels[start+i] = value
+			c.write(start+i, value)
 		}
 	}
 	c.dirty = true
@@ -177,13 +312,27 @@ func (c *Canvas) FillRect(x, y, w, h int, value color.RGBA) error {
 func (c *Canvas) Line(x1, y1, x2, y2 int, value color.RGBA) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
 	if _, err := c.index(x1, y1); err != nil {
 		return err
 	}
 	if _, err := c.index(x2, y2); err != nil {
 		return err
 	}
+	c.line(x1, y1, x2, y2, value)
+	c.dirty = true
+	return nil
+}
 
+// line rasterizes a Bresenham line; callers must hold the write lock and
+// clip or validate endpoints.
+func (c *Canvas) line(x1, y1, x2, y2 int, value color.RGBA) {
 	dx := absInt(x2 - x1)
 	dy := absInt(y2 - y1)
 	sx := -1
@@ -197,7 +346,7 @@ func (c *Canvas) Line(x1, y1, x2, y2 int, value color.RGBA) error {
 	errVal := dx - dy
 
 	for {
-		c.pixels[y1*c.width+x1] = value
+		c.plot(x1, y1, value)
 		if x1 == x2 && y1 == y2 {
 			break
 		}
@@ -211,26 +360,61 @@ func (c *Canvas) Line(x1, y1, x2, y2 int, value color.RGBA) error {
 			y1 += sy
 		}
 	}
-	c.dirty = true
-	return nil
 }
 
-// ExportPNG writes the canvas to a PNG file at the provided path.
+// ExportPNG writes the composite of the current frame to a PNG file at the provided path.
+// An indexed canvas writes a paletted PNG holding its palette and indices.
 func (c *Canvas) ExportPNG(path string) error {
-	file, err := os.Create(path)
+	c.mu.RLock()
+	var img image.Image
+	if c.indexed {
+		img = c.palettedImage(c.compositeIndices(c.frame))
+	} else {
+		img = c.image(c.composite(c.frame))
+	}
+	c.mu.RUnlock()
+	return writePNG(path, img)
+}
+
+// ExportLayerPNG writes a single, unblended layer of the current frame to a PNG file.
+func (c *Canvas) ExportLayerPNG(ref, path string) error {
+	c.mu.RLock()
+	layerIdx, err := c.resolveLayer(ref)
 	if err != nil {
-		return Error{Code: "io", Message: err.Error()}
+		c.mu.RUnlock()
+		return err
+	}
+	cel := c.layers[layerIdx].cels[c.frame]
+	var img image.Image = c.image(cel)
+	if c.indexed {
+		indices := make([]uint8, len(cel))
+		for i, pixel := range cel {
+			indices[i] = pixel.R
+		}
+		img = c.palettedImage(indices)
 	}
+	c.mu.RUnlock()
+	return writePNG(path, img)
+}
 
-	snapshot := c.Snapshot()
-	img := image.NewRGBA(image.Rect(0, 0, snapshot.width, snapshot.height))
-	for y := 0; y < snapshot.height; y++ {
-		row := y * snapshot.width
-		for x := 0; x < snapshot.width; x++ {
-			img.SetRGBA(x, y, snapshot.pixels[row+x])
+// image wraps pixels in an image; canvas colors are straight alpha, so NRGBA
+// keeps translucent pixels intact when encoded.
+func (c *Canvas) image(pixels []color.RGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, c.width, c.height))
+	for y := 0; y < c.height; y++ {
+		row := y * c.width
+		for x := 0; x < c.width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA(pixels[row+x]))
 		}
 	}
+	return img
+}
 
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
 	if err := png.Encode(file, img); err != nil {
 		_ = file.Close()
 		return Error{Code: "io", Message: err.Error()}
@@ -248,6 +432,38 @@ func (c *Canvas) index(x, y int) (int, error) {
 	return y*c.width + x, nil
 }
 
+// pixels returns the active layer's cel on the current frame for reading; writes
+// must go through write so they are recorded. Callers must hold the lock.
+func (c *Canvas) pixels() []color.RGBA {
+	return c.layers[c.active].cels[c.frame]
+}
+
+// write sets a pixel on the active cel, recording its previous value when a
+// change is being recorded; callers must hold the write lock.
+func (c *Canvas) write(idx int, value color.RGBA) {
+	cel := c.layers[c.active].cels[c.frame]
+	if c.rec != nil {
+		c.rec.touch(celKey{layer: c.active, frame: c.frame}, cel, idx, c.width)
+	}
+	cel[idx] = value
+}
+
+// checkWritable reports an error when the active layer is locked.
+func (c *Canvas) checkWritable() error {
+	if l := c.layers[c.active]; l.locked {
+		return Error{Code: "layer_locked", Message: fmt.Sprintf("layer %q is locked", l.name)}
+	}
+	return nil
+}
+
+// plot sets a pixel on the active layer, silently clipping coordinates outside the canvas.
+func (c *Canvas) plot(x, y int, value color.RGBA) {
+	if x < 0 || x >= c.width || y < 0 || y >= c.height {
+		return
+	}
+	c.write(y*c.width+x, value)
+}
+
 func absInt(value int) int {
 	if value < 0 {
 		return -value
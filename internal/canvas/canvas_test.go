@@ -2,7 +2,9 @@ package canvas
 
 import (
 	"image/color"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCanvasSetGetPixel(t *testing.T) {
@@ -247,3 +249,393 @@ func TestCanvasLineErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestCanvasCircleGolden(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	tests := []struct {
+		name   string
+		radius int
+		filled bool
+		want   string
+	}{
+		{
+			name:   "radius 0",
+			radius: 0,
+			want:   "#",
+		},
+		{
+			name:   "radius 1",
+			radius: 1,
+			want: `
+.#.
+#.#
+.#.`,
+		},
+		{
+			name:   "radius 4 outline",
+			radius: 4,
+			want: `
+...###...
+..#...#..
+.#.....#.
+#.......#
+#.......#
+#.......#
+.#.....#.
+..#...#..
+...###...`,
+		},
+		{
+			name:   "radius 4 filled",
+			radius: 4,
+			filled: true,
+			want: `
+...###...
+..#####..
+.#######.
+#########
+#########
+#########
+.#######.
+..#####..
+...###...`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := 2*tt.radius + 1
+			c, err := New(size, size)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Circle(tt.radius, tt.radius, tt.radius, red, tt.filled); err != nil {
+				t.Fatalf("unexpected circle error: %v", err)
+			}
+			assertASCII(t, c, tt.want)
+		})
+	}
+}
+
+func TestCanvasCircleOutlineIsPixelPerfect(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	for r := 1; r <= 24; r++ {
+		size := 2*r + 1
+		c, err := New(size, size)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := c.Circle(r, r, r, red, false); err != nil {
+			t.Fatalf("unexpected circle error: %v", err)
+		}
+		assertSymmetric(t, c)
+		assertNoCornerPixels(t, c)
+	}
+}
+
+func TestCanvasEllipseGolden(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	tests := []struct {
+		name   string
+		rx     int
+		ry     int
+		filled bool
+		want   string
+	}{
+		{
+			name: "wide outline",
+			rx:   7,
+			ry:   4,
+			want: `
+....#######....
+..##.......##..
+.#...........#.
+#.............#
+#.............#
+#.............#
+.#...........#.
+..##.......##..
+....#######....`,
+		},
+		{
+			name:   "wide filled",
+			rx:     7,
+			ry:     4,
+			filled: true,
+			want: `
+....#######....
+..###########..
+.#############.
+###############
+###############
+###############
+.#############.
+..###########..
+....#######....`,
+		},
+		{
+			name: "flat",
+			rx:   5,
+			ry:   0,
+			want: "###########",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(2*tt.rx+1, 2*tt.ry+1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Ellipse(tt.rx, tt.ry, tt.rx, tt.ry, red, tt.filled); err != nil {
+				t.Fatalf("unexpected ellipse error: %v", err)
+			}
+			assertASCII(t, c, tt.want)
+		})
+	}
+}
+
+func TestCanvasEllipseOutlineIsPixelPerfect(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	for rx := 1; rx <= 12; rx++ {
+		for ry := 1; ry <= 12; ry++ {
+			c, err := New(2*rx+1, 2*ry+1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Ellipse(rx, ry, rx, ry, red, false); err != nil {
+				t.Fatalf("unexpected ellipse error: %v", err)
+			}
+			assertSymmetric(t, c)
+			assertNoCornerPixels(t, c)
+		}
+	}
+}
+
+func TestCanvasArcGolden(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	tests := []struct {
+		name   string
+		start  int
+		end    int
+		filled bool
+		want   string
+	}{
+		{
+			name:  "upper right quarter",
+			start: 0,
+			end:   90,
+			want: `
+....##...
+......#..
+.......#.
+........#
+........#
+.........
+.........
+.........
+.........`,
+		},
+		{
+			name:   "upper right pie",
+			start:  0,
+			end:    90,
+			filled: true,
+			want: `
+....##...
+....###..
+....####.
+....#####
+....#####
+.........
+.........
+.........
+.........`,
+		},
+		{
+			name:  "lower half wraps past 360",
+			start: 180,
+			end:   360,
+			want: `
+.........
+.........
+.........
+.........
+#.......#
+#.......#
+.#.....#.
+..#...#..
+...###...`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(9, 9)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Arc(4, 4, 4, tt.start, tt.end, red, tt.filled); err != nil {
+				t.Fatalf("unexpected arc error: %v", err)
+			}
+			assertASCII(t, c, tt.want)
+		})
+	}
+}
+
+func TestCanvasShapesClipAndValidate(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	c, err := New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Circle(0, 0, 3, red, true); err != nil {
+		t.Fatalf("expected clipped circle to succeed, got %v", err)
+	}
+	assertASCII(t, c, `
+####
+####
+###.
+##..`)
+
+	if err := c.Circle(0, 0, -1, red, false); err == nil {
+		t.Fatalf("expected invalid_args for negative radius")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+	if err := c.Ellipse(0, 0, 1, -1, red, false); err == nil {
+		t.Fatalf("expected invalid_args for negative radius")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+	if err := c.Arc(0, 0, -1, 0, 90, red, false); err == nil {
+		t.Fatalf("expected invalid_args for negative radius")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+}
+
+func TestCanvasShapesWithOversizedRadius(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	c, err := New(8, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	if err := c.Circle(0, 0, 5000000, red, false); err != nil {
+		t.Fatalf("unexpected circle error: %v", err)
+	}
+	if err := c.Arc(0, 0, 5000000, 0, 90, red, false); err != nil {
+		t.Fatalf("unexpected arc error: %v", err)
+	}
+	assertASCII(t, c, `
+........
+........
+........
+........
+........
+........
+........
+........`)
+
+	if err := c.Circle(-5000000, 4, 5000003, red, false); err != nil {
+		t.Fatalf("unexpected circle error: %v", err)
+	}
+	for x, want := range []uint8{0, 0, 0, 255, 0} {
+		if got, _ := c.GetPixel(x, 4); got.A != want {
+			t.Fatalf("pixel (%d,4) alpha = %d, want %d", x, got.A, want)
+		}
+	}
+	if err := c.Ellipse(4, 4, 5000000, 5000000, red, true); err != nil {
+		t.Fatalf("unexpected ellipse error: %v", err)
+	}
+	assertASCII(t, c, `
+########
+########
+########
+########
+########
+########
+########
+########`)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("oversized shapes took %v", elapsed)
+	}
+
+	for name, draw := range map[string]func() error{
+		"circle":  func() error { return c.Circle(0, 0, MaxRadius+1, red, false) },
+		"ellipse": func() error { return c.Ellipse(0, 0, 1, MaxRadius+1, red, true) },
+		"arc":     func() error { return c.Arc(0, 0, MaxRadius+1, 0, 90, red, true) },
+	} {
+		if err := draw(); err == nil {
+			t.Fatalf("expected invalid_args for %s radius past MaxRadius", name)
+		} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+			t.Fatalf("expected invalid_args for %s, got %v", name, err)
+		}
+	}
+}
+
+func asciiCanvas(t *testing.T, c *Canvas) string {
+	t.Helper()
+	var b strings.Builder
+	for y := 0; y < c.Height(); y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < c.Width(); x++ {
+			got, err := c.GetPixel(x, y)
+			if err != nil {
+				t.Fatalf("unexpected get error at (%d,%d): %v", x, y, err)
+			}
+			if got.A == 0 {
+				b.WriteByte('.')
+			} else {
+				b.WriteByte('#')
+			}
+		}
+	}
+	return b.String()
+}
+
+func assertASCII(t *testing.T, c *Canvas, want string) {
+	t.Helper()
+	want = strings.TrimPrefix(want, "\n")
+	if got := asciiCanvas(t, c); got != want {
+		t.Fatalf("unexpected canvas:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func assertSymmetric(t *testing.T, c *Canvas) {
+	t.Helper()
+	w, h := c.Width(), c.Height()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			got, _ := c.GetPixel(x, y)
+			mirrorX, _ := c.GetPixel(w-1-x, y)
+			mirrorY, _ := c.GetPixel(x, h-1-y)
+			if got != mirrorX || got != mirrorY {
+				t.Fatalf("%dx%d shape not symmetric at (%d,%d):\n%s", w, h, x, y, asciiCanvas(t, c))
+			}
+		}
+	}
+}
+
+func assertNoCornerPixels(t *testing.T, c *Canvas) {
+	t.Helper()
+	set := func(x, y int) bool {
+		value, err := c.GetPixel(x, y)
+		return err == nil && value.A != 0
+	}
+	for y := 0; y < c.Height(); y++ {
+		for x := 0; x < c.Width(); x++ {
+			if !set(x, y) {
+				continue
+			}
+			horizontal := set(x-1, y) || set(x+1, y)
+			vertical := set(x, y-1) || set(x, y+1)
+			if horizontal && vertical {
+				t.Fatalf("%dx%d outline has doubled corner at (%d,%d):\n%s", c.Width(), c.Height(), x, y, asciiCanvas(t, c))
+			}
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"slices"
+)
+
+// Change is a recorded edit that can be reverted and reapplied against the
+// canvas it was recorded on. Pixel edits keep only the touched pixels of each
+// cel, either as a sparse list or as the bounding region, whichever is smaller;
+// edits that restructure the layer stack or timeline keep whole-state snapshots.
+type Change struct {
+	cels      []celDelta
+	meta      [2]canvasMeta
+	selection [2]selection
+	shape     *[2]Snapshot
+}
+
+// celDelta holds the before and after values of the changed pixels in one cel.
+// Sparse deltas list their pixel indices; region deltas cover rect row by row.
+type celDelta struct {
+	key     celKey
+	indices []int32
+	rect    image.Rectangle
+	before  []color.RGBA
+	after   []color.RGBA
+}
+
+type celKey struct {
+	layer int
+	frame int
+}
+
+type selection struct {
+	layer int
+	frame int
+}
+
+// canvasMeta captures the layer properties and frame durations of a canvas.
+type canvasMeta struct {
+	layers    []layerMeta
+	durations []int
+}
+
+type layerMeta struct {
+	name    string
+	visible bool
+	opacity int
+	locked  bool
+	blend   BlendMode
+}
+
+const (
+	sparsePixelBytes = 12 // int32 index plus before and after colors
+	regionPixelBytes = 8  // before and after colors
+	changeOverhead   = 64
+)
+
+// recording tracks the original values of pixels written since BeginChange.
+type recording struct {
+	selection selection
+	meta      canvasMeta
+	cels      map[celKey]*celRecord
+	shape     *Snapshot
+	// last caches the most recently touched cel, which is nearly always the next.
+	lastKey celKey
+	last    *celRecord
+}
+
+// celRecord keeps the first-touch values of written pixels as a list until
+// enough of the cel has been written that a full copy is cheaper.
+type celRecord struct {
+	width   int
+	indices []int32
+	values  []color.RGBA
+	seen    []uint64
+	copy    []color.RGBA
+	bounds  image.Rectangle
+}
+
+// linearScanLimit is how many touched pixels are checked for repeats by scanning
+// before a bitset of the whole cel is allocated.
+const linearScanLimit = 32
+
+// BeginChange starts recording edits for a Change. Calling it while a change is
+// already being recorded keeps the existing recording.
+func (c *Canvas) BeginChange() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rec != nil {
+		return
+	}
+	c.rec = &recording{
+		selection: selection{layer: c.active, frame: c.frame},
+		meta:      c.meta(),
+		cels:      make(map[celKey]*celRecord),
+	}
+}
+
+// EndChange stops recording and returns the edits made since BeginChange.
+func (c *Canvas) EndChange() Change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec := c.rec
+	if rec == nil {
+		return Change{}
+	}
+	c.rec = nil
+
+	change := Change{
+		selection: [2]selection{rec.selection, {layer: c.active, frame: c.frame}},
+	}
+	if rec.shape != nil {
+		change.shape = &[2]Snapshot{*rec.shape, c.snapshot()}
+		return change
+	}
+	keys := make([]celKey, 0, len(rec.cels))
+	for key := range rec.cels {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b celKey) int {
+		if a.layer != b.layer {
+			return a.layer - b.layer
+		}
+		return a.frame - b.frame
+	})
+	for _, key := range keys {
+		if delta, ok := rec.cels[key].delta(key, c.layers[key.layer].cels[key.frame]); ok {
+			change.cels = append(change.cels, delta)
+		}
+	}
+	if meta := c.meta(); !meta.equal(rec.meta) {
+		change.meta = [2]canvasMeta{rec.meta, meta}
+	}
+	return change
+}
+
+// Empty reports whether the change leaves the canvas as it was.
+func (ch Change) Empty() bool {
+	if ch.shape != nil {
+		return false
+	}
+	return len(ch.cels) == 0 && ch.meta[0].layers == nil && ch.selection[0] == ch.selection[1]
+}
+
+// Size estimates the memory held by the change in bytes.
+func (ch Change) Size() int {
+	size := changeOverhead
+	for _, delta := range ch.cels {
+		if delta.indices != nil {
+			size += len(delta.indices) * sparsePixelBytes
+		} else {
+			size += len(delta.before) * regionPixelBytes
+		}
+	}
+	for _, meta := range ch.meta {
+		size += meta.size()
+	}
+	if ch.shape != nil {
+		size += ch.shape[0].size() + ch.shape[1].size()
+	}
+	return size
+}
+
+// RevertChange undoes a change, restoring the state from before it was recorded.
+func (c *Canvas) RevertChange(ch Change) error {
+	return c.applyChange(ch, 0)
+}
+
+// ReapplyChange redoes a reverted change.
+func (c *Canvas) ReapplyChange(ch Change) error {
+	return c.applyChange(ch, 1)
+}
+
+// applyChange moves the canvas to one side of a change: 0 for before, 1 for after.
+func (c *Canvas) applyChange(ch Change, side int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch.shape != nil {
+		return c.load(ch.shape[side])
+	}
+	if err := c.checkChange(ch); err != nil {
+		return err
+	}
+	for _, delta := range ch.cels {
+		values := delta.before
+		if side == 1 {
+			values = delta.after
+		}
+		delta.write(c.layers[delta.key.layer].cels[delta.key.frame], c.width, values)
+	}
+	if ch.meta[side].layers != nil {
+		c.setMeta(ch.meta[side])
+	}
+	c.active = ch.selection[side].layer
+	c.frame = ch.selection[side].frame
+	c.dirty = true
+	return nil
+}
+
+// checkChange verifies a change fits the canvas layout before any of it is applied.
+func (c *Canvas) checkChange(ch Change) error {
+	mismatch := Error{Code: "invalid_args", Message: "change does not match canvas"}
+	bounds := image.Rect(0, 0, c.width, c.height)
+	for _, delta := range ch.cels {
+		if delta.key.layer >= len(c.layers) || delta.key.frame >= len(c.frames) {
+			return mismatch
+		}
+		if delta.indices == nil && !delta.rect.In(bounds) {
+			return mismatch
+		}
+		for _, idx := range delta.indices {
+			if int(idx) >= c.width*c.height {
+				return mismatch
+			}
+		}
+	}
+	for _, meta := range ch.meta {
+		if meta.layers != nil && (len(meta.layers) != len(c.layers) || len(meta.durations) != len(c.frames)) {
+			return mismatch
+		}
+	}
+	for _, sel := range ch.selection {
+		if sel.layer < 0 || sel.layer >= len(c.layers) || sel.frame < 0 || sel.frame >= len(c.frames) {
+			return mismatch
+		}
+	}
+	return nil
+}
+
+// NewChange builds the change that turns before into after. Snapshots with the
+// same size, layout and palette produce pixel deltas; anything else is kept
+// whole.
+func NewChange(before, after Snapshot) Change {
+	change := Change{
+		selection: [2]selection{
+			{layer: before.active, frame: before.frame},
+			{layer: after.active, frame: after.frame},
+		},
+	}
+	if before.width != after.width || before.height != after.height ||
+		len(before.layers) != len(after.layers) || len(before.frames) != len(after.frames) ||
+		!slices.Equal(before.palette, after.palette) {
+		change.shape = &[2]Snapshot{before, after}
+		return change
+	}
+	for li := range before.layers {
+		for fi := range before.frames {
+			old, cur := before.layers[li].cels[fi], after.layers[li].cels[fi]
+			record := &celRecord{width: before.width, copy: old}
+			for idx := range old {
+				if old[idx] != cur[idx] {
+					record.extend(idx)
+				}
+			}
+			if delta, ok := record.delta(celKey{layer: li, frame: fi}, cur); ok {
+				change.cels = append(change.cels, delta)
+			}
+		}
+	}
+	beforeMeta, afterMeta := before.meta(), after.meta()
+	if !beforeMeta.equal(afterMeta) {
+		change.meta = [2]canvasMeta{beforeMeta, afterMeta}
+	}
+	return change
+}
+
+// touch records the value of cel[idx] before its first write; callers must hold
+// the write lock.
+func (r *recording) touch(key celKey, cel []color.RGBA, idx, width int) {
+	if r.shape != nil {
+		return
+	}
+	if r.last == nil || r.lastKey != key {
+		record := r.cels[key]
+		if record == nil {
+			record = &celRecord{width: width}
+			r.cels[key] = record
+		}
+		r.lastKey, r.last = key, record
+	}
+	r.last.touch(cel, idx)
+}
+
+// recordShape is called before an edit that restructures layers or frames. It
+// saves the whole pre-change state, rolling back anything recorded so far, and
+// stops per-pixel tracking for the rest of the change; callers must hold the
+// write lock.
+func (c *Canvas) recordShape() {
+	rec := c.rec
+	if rec == nil || rec.shape != nil {
+		return
+	}
+	snapshot := c.snapshot()
+	for key, record := range rec.cels {
+		record.restore(snapshot.layers[key.layer].cels[key.frame])
+	}
+	snapshot.setMeta(rec.meta)
+	snapshot.active = rec.selection.layer
+	snapshot.frame = rec.selection.frame
+	rec.shape = &snapshot
+	rec.cels, rec.last = nil, nil
+}
+
+func (r *celRecord) touch(cel []color.RGBA, idx int) {
+	r.extend(idx)
+	if r.copy != nil || r.seenBefore(idx, len(cel)) {
+		return
+	}
+	r.indices = append(r.indices, int32(idx))
+	r.values = append(r.values, cel[idx])
+	if len(r.indices) > len(cel)/16 {
+		original := cloneCel(cel)
+		r.restore(original)
+		r.copy, r.indices, r.values, r.seen = original, nil, nil, nil
+	}
+}
+
+// seenBefore reports whether idx was already touched and marks it as touched.
+func (r *celRecord) seenBefore(idx, size int) bool {
+	if r.seen == nil {
+		if len(r.indices) < linearScanLimit {
+			return slices.Contains(r.indices, int32(idx))
+		}
+		r.seen = make([]uint64, (size+63)/64)
+		for _, i := range r.indices {
+			r.seen[i/64] |= 1 << (i % 64)
+		}
+	}
+	bit := uint64(1) << (idx % 64)
+	if r.seen[idx/64]&bit != 0 {
+		return true
+	}
+	r.seen[idx/64] |= bit
+	return false
+}
+
+func (r *celRecord) extend(idx int) {
+	p := image.Pt(idx%r.width, idx/r.width)
+	pixel := image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))}
+	if r.bounds.Empty() {
+		r.bounds = pixel
+		return
+	}
+	r.bounds = r.bounds.Union(pixel)
+}
+
+// restore writes the recorded original values back into cel.
+func (r *celRecord) restore(cel []color.RGBA) {
+	if r.copy != nil {
+		copy(cel, r.copy)
+		return
+	}
+	for i, idx := range r.indices {
+		cel[idx] = r.values[i]
+	}
+}
+
+// delta compares the recorded original values with cel and encodes the pixels
+// that actually changed in whichever form is smaller.
+func (r *celRecord) delta(key celKey, cel []color.RGBA) (celDelta, bool) {
+	var changed []int32
+	var before []color.RGBA
+	if r.copy != nil {
+		for y := r.bounds.Min.Y; y < r.bounds.Max.Y; y++ {
+			for x := r.bounds.Min.X; x < r.bounds.Max.X; x++ {
+				if idx := y*r.width + x; r.copy[idx] != cel[idx] {
+					changed = append(changed, int32(idx))
+					before = append(before, r.copy[idx])
+				}
+			}
+		}
+	} else {
+		for i, idx := range r.indices {
+			if r.values[i] != cel[idx] {
+				changed = append(changed, idx)
+				before = append(before, r.values[i])
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return celDelta{}, false
+	}
+
+	var rect image.Rectangle
+	for _, idx := range changed {
+		p := image.Pt(int(idx)%r.width, int(idx)/r.width)
+		rect = rect.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+	if len(changed)*sparsePixelBytes <= rect.Dx()*rect.Dy()*regionPixelBytes {
+		after := make([]color.RGBA, len(changed))
+		for i, idx := range changed {
+			after[i] = cel[idx]
+		}
+		return celDelta{key: key, indices: changed, before: before, after: after}, true
+	}
+
+	// Pixels in the region that were not changed are the same on both sides.
+	delta := celDelta{key: key, rect: rect}
+	area := rect.Dx() * rect.Dy()
+	delta.after = make([]color.RGBA, 0, area)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		row := y * r.width
+		delta.after = append(delta.after, cel[row+rect.Min.X:row+rect.Max.X]...)
+	}
+	delta.before = cloneCel(delta.after)
+	for i, idx := range changed {
+		x, y := int(idx)%r.width-rect.Min.X, int(idx)/r.width-rect.Min.Y
+		delta.before[y*rect.Dx()+x] = before[i]
+	}
+	return delta, true
+}
+
+// write stores one side of the delta into cel.
+func (d celDelta) write(cel []color.RGBA, width int, values []color.RGBA) {
+	if d.indices != nil {
+		for i, idx := range d.indices {
+			cel[idx] = values[i]
+		}
+		return
+	}
+	i := 0
+	for y := d.rect.Min.Y; y < d.rect.Max.Y; y++ {
+		row := y * width
+		i += copy(cel[row+d.rect.Min.X:row+d.rect.Max.X], values[i:i+d.rect.Dx()])
+	}
+}
+
+// meta captures layer properties and frame durations; callers must hold the lock.
+func (c *Canvas) meta() canvasMeta {
+	return captureMeta(c.layers, c.frames)
+}
+
+func (s Snapshot) meta() canvasMeta {
+	return captureMeta(s.layers, s.frames)
+}
+
+func captureMeta(layers []*layer, frames []frame) canvasMeta {
+	meta := canvasMeta{
+		layers:    make([]layerMeta, len(layers)),
+		durations: make([]int, len(frames)),
+	}
+	for i, l := range layers {
+		meta.layers[i] = layerMeta{name: l.name, visible: l.visible, opacity: l.opacity, locked: l.locked, blend: l.blend}
+	}
+	for i, f := range frames {
+		meta.durations[i] = f.duration
+	}
+	return meta
+}
+
+// setMeta applies layer properties and frame durations; callers must hold the write lock.
+func (c *Canvas) setMeta(meta canvasMeta) {
+	applyMeta(c.layers, c.frames, meta)
+}
+
+func (s Snapshot) setMeta(meta canvasMeta) {
+	applyMeta(s.layers, s.frames, meta)
+}
+
+func applyMeta(layers []*layer, frames []frame, meta canvasMeta) {
+	for i, m := range meta.layers {
+		l := layers[i]
+		l.name, l.visible, l.opacity, l.locked, l.blend = m.name, m.visible, m.opacity, m.locked, m.blend
+	}
+	for i, duration := range meta.durations {
+		frames[i].duration = duration
+	}
+}
+
+func (m canvasMeta) equal(other canvasMeta) bool {
+	return slices.Equal(m.layers, other.layers) && slices.Equal(m.durations, other.durations)
+}
+
+func (m canvasMeta) size() int {
+	size := len(m.durations) * 8
+	for _, l := range m.layers {
+		size += 32 + len(l.name) + len(l.blend)
+	}
+	return size
+}
+
+// size estimates the memory held by a snapshot in bytes.
+func (s Snapshot) size() int {
+	size := changeOverhead + len(s.frames)*8
+	for _, l := range s.layers {
+		size += 32 + len(l.name) + len(l.cels)*s.width*s.height*4
+	}
+	return size
+}
@@ -0,0 +1,237 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestChangeRevertAndReapplyPixels(t *testing.T) {
+	c, err := New(8, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	red := color.RGBA{R: 255, A: 255}
+	before := c.Snapshot()
+
+	c.BeginChange()
+	if err := c.SetPixel(1, 1, red); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if err := c.FillRect(4, 4, 2, 2, red); err != nil {
+		t.Fatalf("unexpected fill error: %v", err)
+	}
+	change := c.EndChange()
+	after := c.Snapshot()
+
+	if change.Empty() {
+		t.Fatalf("expected a non-empty change")
+	}
+	if err := c.RevertChange(change); err != nil {
+		t.Fatalf("unexpected revert error: %v", err)
+	}
+	if !reflect.DeepEqual(c.Snapshot(), before) {
+		t.Fatalf("expected revert to restore the original state")
+	}
+	if err := c.ReapplyChange(change); err != nil {
+		t.Fatalf("unexpected reapply error: %v", err)
+	}
+	if !reflect.DeepEqual(c.Snapshot(), after) {
+		t.Fatalf("expected reapply to restore the edited state")
+	}
+}
+
+func TestChangeEncodesSmallerDelta(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	tests := []struct {
+		name   string
+		draw   func(*Canvas) error
+		sparse bool
+		pixels int
+	}{
+		{
+			name: "scattered pixels stay sparse",
+			draw: func(c *Canvas) error {
+				if err := c.SetPixel(0, 0, red); err != nil {
+					return err
+				}
+				return c.SetPixel(15, 15, red)
+			},
+			sparse: true,
+			pixels: 2,
+		},
+		{
+			name: "a filled block uses its bounding region",
+			draw: func(c *Canvas) error {
+				return c.FillRect(2, 3, 4, 5, red)
+			},
+			pixels: 20,
+		},
+		{
+			name: "a full clear promotes to a cel copy",
+			draw: func(c *Canvas) error {
+				return c.Clear(red)
+			},
+			pixels: 256,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(16, 16)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c.BeginChange()
+			if err := tt.draw(c); err != nil {
+				t.Fatalf("unexpected draw error: %v", err)
+			}
+			change := c.EndChange()
+			if len(change.cels) != 1 {
+				t.Fatalf("expected one cel delta, got %d", len(change.cels))
+			}
+			delta := change.cels[0]
+			if got := delta.indices != nil; got != tt.sparse {
+				t.Fatalf("expected sparse=%t, got %t", tt.sparse, got)
+			}
+			if len(delta.after) != tt.pixels {
+				t.Fatalf("expected %d stored pixels, got %d", tt.pixels, len(delta.after))
+			}
+		})
+	}
+}
+
+func TestChangeIgnoresUnchangedPixels(t *testing.T) {
+	c, err := New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	red := color.RGBA{R: 255, A: 255}
+
+	c.BeginChange()
+	if err := c.SetPixel(1, 1, red); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if err := c.SetPixel(1, 1, color.RGBA{}); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if err := c.RenameLayer("background", "background"); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+	if change := c.EndChange(); !change.Empty() {
+		t.Fatalf("expected writes that restore the original value to be empty, got %+v", change)
+	}
+}
+
+func TestChangeRecordsLayerProperties(t *testing.T) {
+	c := twoLayerCanvas(t, color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255})
+	before := c.Snapshot()
+
+	c.BeginChange()
+	if err := c.SetLayerOpacity("top", 40); err != nil {
+		t.Fatalf("unexpected opacity error: %v", err)
+	}
+	if err := c.RenameLayer("top", "ink"); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+	change := c.EndChange()
+
+	if len(change.cels) != 0 || change.shape != nil {
+		t.Fatalf("expected a properties-only change, got %+v", change)
+	}
+	if err := c.RevertChange(change); err != nil {
+		t.Fatalf("unexpected revert error: %v", err)
+	}
+	if !reflect.DeepEqual(c.Snapshot(), before) {
+		t.Fatalf("expected revert to restore layer properties")
+	}
+}
+
+func TestChangeStructuralEditRollsBackEarlierPixels(t *testing.T) {
+	c, err := New(3, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	red := color.RGBA{R: 255, A: 255}
+	before := c.Snapshot()
+
+	c.BeginChange()
+	if err := c.SetPixel(0, 0, red); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if _, err := c.AddLayer("ink"); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	if err := c.SetPixel(2, 2, red); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	change := c.EndChange()
+	after := c.Snapshot()
+
+	if change.shape == nil {
+		t.Fatalf("expected adding a layer to record whole snapshots")
+	}
+	if err := c.RevertChange(change); err != nil {
+		t.Fatalf("unexpected revert error: %v", err)
+	}
+	if !reflect.DeepEqual(c.Snapshot(), before) {
+		t.Fatalf("expected revert to undo pixels drawn before the layer was added")
+	}
+	if err := c.ReapplyChange(change); err != nil {
+		t.Fatalf("unexpected reapply error: %v", err)
+	}
+	if !reflect.DeepEqual(c.Snapshot(), after) {
+		t.Fatalf("expected reapply to restore both layers")
+	}
+}
+
+func TestNewChangeMatchesRecordedChange(t *testing.T) {
+	c := threeFrameCanvas(t)
+	before := c.Snapshot()
+	blue := color.RGBA{B: 255, A: 255}
+
+	if err := c.SelectFrame(1); err != nil {
+		t.Fatalf("unexpected select error: %v", err)
+	}
+	if err := c.FillRect(0, 0, 2, 1, blue); err != nil {
+		t.Fatalf("unexpected fill error: %v", err)
+	}
+	if err := c.SetFrameDuration(2, 300); err != nil {
+		t.Fatalf("unexpected duration error: %v", err)
+	}
+	after := c.Snapshot()
+
+	change := NewChange(before, after)
+	if len(change.cels) != 1 || change.cels[0].key != (celKey{layer: 0, frame: 1}) {
+		t.Fatalf("expected one delta on frame 1, got %+v", change.cels)
+	}
+	if got := change.cels[0].rect; got != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("expected the delta to cover the filled row, got %v", got)
+	}
+	if err := c.RevertChange(change); err != nil {
+		t.Fatalf("unexpected revert error: %v", err)
+	}
+	if !reflect.DeepEqual(c.Snapshot(), before) {
+		t.Fatalf("expected revert to restore the original frames")
+	}
+}
+
+func TestRevertChangeRejectsMismatchedCanvas(t *testing.T) {
+	c := twoLayerCanvas(t, color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255})
+	c.BeginChange()
+	if err := c.SetLayerVisible("top", false); err != nil {
+		t.Fatalf("unexpected visibility error: %v", err)
+	}
+	change := c.EndChange()
+
+	other, err := New(c.Width(), c.Height())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := other.RevertChange(change); err == nil {
+		t.Fatalf("expected mismatch error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+}
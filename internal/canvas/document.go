@@ -0,0 +1,142 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Document is a self-contained copy of canvas state with exported fields, used to
+// serialize sessions. Cels are indexed by frame and hold width*height pixels each.
+type Document struct {
+	Width        int
+	Height       int
+	Layers       []LayerDocument
+	Durations    []int
+	ActiveLayer  int
+	CurrentFrame int
+}
+
+// LayerDocument is the serializable form of a layer and its per-frame cels.
+type LayerDocument struct {
+	Name    string
+	Visible bool
+	Opacity int
+	Locked  bool
+	Blend   BlendMode
+	Cels    [][]color.RGBA
+}
+
+// Document returns a copy of the snapshot in serializable form. Documents
+// always hold colors: indexed snapshots are resolved through their palette.
+func (s Snapshot) Document() Document {
+	doc := Document{
+		Width:        s.width,
+		Height:       s.height,
+		Layers:       make([]LayerDocument, len(s.layers)),
+		Durations:    make([]int, len(s.frames)),
+		ActiveLayer:  s.active,
+		CurrentFrame: s.frame,
+	}
+	for i, l := range s.layers {
+		copied := l.clone()
+		if s.indexed {
+			for f, cel := range copied.cels {
+				copied.cels[f] = resolveCel(s.palette, cel)
+			}
+		}
+		doc.Layers[i] = LayerDocument{
+			Name:    copied.name,
+			Visible: copied.visible,
+			Opacity: copied.opacity,
+			Locked:  copied.locked,
+			Blend:   copied.blend,
+			Cels:    copied.cels,
+		}
+	}
+	for i, f := range s.frames {
+		doc.Durations[i] = f.duration
+	}
+	return doc
+}
+
+// Snapshot validates the document and converts it back into a snapshot.
+func (d Document) Snapshot() (Snapshot, error) {
+	invalid := func(format string, args ...any) (Snapshot, error) {
+		return Snapshot{}, Error{Code: "invalid_args", Message: fmt.Sprintf(format, args...)}
+	}
+	if d.Width <= 0 || d.Height <= 0 {
+		return invalid("canvas dimensions must be positive")
+	}
+	if len(d.Layers) == 0 || d.ActiveLayer < 0 || d.ActiveLayer >= len(d.Layers) {
+		return invalid("document has no valid active layer")
+	}
+	if len(d.Durations) == 0 || d.CurrentFrame < 0 || d.CurrentFrame >= len(d.Durations) {
+		return invalid("document has no valid current frame")
+	}
+
+	snapshot := Snapshot{
+		width:  d.Width,
+		height: d.Height,
+		layers: make([]*layer, len(d.Layers)),
+		active: d.ActiveLayer,
+		frames: make([]frame, len(d.Durations)),
+		frame:  d.CurrentFrame,
+	}
+	for i, duration := range d.Durations {
+		if duration <= 0 {
+			return invalid("frame %d duration must be > 0", i)
+		}
+		snapshot.frames[i] = frame{duration: duration}
+	}
+	names := make(map[string]bool, len(d.Layers))
+	for i, l := range d.Layers {
+		if names[l.Name] {
+			return invalid("duplicate layer name %q", l.Name)
+		}
+		names[l.Name] = true
+		if l.Opacity < 0 || l.Opacity > 100 {
+			return invalid("layer %q opacity out of range", l.Name)
+		}
+		if _, err := ParseBlendMode(string(l.Blend)); err != nil {
+			return Snapshot{}, err
+		}
+		if len(l.Cels) != len(d.Durations) {
+			return invalid("layer %q has %d cels for %d frames", l.Name, len(l.Cels), len(d.Durations))
+		}
+		copied := &layer{
+			name:    l.Name,
+			visible: l.Visible,
+			opacity: l.Opacity,
+			locked:  l.Locked,
+			blend:   l.Blend,
+			cels:    make([][]color.RGBA, len(l.Cels)),
+		}
+		for f, cel := range l.Cels {
+			if len(cel) != d.Width*d.Height {
+				return invalid("layer %q frame %d has %d pixels, want %d", l.Name, f, len(cel), d.Width*d.Height)
+			}
+			copied.cels[f] = cloneCel(cel)
+		}
+		snapshot.layers[i] = copied
+	}
+	return snapshot, nil
+}
+
+// Load replaces the whole canvas state with the snapshot, including its dimensions.
+func (c *Canvas) Load(snapshot Snapshot) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load(snapshot)
+}
+
+// load is Load for callers that already hold the write lock.
+func (c *Canvas) load(snapshot Snapshot) error {
+	c.recordShape()
+	width, height := c.width, c.height
+	c.width, c.height = snapshot.width, snapshot.height
+	if err := c.restore(snapshot); err != nil {
+		c.width, c.height = width, height
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SheetAtlas describes where each frame sits in an exported sprite sheet.
+type SheetAtlas struct {
+	Image       string            `json:"image"`
+	FrameWidth  int               `json:"frameWidth"`
+	FrameHeight int               `json:"frameHeight"`
+	Rows        int               `json:"rows"`
+	Cols        int               `json:"cols"`
+	Frames      []SheetAtlasFrame `json:"frames"`
+}
+
+// SheetAtlasFrame is the rectangle and display time of one frame in a sprite sheet.
+type SheetAtlasFrame struct {
+	Index    int `json:"index"`
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	W        int `json:"w"`
+	H        int `json:"h"`
+	Duration int `json:"duration"`
+}
+
+// ExportGIF writes every frame's composite to an animated, looping GIF.
+// Pixels with alpha below 128 become transparent.
+func (c *Canvas) ExportGIF(path string) error {
+	c.mu.RLock()
+	composites := make([][]color.RGBA, len(c.frames))
+	delays := make([]int, len(c.frames))
+	for i, f := range c.frames {
+		composites[i] = c.composite(i)
+		delays[i] = max(1, (f.duration+5)/10)
+	}
+	width, height := c.width, c.height
+	c.mu.RUnlock()
+
+	pal := gifPalette(composites)
+	anim := &gif.GIF{Delay: delays}
+	for _, pixels := range composites {
+		img := image.NewPaletted(image.Rect(0, 0, width, height), pal)
+		for i, value := range pixels {
+			if value.A < 128 {
+				img.Pix[i] = 0
+				continue
+			}
+			value.A = 255
+			img.Pix[i] = uint8(pal.Index(value))
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	if err := gif.EncodeAll(file, anim); err != nil {
+		_ = file.Close()
+		return Error{Code: "io", Message: err.Error()}
+	}
+	if err := file.Close(); err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	return nil
+}
+
+// gifPalette returns a transparent entry followed by the exact opaque colors used,
+// falling back to the web-safe palette when there are more than 255 of them.
+func gifPalette(frames [][]color.RGBA) color.Palette {
+	pal := color.Palette{color.RGBA{}}
+	seen := make(map[color.RGBA]bool)
+	for _, pixels := range frames {
+		for _, value := range pixels {
+			if value.A < 128 {
+				continue
+			}
+			value.A = 255
+			if seen[value] {
+				continue
+			}
+			if len(pal) == 256 {
+				return append(color.Palette{color.RGBA{}}, palette.WebSafe...)
+			}
+			seen[value] = true
+			pal = append(pal, value)
+		}
+	}
+	return pal
+}
+
+// ExportSheet lays the frames out row by row in a rows x cols PNG grid and writes
+// a JSON atlas with the same base name next to it. It returns the atlas path. The
+// grid must hold every frame without a fully empty row or column.
+func (c *Canvas) ExportSheet(path string, rows, cols int) (string, error) {
+	if rows <= 0 || cols <= 0 {
+		return "", Error{Code: "invalid_args", Message: "sheet rows and cols must be positive"}
+	}
+	c.mu.RLock()
+	count := len(c.frames)
+	if rows > count || cols > count || (rows-1)*cols >= count {
+		c.mu.RUnlock()
+		return "", Error{Code: "invalid_args", Message: fmt.Sprintf("%dx%d sheet leaves empty rows or columns for %d frames", rows, cols, count)}
+	}
+	if count > rows*cols {
+		c.mu.RUnlock()
+		return "", Error{Code: "invalid_args", Message: fmt.Sprintf("%dx%d sheet cannot hold %d frames", rows, cols, count)}
+	}
+	width, height := c.width, c.height
+	sheet := image.NewNRGBA(image.Rect(0, 0, cols*width, rows*height))
+	atlas := SheetAtlas{
+		Image:       filepath.Base(path),
+		FrameWidth:  width,
+		FrameHeight: height,
+		Rows:        rows,
+		Cols:        cols,
+		Frames:      make([]SheetAtlasFrame, len(c.frames)),
+	}
+	for i, f := range c.frames {
+		originX, originY := (i%cols)*width, (i/cols)*height
+		pixels := c.composite(i)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sheet.SetNRGBA(originX+x, originY+y, color.NRGBA(pixels[y*width+x]))
+			}
+		}
+		atlas.Frames[i] = SheetAtlasFrame{Index: i, X: originX, Y: originY, W: width, H: height, Duration: f.duration}
+	}
+	c.mu.RUnlock()
+
+	if err := writePNG(path, sheet); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(atlas, "", "  ")
+	if err != nil {
+		return "", Error{Code: "io", Message: err.Error()}
+	}
+	atlasPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+	if err := os.WriteFile(atlasPath, append(data, '\n'), 0o644); err != nil {
+		return "", Error{Code: "io", Message: err.Error()}
+	}
+	return atlasPath, nil
+}
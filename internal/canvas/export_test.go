@@ -1,7 +1,9 @@
 package canvas
 
 import (
+	"encoding/json"
 	"image/color"
+	"image/gif"
 	"image/png"
 	"os"
 	"path/filepath"
@@ -79,3 +81,116 @@ func TestCanvasExportPNGIOError(t *testing.T) {
 		t.Fatalf("expected io error, got %v", err)
 	}
 }
+
+func TestCanvasExportGIF(t *testing.T) {
+	c := threeFrameCanvas(t)
+	if err := c.SetFrameDuration(1, 250); err != nil {
+		t.Fatalf("unexpected duration error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "anim.gif")
+	if err := c.ExportGIF(path); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open gif: %v", err)
+	}
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatalf("failed to decode gif: %v", err)
+	}
+
+	if len(anim.Image) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(anim.Image))
+	}
+	wantDelays := []int{10, 25, 10}
+	for i, want := range wantDelays {
+		if anim.Delay[i] != want {
+			t.Fatalf("frame %d: expected delay %d, got %d", i, want, anim.Delay[i])
+		}
+	}
+	r, _, _, a := anim.Image[0].At(0, 0).RGBA()
+	if r>>8 != 255 || a>>8 != 255 {
+		t.Fatalf("expected opaque red at frame 0, got r=%d a=%d", r>>8, a>>8)
+	}
+	if _, _, _, a := anim.Image[0].At(1, 0).RGBA(); a != 0 {
+		t.Fatalf("expected transparent pixel, got alpha %d", a)
+	}
+}
+
+func TestCanvasExportSheet(t *testing.T) {
+	c := threeFrameCanvas(t)
+	path := filepath.Join(t.TempDir(), "walk.png")
+
+	atlasPath, err := c.ExportSheet(path, 2, 2)
+	if err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(path), "walk.json"); atlasPath != want {
+		t.Fatalf("expected atlas %q, got %q", want, atlasPath)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open sheet: %v", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("failed to decode sheet: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 4 || bounds.Dy() != 2 {
+		t.Fatalf("expected 4x2 sheet, got %v", bounds)
+	}
+	if got := color.RGBAModel.Convert(img.At(0, 1)).(color.RGBA); got != (color.RGBA{B: 255, A: 255}) {
+		t.Fatalf("expected third frame in second row, got %v", got)
+	}
+
+	data, err := os.ReadFile(atlasPath)
+	if err != nil {
+		t.Fatalf("failed to read atlas: %v", err)
+	}
+	var atlas SheetAtlas
+	if err := json.Unmarshal(data, &atlas); err != nil {
+		t.Fatalf("failed to parse atlas: %v", err)
+	}
+	if atlas.Image != "walk.png" || atlas.Rows != 2 || atlas.Cols != 2 || len(atlas.Frames) != 3 {
+		t.Fatalf("unexpected atlas header: %+v", atlas)
+	}
+	want := SheetAtlasFrame{Index: 2, X: 0, Y: 1, W: 2, H: 1, Duration: DefaultFrameDuration}
+	if atlas.Frames[2] != want {
+		t.Fatalf("expected %+v, got %+v", want, atlas.Frames[2])
+	}
+
+	if _, err := c.ExportSheet(path, 1, 2); err == nil {
+		t.Fatalf("expected error when the grid is too small")
+	}
+	for _, size := range [][2]int{{3, 2}, {1, 4}, {1 << 20, 1 << 20}} {
+		if _, err := c.ExportSheet(path, size[0], size[1]); err == nil {
+			t.Fatalf("expected error for oversized %dx%d grid", size[0], size[1])
+		} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+			t.Fatalf("expected invalid_args for %dx%d grid, got %v", size[0], size[1], err)
+		}
+	}
+}
+
+// threeFrameCanvas returns a 2x1 canvas with red, green and blue at (0,0) on frames 0-2.
+func threeFrameCanvas(t *testing.T) *Canvas {
+	t.Helper()
+	c, err := New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, value := range []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}} {
+		if i > 0 {
+			c.AddFrame()
+		}
+		if err := c.SetPixel(0, 0, value); err != nil {
+			t.Fatalf("unexpected set error: %v", err)
+		}
+	}
+	return c
+}
@@ -0,0 +1,101 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// FillOptions configures flood fill matching.
+type FillOptions struct {
+	// Connectivity is 4 (edge neighbors) or 8 (edge and corner neighbors).
+	Connectivity int
+	// Tolerance is the maximum per-channel difference from the seed color.
+	Tolerance int
+	// Global replaces every matching pixel instead of the contiguous region.
+	Global bool
+}
+
+// DefaultFillOptions returns 4-connected, exact-match contiguous fill options.
+func DefaultFillOptions() FillOptions {
+	return FillOptions{Connectivity: 4}
+}
+
+// FloodFill replaces the region matching the color at (x,y) with the provided color.
+// Indexed canvases match on the colors the pixels resolve to.
+func (c *Canvas) FloodFill(x, y int, value color.RGBA, opts FillOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if opts.Connectivity != 4 && opts.Connectivity != 8 {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("connectivity must be 4 or 8, got %d", opts.Connectivity)}
+	}
+	if opts.Tolerance < 0 || opts.Tolerance > 255 {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("tolerance must be between 0 and 255, got %d", opts.Tolerance)}
+	}
+	seed, err := c.index(x, y)
+	if err != nil {
+		return err
+	}
+
+	pixels := c.pixels()
+	target := c.color(pixels[seed])
+	if opts.Global {
+		for i, current := range pixels {
+			if withinTolerance(c.color(current), target, opts.Tolerance) {
+				c.write(i, value)
+			}
+		}
+		c.dirty = true
+		return nil
+	}
+
+	visited := make([]bool, len(pixels))
+	visited[seed] = true
+	queue := []int{seed}
+	for len(queue) > 0 {
+		idx := queue[0]
+		queue = queue[1:]
+		c.write(idx, value)
+
+		px, py := idx%c.width, idx/c.width
+		for _, offset := range neighborOffsets(opts.Connectivity) {
+			nx, ny := px+offset[0], py+offset[1]
+			if nx < 0 || nx >= c.width || ny < 0 || ny >= c.height {
+				continue
+			}
+			next := ny*c.width + nx
+			if visited[next] || !withinTolerance(c.color(pixels[next]), target, opts.Tolerance) {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+		}
+	}
+	c.dirty = true
+	return nil
+}
+
+var (
+	offsets4 = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	offsets8 = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func neighborOffsets(connectivity int) [][2]int {
+	if connectivity == 8 {
+		return offsets8
+	}
+	return offsets4
+}
+
+func withinTolerance(a, b color.RGBA, tolerance int) bool {
+	return absInt(int(a.R)-int(b.R)) <= tolerance &&
+		absInt(int(a.G)-int(b.G)) <= tolerance &&
+		absInt(int(a.B)-int(b.B)) <= tolerance &&
+		absInt(int(a.A)-int(b.A)) <= tolerance
+}
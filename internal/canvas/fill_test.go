@@ -0,0 +1,192 @@
+package canvas
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCanvasFloodFillContiguous(t *testing.T) {
+	c, err := New(5, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wall := color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	if err := c.Line(2, 0, 2, 4, wall); err != nil {
+		t.Fatalf("unexpected line error: %v", err)
+	}
+
+	if err := c.FloodFill(0, 0, red, DefaultFillOptions()); err != nil {
+		t.Fatalf("unexpected fill error: %v", err)
+	}
+
+	for y := 0; y < c.Height(); y++ {
+		for x := 0; x < c.Width(); x++ {
+			got, err := c.GetPixel(x, y)
+			if err != nil {
+				t.Fatalf("unexpected get error at (%d,%d): %v", x, y, err)
+			}
+			var want color.RGBA
+			switch {
+			case x < 2:
+				want = red
+			case x == 2:
+				want = wall
+			}
+			if got != want {
+				t.Fatalf("expected %v at (%d,%d), got %v", want, x, y, got)
+			}
+		}
+	}
+}
+
+func TestCanvasFloodFillConnectivity(t *testing.T) {
+	wall := color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+
+	tests := []struct {
+		name         string
+		connectivity int
+		wantCorner   color.RGBA
+	}{
+		{name: "4-connected stops at diagonal wall", connectivity: 4, wantCorner: color.RGBA{}},
+		{name: "8-connected leaks through diagonal wall", connectivity: 8, wantCorner: red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(4, 4)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Line(3, 0, 0, 3, wall); err != nil {
+				t.Fatalf("unexpected line error: %v", err)
+			}
+
+			opts := DefaultFillOptions()
+			opts.Connectivity = tt.connectivity
+			if err := c.FloodFill(0, 0, red, opts); err != nil {
+				t.Fatalf("unexpected fill error: %v", err)
+			}
+
+			got, err := c.GetPixel(3, 3)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			if got != tt.wantCorner {
+				t.Fatalf("expected %v at (3,3), got %v", tt.wantCorner, got)
+			}
+		})
+	}
+}
+
+func TestCanvasFloodFillTolerance(t *testing.T) {
+	c, err := New(3, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	base := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+	near := color.RGBA{R: 110, G: 95, B: 100, A: 255}
+	far := color.RGBA{R: 140, G: 100, B: 100, A: 255}
+	blue := color.RGBA{R: 0, G: 0, B: 255, A: 255}
+	for x, value := range []color.RGBA{base, near, far} {
+		if err := c.SetPixel(x, 0, value); err != nil {
+			t.Fatalf("unexpected set error: %v", err)
+		}
+	}
+
+	opts := DefaultFillOptions()
+	opts.Tolerance = 10
+	if err := c.FloodFill(0, 0, blue, opts); err != nil {
+		t.Fatalf("unexpected fill error: %v", err)
+	}
+
+	for x, want := range []color.RGBA{blue, blue, far} {
+		got, err := c.GetPixel(x, 0)
+		if err != nil {
+			t.Fatalf("unexpected get error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("expected %v at (%d,0), got %v", want, x, got)
+		}
+	}
+}
+
+func TestCanvasFloodFillGlobal(t *testing.T) {
+	c, err := New(5, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wall := color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	green := color.RGBA{R: 0, G: 255, B: 0, A: 255}
+	if err := c.SetPixel(2, 0, wall); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+
+	opts := DefaultFillOptions()
+	opts.Global = true
+	if err := c.FloodFill(0, 0, green, opts); err != nil {
+		t.Fatalf("unexpected fill error: %v", err)
+	}
+
+	for x := 0; x < c.Width(); x++ {
+		got, err := c.GetPixel(x, 0)
+		if err != nil {
+			t.Fatalf("unexpected get error: %v", err)
+		}
+		want := green
+		if x == 2 {
+			want = wall
+		}
+		if got != want {
+			t.Fatalf("expected %v at (%d,0), got %v", want, x, got)
+		}
+	}
+}
+
+func TestCanvasFloodFillSameColorIsNoop(t *testing.T) {
+	c, err := New(3, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.FloodFill(1, 1, color.RGBA{}, DefaultFillOptions()); err != nil {
+		t.Fatalf("unexpected fill error: %v", err)
+	}
+	got, err := c.GetPixel(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if got != (color.RGBA{}) {
+		t.Fatalf("expected zero color, got %v", got)
+	}
+}
+
+func TestCanvasFloodFillErrors(t *testing.T) {
+	c, err := New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.FloodFill(2, 0, color.RGBA{}, DefaultFillOptions()); err == nil {
+		t.Fatalf("expected out_of_bounds error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "out_of_bounds" {
+		t.Fatalf("expected out_of_bounds, got %v", err)
+	}
+
+	invalid := []FillOptions{
+		{Connectivity: 6},
+		{Connectivity: 4, Tolerance: -1},
+		{Connectivity: 4, Tolerance: 256},
+	}
+	for _, opts := range invalid {
+		if err := c.FloodFill(0, 0, color.RGBA{}, opts); err == nil {
+			t.Fatalf("expected invalid_args error for %+v", opts)
+		} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+			t.Fatalf("expected invalid_args for %+v, got %v", opts, err)
+		}
+	}
+}
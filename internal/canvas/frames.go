@@ -0,0 +1,181 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// DefaultFrameDuration is the display time in milliseconds given to new frames.
+const DefaultFrameDuration = 100
+
+// FrameInfo describes an animation frame without its pixels.
+type FrameInfo struct {
+	Index    int
+	Duration int
+	Active   bool
+}
+
+type frame struct {
+	duration int
+}
+
+// Frames lists the animation timeline in playback order.
+func (c *Canvas) Frames() []FrameInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	infos := make([]FrameInfo, len(c.frames))
+	for i, f := range c.frames {
+		infos[i] = FrameInfo{Index: i, Duration: f.duration, Active: i == c.frame}
+	}
+	return infos
+}
+
+// AddFrame inserts an empty frame after the current frame and makes it current.
+func (c *Canvas) AddFrame() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insertFrame(c.frame+1, frame{duration: DefaultFrameDuration}, func(*layer) []color.RGBA {
+		return make([]color.RGBA, c.width*c.height)
+	})
+	return c.frame
+}
+
+// DuplicateFrame inserts a copy of a frame directly after it and makes the copy current.
+func (c *Canvas) DuplicateFrame(index int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkFrame(index); err != nil {
+		return 0, err
+	}
+	c.insertFrame(index+1, c.frames[index], func(l *layer) []color.RGBA {
+		return cloneCel(l.cels[index])
+	})
+	return c.frame, nil
+}
+
+// insertFrame adds a frame at index with a cel per layer and selects it;
+// callers must hold the write lock.
+func (c *Canvas) insertFrame(index int, f frame, cel func(*layer) []color.RGBA) {
+	c.recordShape()
+	for _, l := range c.layers {
+		l.cels = append(l.cels, nil)
+		copy(l.cels[index+1:], l.cels[index:])
+		l.cels[index] = cel(l)
+	}
+	c.frames = append(c.frames, frame{})
+	copy(c.frames[index+1:], c.frames[index:])
+	c.frames[index] = f
+	c.frame = index
+	c.dirty = true
+}
+
+// DeleteFrame removes a frame. The last remaining frame cannot be removed.
+func (c *Canvas) DeleteFrame(index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkFrame(index); err != nil {
+		return err
+	}
+	if len(c.frames) == 1 {
+		return Error{Code: "invalid_args", Message: "cannot delete the only frame"}
+	}
+	c.recordShape()
+	for _, l := range c.layers {
+		l.cels = append(l.cels[:index], l.cels[index+1:]...)
+	}
+	c.frames = append(c.frames[:index], c.frames[index+1:]...)
+	if c.frame > index || c.frame == len(c.frames) {
+		c.frame--
+	}
+	c.dirty = true
+	return nil
+}
+
+// SelectFrame makes a frame the target of drawing operations.
+func (c *Canvas) SelectFrame(index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkFrame(index); err != nil {
+		return err
+	}
+	if c.frame != index {
+		c.frame = index
+		c.dirty = true
+	}
+	return nil
+}
+
+// MoveFrame moves a frame to a new timeline position, keeping the current frame selected.
+func (c *Canvas) MoveFrame(from, to int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkFrame(from); err != nil {
+		return err
+	}
+	if err := c.checkFrame(to); err != nil {
+		return err
+	}
+	c.recordShape()
+	for _, l := range c.layers {
+		l.cels = moveItem(l.cels, from, to)
+	}
+	c.frames = moveItem(c.frames, from, to)
+	switch {
+	case c.frame == from:
+		c.frame = to
+	case from < c.frame && c.frame <= to:
+		c.frame--
+	case to <= c.frame && c.frame < from:
+		c.frame++
+	}
+	c.dirty = true
+	return nil
+}
+
+// SetFrameDuration sets how long a frame is shown, in milliseconds.
+func (c *Canvas) SetFrameDuration(index, duration int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkFrame(index); err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return Error{Code: "invalid_args", Message: "frame duration must be > 0"}
+	}
+	c.frames[index].duration = duration
+	c.dirty = true
+	return nil
+}
+
+// SetPlayback enables or disables animation playback in the renderer.
+func (c *Canvas) SetPlayback(playing bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.playing != playing {
+		c.playing = playing
+		c.dirty = true
+	}
+}
+
+// Playing reports whether animation playback is enabled.
+func (c *Canvas) Playing() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.playing
+}
+
+func (c *Canvas) checkFrame(index int) error {
+	if index < 0 || index >= len(c.frames) {
+		return Error{Code: "invalid_frame", Message: fmt.Sprintf("frame index %d out of range 0-%d", index, len(c.frames)-1)}
+	}
+	return nil
+}
+
+func moveItem[T any](items []T, from, to int) []T {
+	moved := items[from]
+	items = append(items[:from], items[from+1:]...)
+	items = append(items, moved)
+	copy(items[to+1:], items[to:])
+	items[to] = moved
+	return items
+}
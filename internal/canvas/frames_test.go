@@ -0,0 +1,155 @@
+package canvas
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCanvasFramesHaveIndependentCels(t *testing.T) {
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	if err := c.SetPixel(0, 0, red); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if got := c.AddFrame(); got != 1 {
+		t.Fatalf("expected new frame at index 1, got %d", got)
+	}
+	assertPixel(t, c, 0, 0, color.RGBA{})
+	if err := c.SetPixel(0, 0, blue); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+
+	if err := c.SelectFrame(0); err != nil {
+		t.Fatalf("unexpected select error: %v", err)
+	}
+	assertPixel(t, c, 0, 0, red)
+
+	if _, err := c.AddLayer("top"); err != nil {
+		t.Fatalf("unexpected add layer error: %v", err)
+	}
+	if err := c.SelectFrame(1); err != nil {
+		t.Fatalf("unexpected select error: %v", err)
+	}
+	got, err := c.GetLayerPixel("top", 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if got != (color.RGBA{}) {
+		t.Fatalf("expected new layer to have an empty cel on every frame, got %v", got)
+	}
+}
+
+func TestCanvasFrameTimeline(t *testing.T) {
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	shades := []color.RGBA{{R: 10, A: 255}, {R: 20, A: 255}, {R: 30, A: 255}}
+	for i, shade := range shades {
+		if i > 0 {
+			c.AddFrame()
+		}
+		if err := c.SetPixel(0, 0, shade); err != nil {
+			t.Fatalf("unexpected set error: %v", err)
+		}
+	}
+
+	dup, err := c.DuplicateFrame(0)
+	if err != nil {
+		t.Fatalf("unexpected dup error: %v", err)
+	}
+	if dup != 1 {
+		t.Fatalf("expected duplicate at index 1, got %d", dup)
+	}
+	assertPixel(t, c, 0, 0, shades[0])
+
+	if err := c.MoveFrame(1, 3); err != nil {
+		t.Fatalf("unexpected move error: %v", err)
+	}
+	if err := c.SetFrameDuration(3, 250); err != nil {
+		t.Fatalf("unexpected duration error: %v", err)
+	}
+	if err := c.DeleteFrame(0); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+
+	frames := c.Frames()
+	want := []FrameInfo{
+		{Index: 0, Duration: DefaultFrameDuration},
+		{Index: 1, Duration: DefaultFrameDuration},
+		{Index: 2, Duration: 250, Active: true},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("expected %d frames, got %+v", len(want), frames)
+	}
+	for i := range want {
+		if frames[i] != want[i] {
+			t.Fatalf("expected frame %+v, got %+v", want[i], frames[i])
+		}
+	}
+	for i, shade := range []color.RGBA{shades[1], shades[2], shades[0]} {
+		if err := c.SelectFrame(i); err != nil {
+			t.Fatalf("unexpected select error: %v", err)
+		}
+		assertPixel(t, c, 0, 0, shade)
+	}
+}
+
+func TestCanvasFrameErrors(t *testing.T) {
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.DeleteFrame(0); err == nil {
+		t.Fatalf("expected error deleting the only frame")
+	}
+	if err := c.SelectFrame(1); err == nil {
+		t.Fatalf("expected invalid_frame error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_frame" {
+		t.Fatalf("expected invalid_frame, got %v", err)
+	}
+	if err := c.SetFrameDuration(0, 0); err == nil {
+		t.Fatalf("expected invalid duration error")
+	}
+}
+
+func TestCanvasRenderSnapshotIncludesFramesWhilePlaying(t *testing.T) {
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.AddFrame()
+
+	if frames := c.RenderSnapshot().Frames; frames != nil {
+		t.Fatalf("expected no frames while paused, got %d", len(frames))
+	}
+	c.SetPlayback(true)
+	if !c.Dirty() {
+		t.Fatalf("expected playback toggle to mark canvas dirty")
+	}
+	frames := c.RenderSnapshot().Frames
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames while playing, got %d", len(frames))
+	}
+	if frames[0].Duration.Milliseconds() != DefaultFrameDuration {
+		t.Fatalf("expected default duration, got %v", frames[0].Duration)
+	}
+}
+
+func assertPixel(t *testing.T, c *Canvas, x, y int, want color.RGBA) {
+	t.Helper()
+	got, err := c.GetPixel(x, y)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("expected %v at (%d,%d), got %v", want, x, y, got)
+	}
+}
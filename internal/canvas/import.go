@@ -0,0 +1,88 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+// ReadPNG decodes a PNG file. Open and decode failures are reported as io errors.
+func ReadPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, Error{Code: "io", Message: err.Error()}
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, Error{Code: "io", Message: "decode " + path + ": " + err.Error()}
+	}
+	return img, nil
+}
+
+// NewFromImage creates a single-layer, single-frame canvas sized to img and
+// initialized from its pixels.
+func NewFromImage(img image.Image) (*Canvas, error) {
+	bounds := img.Bounds()
+	c, err := New(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.paste(img, 0, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewIndexedFromImage is NewFromImage for an indexed canvas, whose palette is
+// the image's colors in the order they first appear. An image with more
+// colors than an indexed canvas can use fails with off_palette.
+func NewIndexedFromImage(img image.Image) (*Canvas, error) {
+	c, err := NewFromImage(img)
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := c.Snapshot().Indexed(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Load(indexed); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Paste copies img onto the active layer with its top-left corner at (x,y),
+// replacing the covered pixels and clipping anything outside the canvas.
+func (c *Canvas) Paste(img image.Image, x, y int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	return c.paste(img, x, y)
+}
+
+// paste converts every source pixel to straight (non-premultiplied) 8-bit RGBA,
+// which handles paletted, grayscale and 16-bit images alike; callers must hold the lock.
+// On an indexed canvas every pixel must be a palette color, and nothing is
+// pasted if one is not.
+func (c *Canvas) paste(img image.Image, x, y int) error {
+	bounds := img.Bounds()
+	values := make([]color.RGBA, 0, bounds.Dx()*bounds.Dy())
+	for sy := bounds.Min.Y; sy < bounds.Max.Y; sy++ {
+		for sx := bounds.Min.X; sx < bounds.Max.X; sx++ {
+			value, err := c.encode(color.RGBA(color.NRGBAModel.Convert(img.At(sx, sy)).(color.NRGBA)))
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+	}
+	for i, value := range values {
+		c.plot(x+i%bounds.Dx(), y+i/bounds.Dx(), value)
+	}
+	c.dirty = true
+	return nil
+}
@@ -0,0 +1,156 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPNGConvertsImageTypes(t *testing.T) {
+	paletted := image.NewPaletted(image.Rect(0, 0, 2, 1), color.Palette{
+		color.NRGBA{},
+		color.NRGBA{R: 255, G: 128, A: 255},
+	})
+	paletted.SetColorIndex(1, 0, 1)
+
+	deep := image.NewNRGBA64(image.Rect(0, 0, 2, 1))
+	deep.SetNRGBA64(1, 0, color.NRGBA64{R: 0xffff, G: 0x8080, B: 0, A: 0xffff})
+
+	gray := image.NewGray16(image.Rect(0, 0, 2, 1))
+	gray.SetGray16(1, 0, color.Gray16{Y: 0x8080})
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	translucent.SetNRGBA(1, 0, color.NRGBA{R: 255, G: 128, A: 128})
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want color.RGBA
+	}{
+		{name: "paletted", img: paletted, want: color.RGBA{R: 255, G: 128, A: 255}},
+		{name: "16-bit", img: deep, want: color.RGBA{R: 255, G: 128, A: 255}},
+		{name: "16-bit gray", img: gray, want: color.RGBA{R: 128, G: 128, B: 128, A: 255}},
+		{name: "translucent", img: translucent, want: color.RGBA{R: 255, G: 128, A: 128}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := ReadPNG(writeTestPNG(t, tt.img))
+			if err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			c, err := NewFromImage(img)
+			if err != nil {
+				t.Fatalf("unexpected canvas error: %v", err)
+			}
+			if c.Width() != 2 || c.Height() != 1 {
+				t.Fatalf("expected 2x1 canvas, got %dx%d", c.Width(), c.Height())
+			}
+			assertPixel(t, c, 1, 0, tt.want)
+		})
+	}
+}
+
+func TestReadPNGDecodeErrorIsIO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.png")
+	if err := os.WriteFile(path, []byte("not a png"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	for _, p := range []string{path, filepath.Join(t.TempDir(), "missing.png")} {
+		if _, err := ReadPNG(p); err == nil {
+			t.Fatalf("expected io error for %s", p)
+		} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "io" {
+			t.Fatalf("expected io, got %v", err)
+		}
+	}
+}
+
+func TestCanvasPasteClipsAtOffset(t *testing.T) {
+	c, err := New(4, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	if err := c.Paste(src, 3, -1); err != nil {
+		t.Fatalf("unexpected paste error: %v", err)
+	}
+	assertASCII(t, c, `
+...#
+....
+....`)
+}
+
+func TestCanvasExportImportRoundTrip(t *testing.T) {
+	c, err := New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	translucent := color.RGBA{R: 200, G: 40, B: 10, A: 100}
+	if err := c.SetPixel(0, 0, translucent); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "round.png")
+	if err := c.ExportPNG(path); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	img, err := ReadPNG(path)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	loaded, err := NewFromImage(img)
+	if err != nil {
+		t.Fatalf("unexpected canvas error: %v", err)
+	}
+	assertPixel(t, loaded, 0, 0, translucent)
+}
+
+func TestNewIndexedFromImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	img.SetNRGBA(2, 0, color.NRGBA{R: 255, A: 255})
+	img.SetNRGBA(1, 0, color.NRGBA{B: 255, A: 255})
+
+	c, err := NewIndexedFromImage(img)
+	if err != nil {
+		t.Fatalf("unexpected canvas error: %v", err)
+	}
+	if !c.Indexed() {
+		t.Fatalf("expected an indexed canvas")
+	}
+	palette := c.Palette()
+	if len(palette) != 2 || palette[0].Color != (color.RGBA{R: 255, A: 255}) || palette[1].Color != (color.RGBA{B: 255, A: 255}) {
+		t.Fatalf("expected the image colors in order of appearance, got %v", palette)
+	}
+	assertPixel(t, c, 2, 0, color.RGBA{R: 255, A: 255})
+	if _, err := c.AddLayer(""); err != nil {
+		t.Fatalf("unexpected layer error: %v", err)
+	}
+	assertPixel(t, c, 1, 0, color.RGBA{B: 255, A: 255})
+}
+
+func writeTestPNG(t *testing.T, img image.Image) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create png: %v", err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("failed to close png: %v", err)
+	}
+	return path
+}
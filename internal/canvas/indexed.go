@@ -0,0 +1,267 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+
+	pxcolor "pxcli/internal/color"
+)
+
+// MaxPaletteIndex is the highest palette index an indexed canvas can store.
+const MaxPaletteIndex = 254
+
+// An indexed canvas stores a palette index for every pixel instead of a color,
+// so editing the palette recolors every pixel that uses the edited entry. The
+// index plus one is kept in the R channel of an otherwise zero color.RGBA,
+// which lets drawing, undo history and snapshots handle both kinds of canvas
+// alike; only reads and exports resolve indices through the palette. The zero
+// pixel is transparent whatever the palette holds, so new canvases, layers and
+// frames start empty, and indices past the end of the palette also resolve to
+// transparent.
+
+// NewIndexed creates an indexed canvas with the provided dimensions and palette.
+func NewIndexed(width, height int, palette pxcolor.Palette) (*Canvas, error) {
+	c, err := New(width, height)
+	if err != nil {
+		return nil, err
+	}
+	c.indexed = true
+	c.palette = slices.Clone(palette)
+	return c, nil
+}
+
+// Indexed reports whether the canvas stores palette indices.
+func (c *Canvas) Indexed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.indexed
+}
+
+// Palette returns the palette an indexed canvas resolves its pixels with.
+func (c *Canvas) Palette() pxcolor.Palette {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.palette)
+}
+
+// SetPalette replaces the palette an indexed canvas resolves its pixels with.
+// With a nil mapping pixels keep their indices, so changing an entry recolors
+// every pixel using it. Otherwise pixels using old index i move to
+// mapping[i], and nothing changes if a pixel uses an index mapped to -1.
+// The palette is part of the recorded state, so undo restores it along with
+// the indices.
+func (c *Canvas) SetPalette(palette pxcolor.Palette, mapping []int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.indexed {
+		return Error{Code: "invalid_args", Message: "canvas is not indexed"}
+	}
+	remap := func(pixel color.RGBA) (color.RGBA, error) {
+		index := int(pixel.R) - 1
+		if mapping == nil || index < 0 {
+			return pixel, nil
+		}
+		if index >= len(mapping) {
+			return color.RGBA{}, nil
+		}
+		if mapping[index] < 0 {
+			return color.RGBA{}, Error{Code: "swatch_in_use", Message: fmt.Sprintf("swatch %d is used by pixels", index)}
+		}
+		return indexPixel(mapping[index]), nil
+	}
+	for _, l := range c.layers {
+		for _, cel := range l.cels {
+			for _, pixel := range cel {
+				if _, err := remap(pixel); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	c.recordShape()
+	for _, l := range c.layers {
+		for _, cel := range l.cels {
+			for idx, pixel := range cel {
+				cel[idx], _ = remap(pixel)
+			}
+		}
+	}
+	c.palette = slices.Clone(palette)
+	c.dirty = true
+	return nil
+}
+
+// Indexed returns the snapshot in indexed form against palette, such as a
+// snapshot read from a project file for an indexed canvas. Each color maps to
+// the first swatch with that color, and colors the palette lacks are appended
+// to it as unnamed swatches in the order they first appear; fully transparent
+// pixels need no swatch. A snapshot that is already indexed is returned as is.
+func (s Snapshot) Indexed(palette pxcolor.Palette) (Snapshot, error) {
+	if s.indexed {
+		return s, nil
+	}
+	palette = slices.Clone(palette)
+	converted := s
+	converted.layers = make([]*layer, len(s.layers))
+	for i, l := range s.layers {
+		copied := l.clone()
+		for _, cel := range copied.cels {
+			for idx, value := range cel {
+				if value.A > 0 && palette.Index(value) < 0 {
+					palette = append(palette, pxcolor.Swatch{Color: value})
+				}
+				pixel, err := encodeIndex(palette, value)
+				if err != nil {
+					return Snapshot{}, err
+				}
+				cel[idx] = pixel
+			}
+		}
+		converted.layers[i] = copied
+	}
+	converted.indexed = true
+	converted.palette = palette
+	return converted, nil
+}
+
+// PaintWithSwatch runs paint with indexed writes of the color of swatch index
+// storing that index rather than the first swatch with the same color, so a
+// drawing that names one of several equal swatches stays tied to it when the
+// palette changes. A negative index runs paint unchanged.
+func (c *Canvas) PaintWithSwatch(index int, paint func() error) error {
+	if index < 0 {
+		return paint()
+	}
+	c.mu.Lock()
+	c.swatch = index + 1
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.swatch = 0
+		c.mu.Unlock()
+	}()
+	return paint()
+}
+
+// encode converts a drawing color to the pixel stored in cels: the color
+// itself, or on an indexed canvas the index of the first swatch with that
+// color, or of the swatch PaintWithSwatch selected. Fully transparent colors
+// are stored as the transparent pixel and need no swatch. Callers must hold
+// the lock.
+func (c *Canvas) encode(value color.RGBA) (color.RGBA, error) {
+	if !c.indexed {
+		return value, nil
+	}
+	if index := c.swatch - 1; index >= 0 && index <= MaxPaletteIndex && index < len(c.palette) &&
+		value.A > 0 && c.palette[index].Color == value {
+		return indexPixel(index), nil
+	}
+	return encodeIndex(c.palette, value)
+}
+
+func encodeIndex(palette pxcolor.Palette, value color.RGBA) (color.RGBA, error) {
+	if value.A == 0 {
+		return color.RGBA{}, nil
+	}
+	index := palette.Index(value)
+	switch {
+	case index < 0:
+		return color.RGBA{}, Error{Code: "off_palette", Message: fmt.Sprintf("color %s is not in the palette", pxcolor.Format(value))}
+	case index > MaxPaletteIndex:
+		return color.RGBA{}, Error{Code: "off_palette", Message: fmt.Sprintf("color %s is past the %d swatches an indexed canvas can use", pxcolor.Format(value), MaxPaletteIndex+1)}
+	}
+	return indexPixel(index), nil
+}
+
+// color resolves a stored pixel to its color; callers must hold the lock.
+func (c *Canvas) color(pixel color.RGBA) color.RGBA {
+	if !c.indexed {
+		return pixel
+	}
+	return resolveIndex(c.palette, pixel)
+}
+
+// colors resolves a cel to a new slice of colors; callers must hold the lock.
+func (c *Canvas) colors(cel []color.RGBA) []color.RGBA {
+	if !c.indexed {
+		return cloneCel(cel)
+	}
+	return resolveCel(c.palette, cel)
+}
+
+func indexPixel(index int) color.RGBA {
+	return color.RGBA{R: uint8(index + 1)}
+}
+
+func resolveIndex(palette pxcolor.Palette, pixel color.RGBA) color.RGBA {
+	if index := int(pixel.R) - 1; index >= 0 && index < len(palette) {
+		return palette[index].Color
+	}
+	return color.RGBA{}
+}
+
+func resolveCel(palette pxcolor.Palette, cel []color.RGBA) []color.RGBA {
+	out := make([]color.RGBA, len(cel))
+	for i, pixel := range cel {
+		out[i] = resolveIndex(palette, pixel)
+	}
+	return out
+}
+
+// pngPalette is the palette of paletted exports, with straight alpha: the
+// transparent entry followed by the swatches, so that stored pixels are PNG
+// indices.
+func (c *Canvas) pngPalette() color.Palette {
+	entries := c.palette[:min(len(c.palette), MaxPaletteIndex+1)]
+	palette := make(color.Palette, len(entries)+1)
+	palette[0] = color.NRGBA{}
+	for i, swatch := range entries {
+		palette[i+1] = color.NRGBA(swatch.Color)
+	}
+	return palette
+}
+
+// palettedImage wraps stored pixels in a paletted image. Indices past the
+// palette resolve to transparent, so they are written as the transparent
+// entry. Callers must hold the lock.
+func (c *Canvas) palettedImage(indices []uint8) *image.Paletted {
+	palette := c.pngPalette()
+	img := image.NewPaletted(image.Rect(0, 0, c.width, c.height), palette)
+	for i, index := range indices {
+		if int(index) >= len(palette) {
+			index = 0
+		}
+		img.Pix[i] = index
+	}
+	return img
+}
+
+// compositeIndices flattens the visible layers of a frame to palette indices.
+// Where the composite is the color of the topmost layer drawn at a pixel, as
+// it is for opaque pixels on plain layers, that layer's index is kept; other
+// composite colors, produced by blending, use the closest swatch. Callers must
+// hold the lock.
+func (c *Canvas) compositeIndices(frame int) []uint8 {
+	palette := c.pngPalette()
+	indices := make([]uint8, c.width*c.height)
+	for i := range indices {
+		composite := c.compositeAt(frame, i)
+		top := -1
+		for l := len(c.layers) - 1; l >= 0; l-- {
+			if candidate := c.layers[l]; candidate.visible && candidate.opacity > 0 && c.color(candidate.cels[frame][i]).A > 0 {
+				top = l
+				break
+			}
+		}
+		if top >= 0 {
+			if pixel := c.layers[top].cels[frame][i]; c.color(pixel) == composite {
+				indices[i] = pixel.R
+				continue
+			}
+		}
+		indices[i] = uint8(palette.Index(color.NRGBA(composite)))
+	}
+	return indices
+}
@@ -0,0 +1,289 @@
+package canvas
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pxcolor "pxcli/internal/color"
+)
+
+var (
+	blank = color.RGBA{}
+	ink   = color.RGBA{R: 20, G: 20, B: 40, A: 255}
+	skin  = color.RGBA{R: 230, G: 180, B: 140, A: 255}
+)
+
+// swatches builds an unnamed palette from colors.
+func swatches(colors ...color.RGBA) pxcolor.Palette {
+	palette := make(pxcolor.Palette, len(colors))
+	for i, value := range colors {
+		palette[i].Color = value
+	}
+	return palette
+}
+
+func newIndexedCanvas(t *testing.T) *Canvas {
+	t.Helper()
+	c, err := NewIndexed(3, 2, swatches(blank, ink, skin))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestIndexedCanvasResolvesPalette(t *testing.T) {
+	c := newIndexedCanvas(t)
+	if err := c.FillRect(0, 0, 2, 2, skin); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPixel(2, 1, ink); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var canvasErr Error
+	if err := c.SetPixel(0, 0, color.RGBA{R: 1, A: 255}); !errors.As(err, &canvasErr) || canvasErr.Code != "off_palette" {
+		t.Fatalf("expected off_palette, got %v", err)
+	}
+	if got, _ := c.GetPixel(0, 0); got != skin {
+		t.Fatalf("expected skin, got %v", got)
+	}
+
+	recolored := color.RGBA{R: 120, G: 80, B: 60, A: 255}
+	if err := c.SetPalette(swatches(blank, ink, recolored), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.Dirty() {
+		t.Fatalf("expected a palette change to mark the canvas dirty")
+	}
+	snapshot := c.RenderSnapshot()
+	for i, want := range []color.RGBA{recolored, recolored, blank, recolored, recolored, ink} {
+		got := color.RGBA{R: snapshot.Pixels[i*4], G: snapshot.Pixels[i*4+1], B: snapshot.Pixels[i*4+2], A: snapshot.Pixels[i*4+3]}
+		if got != want {
+			t.Fatalf("pixel %d: expected %v, got %v", i, want, got)
+		}
+	}
+
+	if err := c.SetPalette(swatches(blank, ink), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := c.GetPixel(0, 0); got != blank {
+		t.Fatalf("expected an index past the palette to be transparent, got %v", got)
+	}
+}
+
+func TestIndexedCanvasStartsTransparent(t *testing.T) {
+	c, err := NewIndexed(2, 1, swatches(ink))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := c.GetPixel(0, 0); got != blank {
+		t.Fatalf("expected a new indexed canvas to be transparent, got %v", got)
+	}
+	if err := c.Clear(ink); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.AddLayer("top"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPixel(1, 0, blank); err != nil {
+		t.Fatalf("expected erasing to need no transparent swatch, got %v", err)
+	}
+	if got, _ := c.GetPixel(0, 0); got != ink {
+		t.Fatalf("expected a new layer not to cover the one below, got %v", got)
+	}
+	c.AddFrame()
+	if got, _ := c.GetPixel(0, 0); got != blank {
+		t.Fatalf("expected a new frame to be transparent, got %v", got)
+	}
+}
+
+func TestIndexedCanvasUndoKeepsIndices(t *testing.T) {
+	c := newIndexedCanvas(t)
+	c.BeginChange()
+	if err := c.FloodFill(0, 0, ink, DefaultFillOptions()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	change := c.EndChange()
+
+	recolored := color.RGBA{G: 200, A: 255}
+	if err := c.SetPalette(swatches(blank, recolored, skin), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.RevertChange(change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.ReapplyChange(change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := c.GetPixel(2, 1); got != recolored {
+		t.Fatalf("expected redo to restore index 1 in its new color, got %v", got)
+	}
+}
+
+func TestIndexedCanvasRemapsPalette(t *testing.T) {
+	c := newIndexedCanvas(t)
+	if err := c.SetPixel(0, 0, skin); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var canvasErr Error
+	if err := c.SetPalette(swatches(blank, ink), []int{0, 1, -1}); !errors.As(err, &canvasErr) || canvasErr.Code != "swatch_in_use" {
+		t.Fatalf("expected swatch_in_use for dropping a used swatch, got %v", err)
+	}
+
+	c.BeginChange()
+	if err := c.SetPalette(swatches(ink, skin), []int{-1, 0, 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	change := c.EndChange()
+	if got, _ := c.GetPixel(0, 0); got != skin {
+		t.Fatalf("expected removing an unused swatch to keep colors, got %v", got)
+	}
+	if err := c.RevertChange(change); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.Palette(); len(got) != 3 {
+		t.Fatalf("expected undo to restore the palette, got %v", got)
+	}
+	if got, _ := c.GetPixel(0, 0); got != skin {
+		t.Fatalf("expected undo to restore the indices, got %v", got)
+	}
+}
+
+func TestIndexedCanvasPaintWithSwatch(t *testing.T) {
+	c, err := NewIndexed(2, 1, swatches(ink, ink))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.PaintWithSwatch(1, func() error { return c.SetPixel(0, 0, ink) }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPixel(1, 0, ink); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPalette(swatches(ink, skin), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for x, want := range []color.RGBA{skin, ink} {
+		if got, _ := c.GetPixel(x, 0); got != want {
+			t.Fatalf("pixel (%d,0) = %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestIndexedCanvasExportPaletted(t *testing.T) {
+	c := newIndexedCanvas(t)
+	if err := c.Line(0, 0, 2, 0, skin); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.AddLayer("shade"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPixel(1, 0, ink); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "indexed.png")
+	if err := c.ExportPNG(path); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open png: %v", err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("failed to decode png: %v", err)
+	}
+	img, ok := decoded.(*image.Paletted)
+	if !ok {
+		t.Fatalf("expected a paletted png, got %T", decoded)
+	}
+	if len(img.Palette) != 4 {
+		t.Fatalf("expected the transparent entry and 3 swatches, got %d entries", len(img.Palette))
+	}
+	if want := []uint8{3, 2, 3, 0, 0, 0}; string(img.Pix) != string(want) {
+		t.Fatalf("expected indices %v, got %v", want, img.Pix)
+	}
+}
+
+func TestIndexedSnapshotDocumentRoundTrip(t *testing.T) {
+	c := newIndexedCanvas(t)
+	if err := c.SetPixel(1, 1, skin); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc := c.Snapshot().Document()
+	if got := doc.Layers[0].Cels[0][4]; got != skin {
+		t.Fatalf("expected documents to hold colors, got %v", got)
+	}
+
+	snapshot, err := doc.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Load(snapshot); err == nil {
+		t.Fatalf("expected an indexed canvas to refuse a color snapshot")
+	}
+	converted, err := snapshot.Indexed(c.Palette())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Load(converted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := c.GetPixel(1, 1); got != skin {
+		t.Fatalf("expected skin after loading, got %v", got)
+	}
+
+	missing := color.RGBA{B: 1, A: 255}
+	doc.Layers[0].Cels[0][0] = missing
+	snapshot, err = doc.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	converted, err = snapshot.Indexed(c.Palette())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Load(converted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.Palette(); len(got) != 4 || got[3].Color != missing {
+		t.Fatalf("expected a missing color to be appended to the palette, got %v", got)
+	}
+	if got, _ := c.GetPixel(0, 0); got != missing {
+		t.Fatalf("expected the appended color after loading, got %v", got)
+	}
+
+	crowded, err := New(16, 16)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 256 {
+		if err := crowded.SetPixel(i%16, i/16, color.RGBA{R: uint8(i), A: 255}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	var canvasErr Error
+	if _, err := crowded.Snapshot().Indexed(nil); !errors.As(err, &canvasErr) || canvasErr.Code != "off_palette" {
+		t.Fatalf("expected off_palette for more colors than an indexed canvas holds, got %v", err)
+	}
+
+	scratch, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := scratch.Load(c.Snapshot()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !scratch.Indexed() {
+		t.Fatalf("expected loading an indexed snapshot to make the canvas indexed")
+	}
+	if got, _ := scratch.GetPixel(1, 1); got != skin {
+		t.Fatalf("expected skin on the loaded copy, got %v", got)
+	}
+}
@@ -0,0 +1,363 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BlendMode controls how a layer combines with the layers beneath it.
+type BlendMode string
+
+const (
+	BlendNormal   BlendMode = "normal"
+	BlendMultiply BlendMode = "multiply"
+	BlendScreen   BlendMode = "screen"
+	BlendOverlay  BlendMode = "overlay"
+	BlendAdd      BlendMode = "add"
+)
+
+// ParseBlendMode validates a blend mode name.
+func ParseBlendMode(value string) (BlendMode, error) {
+	switch mode := BlendMode(strings.ToLower(value)); mode {
+	case BlendNormal, BlendMultiply, BlendScreen, BlendOverlay, BlendAdd:
+		return mode, nil
+	default:
+		return "", Error{Code: "invalid_args", Message: fmt.Sprintf("unknown blend mode %q", value)}
+	}
+}
+
+// LayerInfo describes a layer's properties without its pixels.
+type LayerInfo struct {
+	Index   int
+	Name    string
+	Visible bool
+	Opacity int
+	Locked  bool
+	Blend   BlendMode
+	Active  bool
+}
+
+// layer holds one cel of pixels per animation frame.
+type layer struct {
+	name    string
+	visible bool
+	opacity int
+	locked  bool
+	blend   BlendMode
+	cels    [][]color.RGBA
+}
+
+func newLayer(name string, size, frames int) *layer {
+	cels := make([][]color.RGBA, frames)
+	for i := range cels {
+		cels[i] = make([]color.RGBA, size)
+	}
+	return &layer{
+		name:    name,
+		visible: true,
+		opacity: 100,
+		blend:   BlendNormal,
+		cels:    cels,
+	}
+}
+
+func (l *layer) clone() *layer {
+	copied := *l
+	copied.cels = make([][]color.RGBA, len(l.cels))
+	for i, cel := range l.cels {
+		copied.cels[i] = cloneCel(cel)
+	}
+	return &copied
+}
+
+func cloneCel(cel []color.RGBA) []color.RGBA {
+	copied := make([]color.RGBA, len(cel))
+	copy(copied, cel)
+	return copied
+}
+
+func defaultLayerName(index int) string {
+	if index == 0 {
+		return "background"
+	}
+	return fmt.Sprintf("layer%d", index)
+}
+
+// Layers lists the layer stack from bottom to top.
+func (c *Canvas) Layers() []LayerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	infos := make([]LayerInfo, len(c.layers))
+	for i, l := range c.layers {
+		infos[i] = LayerInfo{
+			Index:   i,
+			Name:    l.name,
+			Visible: l.visible,
+			Opacity: l.opacity,
+			Locked:  l.locked,
+			Blend:   l.blend,
+			Active:  i == c.active,
+		}
+	}
+	return infos
+}
+
+// AddLayer adds an empty layer above the active layer and makes it active.
+// An empty name picks the next free default name.
+func (c *Canvas) AddLayer(name string) (LayerInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name == "" {
+		for i := len(c.layers); ; i++ {
+			name = defaultLayerName(i)
+			if c.findLayer(name) < 0 {
+				break
+			}
+		}
+	}
+	if err := c.checkLayerName(name); err != nil {
+		return LayerInfo{}, err
+	}
+
+	c.recordShape()
+	index := c.active + 1
+	c.layers = append(c.layers, nil)
+	copy(c.layers[index+1:], c.layers[index:])
+	c.layers[index] = newLayer(name, c.width*c.height, len(c.frames))
+	c.active = index
+	c.dirty = true
+	return LayerInfo{Index: index, Name: name, Visible: true, Opacity: 100, Blend: BlendNormal, Active: true}, nil
+}
+
+// RemoveLayer deletes a layer. The last remaining layer cannot be removed.
+func (c *Canvas) RemoveLayer(ref string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index, err := c.resolveLayer(ref)
+	if err != nil {
+		return err
+	}
+	if len(c.layers) == 1 {
+		return Error{Code: "invalid_args", Message: "cannot remove the only layer"}
+	}
+	c.recordShape()
+	c.layers = append(c.layers[:index], c.layers[index+1:]...)
+	if c.active > index || c.active == len(c.layers) {
+		c.active--
+	}
+	c.dirty = true
+	return nil
+}
+
+// MoveLayer moves a layer to a new stack position, keeping the active layer selected.
+func (c *Canvas) MoveLayer(ref string, to int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from, err := c.resolveLayer(ref)
+	if err != nil {
+		return err
+	}
+	if to < 0 || to >= len(c.layers) {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("layer index %d out of range 0-%d", to, len(c.layers)-1)}
+	}
+	c.recordShape()
+	active := c.layers[c.active]
+	c.layers = moveItem(c.layers, from, to)
+	for i, l := range c.layers {
+		if l == active {
+			c.active = i
+		}
+	}
+	c.dirty = true
+	return nil
+}
+
+// RenameLayer changes a layer's name.
+func (c *Canvas) RenameLayer(ref, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index, err := c.resolveLayer(ref)
+	if err != nil {
+		return err
+	}
+	if c.layers[index].name == name {
+		return nil
+	}
+	if err := c.checkLayerName(name); err != nil {
+		return err
+	}
+	c.layers[index].name = name
+	return nil
+}
+
+// SelectLayer makes a layer the target of drawing operations.
+func (c *Canvas) SelectLayer(ref string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index, err := c.resolveLayer(ref)
+	if err != nil {
+		return err
+	}
+	c.active = index
+	return nil
+}
+
+// SetLayerVisible shows or hides a layer in the composite.
+func (c *Canvas) SetLayerVisible(ref string, visible bool) error {
+	return c.updateLayer(ref, func(l *layer) {
+		l.visible = visible
+	})
+}
+
+// SetLayerOpacity sets a layer's opacity as a percentage from 0 to 100.
+func (c *Canvas) SetLayerOpacity(ref string, opacity int) error {
+	if opacity < 0 || opacity > 100 {
+		return Error{Code: "invalid_args", Message: "opacity must be between 0 and 100"}
+	}
+	return c.updateLayer(ref, func(l *layer) {
+		l.opacity = opacity
+	})
+}
+
+// SetLayerLocked locks or unlocks a layer against drawing.
+func (c *Canvas) SetLayerLocked(ref string, locked bool) error {
+	return c.updateLayer(ref, func(l *layer) {
+		l.locked = locked
+	})
+}
+
+// SetLayerBlend sets a layer's blend mode.
+func (c *Canvas) SetLayerBlend(ref string, mode BlendMode) error {
+	if _, err := ParseBlendMode(string(mode)); err != nil {
+		return err
+	}
+	return c.updateLayer(ref, func(l *layer) {
+		l.blend = mode
+	})
+}
+
+func (c *Canvas) updateLayer(ref string, update func(*layer)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index, err := c.resolveLayer(ref)
+	if err != nil {
+		return err
+	}
+	update(c.layers[index])
+	c.dirty = true
+	return nil
+}
+
+// resolveLayer finds a layer by name, falling back to its stack index.
+func (c *Canvas) resolveLayer(ref string) (int, error) {
+	if index := c.findLayer(ref); index >= 0 {
+		return index, nil
+	}
+	if index, err := strconv.Atoi(ref); err == nil && index >= 0 && index < len(c.layers) {
+		return index, nil
+	}
+	return 0, Error{Code: "invalid_layer", Message: fmt.Sprintf("no layer %q", ref)}
+}
+
+func (c *Canvas) findLayer(name string) int {
+	for i, l := range c.layers {
+		if l.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Canvas) checkLayerName(name string) error {
+	if strings.TrimSpace(name) != name || name == "" {
+		return Error{Code: "invalid_args", Message: "layer name must be non-empty with no leading or trailing whitespace"}
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return Error{Code: "invalid_args", Message: "layer name must not contain control characters"}
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return Error{Code: "invalid_args", Message: "layer name must not be a number"}
+	}
+	if c.findLayer(name) >= 0 {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("layer %q already exists", name)}
+	}
+	return nil
+}
+
+// composite flattens the visible layers of a frame; callers must hold the lock.
+func (c *Canvas) composite(frame int) []color.RGBA {
+	if len(c.layers) == 1 && c.layers[0].isPlain() {
+		return c.colors(c.layers[0].cels[frame])
+	}
+	out := make([]color.RGBA, c.width*c.height)
+	for i := range out {
+		out[i] = c.compositeAt(frame, i)
+	}
+	return out
+}
+
+// compositeAt flattens the visible layers of a frame at a single pixel index.
+func (c *Canvas) compositeAt(frame, idx int) color.RGBA {
+	if len(c.layers) == 1 && c.layers[0].isPlain() {
+		return c.color(c.layers[0].cels[frame][idx])
+	}
+	var dst [4]float64
+	for _, l := range c.layers {
+		if !l.visible || l.opacity == 0 {
+			continue
+		}
+		dst = blendPixel(dst, c.color(l.cels[frame][idx]), float64(l.opacity)/100, l.blend)
+	}
+	return color.RGBA{
+		R: uint8(math.Round(dst[0] * 255)),
+		G: uint8(math.Round(dst[1] * 255)),
+		B: uint8(math.Round(dst[2] * 255)),
+		A: uint8(math.Round(dst[3] * 255)),
+	}
+}
+
+func (l *layer) isPlain() bool {
+	return l.visible && l.opacity == 100 && l.blend == BlendNormal
+}
+
+// blendPixel composites src over the straight-alpha backdrop dst using the W3C
+// separable blend mode formulas followed by source-over.
+func blendPixel(dst [4]float64, src color.RGBA, opacity float64, mode BlendMode) [4]float64 {
+	sa := float64(src.A) / 255 * opacity
+	if sa == 0 {
+		return dst
+	}
+	ba := dst[3]
+	outA := sa + ba*(1-sa)
+	var out [4]float64
+	for i, channel := range [3]uint8{src.R, src.G, src.B} {
+		cs := float64(channel) / 255
+		cb := dst[i]
+		mixed := (1-ba)*cs + ba*blendChannel(cb, cs, mode)
+		out[i] = (sa*mixed + ba*cb*(1-sa)) / outA
+	}
+	out[3] = outA
+	return out
+}
+
+func blendChannel(cb, cs float64, mode BlendMode) float64 {
+	switch mode {
+	case BlendMultiply:
+		return cb * cs
+	case BlendScreen:
+		return cb + cs - cb*cs
+	case BlendOverlay:
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+		return 1 - 2*(1-cb)*(1-cs)
+	case BlendAdd:
+		return math.Min(1, cb+cs)
+	default:
+		return cs
+	}
+}
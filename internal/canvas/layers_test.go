@@ -0,0 +1,278 @@
+package canvas
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCanvasNewHasBackgroundLayer(t *testing.T) {
+	c, err := New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	layers := c.Layers()
+	if len(layers) != 1 {
+		t.Fatalf("expected 1 layer, got %d", len(layers))
+	}
+	want := LayerInfo{Index: 0, Name: "background", Visible: true, Opacity: 100, Blend: BlendNormal, Active: true}
+	if layers[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, layers[0])
+	}
+}
+
+func TestCanvasDrawingTargetsActiveLayer(t *testing.T) {
+	c, err := New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	if err := c.SetPixel(0, 0, red); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if _, err := c.AddLayer("top"); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	if err := c.SetPixel(1, 0, blue); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+
+	tests := []struct {
+		ref  string
+		x    int
+		want color.RGBA
+	}{
+		{ref: "background", x: 0, want: red},
+		{ref: "background", x: 1, want: color.RGBA{}},
+		{ref: "top", x: 0, want: color.RGBA{}},
+		{ref: "1", x: 1, want: blue},
+	}
+	for _, tt := range tests {
+		got, err := c.GetLayerPixel(tt.ref, tt.x, 0)
+		if err != nil {
+			t.Fatalf("unexpected get error: %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("layer %s x=%d: expected %v, got %v", tt.ref, tt.x, tt.want, got)
+		}
+	}
+
+	for x, want := range []color.RGBA{red, blue} {
+		got, err := c.GetPixel(x, 0)
+		if err != nil {
+			t.Fatalf("unexpected get error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("composite x=%d: expected %v, got %v", x, want, got)
+		}
+	}
+}
+
+func TestCanvasBlendModes(t *testing.T) {
+	backdrop := color.RGBA{R: 200, G: 100, B: 50, A: 255}
+	source := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+
+	tests := []struct {
+		mode BlendMode
+		want color.RGBA
+	}{
+		{mode: BlendNormal, want: source},
+		{mode: BlendMultiply, want: color.RGBA{R: 78, G: 39, B: 20, A: 255}},
+		{mode: BlendScreen, want: color.RGBA{R: 222, G: 161, B: 130, A: 255}},
+		{mode: BlendOverlay, want: color.RGBA{R: 188, G: 78, B: 39, A: 255}},
+		{mode: BlendAdd, want: color.RGBA{R: 255, G: 200, B: 150, A: 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			c := twoLayerCanvas(t, backdrop, source)
+			if err := c.SetLayerBlend("top", tt.mode); err != nil {
+				t.Fatalf("unexpected blend error: %v", err)
+			}
+			got, err := c.GetPixel(0, 0)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCanvasLayerOpacityAndVisibility(t *testing.T) {
+	backdrop := color.RGBA{R: 255, A: 255}
+	source := color.RGBA{B: 255, A: 255}
+	c := twoLayerCanvas(t, backdrop, source)
+
+	if err := c.SetLayerOpacity("top", 50); err != nil {
+		t.Fatalf("unexpected opacity error: %v", err)
+	}
+	got, err := c.GetPixel(0, 0)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if want := (color.RGBA{R: 128, B: 128, A: 255}); got != want {
+		t.Fatalf("expected half-opacity mix %v, got %v", want, got)
+	}
+
+	if err := c.SetLayerVisible("top", false); err != nil {
+		t.Fatalf("unexpected visibility error: %v", err)
+	}
+	got, err = c.GetPixel(0, 0)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if got != backdrop {
+		t.Fatalf("expected hidden layer to be skipped, got %v", got)
+	}
+
+	if err := c.SetLayerOpacity("top", 101); err == nil {
+		t.Fatalf("expected invalid opacity error")
+	}
+}
+
+func TestCanvasLockedLayerRejectsDrawing(t *testing.T) {
+	c, err := New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetLayerLocked("background", true); err != nil {
+		t.Fatalf("unexpected lock error: %v", err)
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+	draws := map[string]func() error{
+		"set_pixel": func() error { return c.SetPixel(0, 0, red) },
+		"fill_rect": func() error { return c.FillRect(0, 0, 1, 1, red) },
+		"line":      func() error { return c.Line(0, 0, 1, 1, red) },
+		"fill":      func() error { return c.FloodFill(0, 0, red, DefaultFillOptions()) },
+		"circle":    func() error { return c.Circle(1, 1, 1, red, false) },
+		"clear":     func() error { return c.Clear(red) },
+	}
+	for name, draw := range draws {
+		if err := draw(); err == nil {
+			t.Fatalf("%s: expected layer_locked error", name)
+		} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "layer_locked" {
+			t.Fatalf("%s: expected layer_locked, got %v", name, err)
+		}
+	}
+}
+
+func TestCanvasLayerStructure(t *testing.T) {
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := c.AddLayer(name); err != nil {
+			t.Fatalf("unexpected add error: %v", err)
+		}
+	}
+
+	if err := c.MoveLayer("b", 0); err != nil {
+		t.Fatalf("unexpected move error: %v", err)
+	}
+	if err := c.RenameLayer("a", "c"); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+	assertLayerOrder(t, c, []string{"b", "background", "c"}, "b")
+
+	if err := c.RemoveLayer("b"); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+	assertLayerOrder(t, c, []string{"background", "c"}, "background")
+
+	if err := c.RenameLayer("c", "background"); err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+	if _, err := c.AddLayer("42"); err == nil {
+		t.Fatalf("expected numeric name error")
+	}
+	for _, name := range []string{" padded", "tab\tname", ""} {
+		if err := c.RenameLayer("c", name); err == nil {
+			t.Fatalf("expected invalid name error for %q", name)
+		}
+	}
+	if err := c.RenameLayer("c", "line art"); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+	if err := c.RenameLayer("line art", "c"); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+	if err := c.SelectLayer("5"); err == nil {
+		t.Fatalf("expected invalid_layer error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_layer" {
+		t.Fatalf("expected invalid_layer, got %v", err)
+	}
+
+	if err := c.RemoveLayer("c"); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+	if err := c.RemoveLayer("background"); err == nil {
+		t.Fatalf("expected error removing the only layer")
+	}
+}
+
+func TestCanvasSnapshotRestoresLayers(t *testing.T) {
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snapshot := c.Snapshot()
+
+	if _, err := c.AddLayer("top"); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	if err := c.SetPixel(0, 0, color.RGBA{G: 255, A: 255}); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if err := c.Restore(snapshot); err != nil {
+		t.Fatalf("unexpected restore error: %v", err)
+	}
+
+	assertLayerOrder(t, c, []string{"background"}, "background")
+	got, err := c.GetPixel(0, 0)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if got != (color.RGBA{}) {
+		t.Fatalf("expected restored pixel to be transparent, got %v", got)
+	}
+}
+
+func twoLayerCanvas(t *testing.T, backdrop, source color.RGBA) *Canvas {
+	t.Helper()
+	c, err := New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPixel(0, 0, backdrop); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if _, err := c.AddLayer("top"); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	if err := c.SetPixel(0, 0, source); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	return c
+}
+
+func assertLayerOrder(t *testing.T, c *Canvas, names []string, active string) {
+	t.Helper()
+	layers := c.Layers()
+	if len(layers) != len(names) {
+		t.Fatalf("expected %d layers, got %+v", len(names), layers)
+	}
+	for i, l := range layers {
+		if l.Name != names[i] {
+			t.Fatalf("expected layer %d to be %q, got %+v", i, names[i], layers)
+		}
+		if l.Active != (l.Name == active) {
+			t.Fatalf("expected %q to be active, got %+v", active, layers)
+		}
+	}
+}
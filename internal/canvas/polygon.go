@@ -0,0 +1,177 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// FillRule selects how a polygon's interior is determined for self-intersecting outlines.
+type FillRule int
+
+const (
+	// EvenOdd fills regions crossed an odd number of times by a ray.
+	EvenOdd FillRule = iota
+	// NonZero fills regions with a non-zero winding number.
+	NonZero
+)
+
+// ParseFillRule converts "evenodd" or "nonzero" into a FillRule.
+func ParseFillRule(value string) (FillRule, error) {
+	switch value {
+	case "evenodd":
+		return EvenOdd, nil
+	case "nonzero":
+		return NonZero, nil
+	default:
+		return EvenOdd, Error{Code: "invalid_args", Message: fmt.Sprintf("fill rule must be evenodd or nonzero, got %q", value)}
+	}
+}
+
+// Rect draws a one-pixel rectangle outline.
+func (c *Canvas) Rect(x, y, w, h int, value color.RGBA) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if w <= 0 || h <= 0 {
+		return Error{Code: "invalid_args", Message: "rect width and height must be positive"}
+	}
+	if x < 0 || y < 0 || x+w > c.width || y+h > c.height {
+		return Error{
+			Code:    "out_of_bounds",
+			Message: fmt.Sprintf("rect (%d,%d) size %dx%d outside canvas", x, y, w, h),
+		}
+	}
+
+	right, bottom := x+w-1, y+h-1
+	c.line(x, y, right, y, value)
+	c.line(x, bottom, right, bottom, value)
+	c.line(x, y, x, bottom, value)
+	c.line(right, y, right, bottom, value)
+	c.dirty = true
+	return nil
+}
+
+// Polyline draws connected line segments through the provided points.
+func (c *Canvas) Polyline(points []image.Point, value color.RGBA) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if len(points) < 2 {
+		return Error{Code: "invalid_args", Message: "polyline needs at least 2 points"}
+	}
+	if err := c.checkPoints(points); err != nil {
+		return err
+	}
+	for i := 1; i < len(points); i++ {
+		c.line(points[i-1].X, points[i-1].Y, points[i].X, points[i].Y, value)
+	}
+	c.dirty = true
+	return nil
+}
+
+// Polygon draws a closed polygon outline, or its scanline-filled interior and outline
+// when filled is true.
+func (c *Canvas) Polygon(points []image.Point, value color.RGBA, filled bool, rule FillRule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if len(points) < 3 {
+		return Error{Code: "invalid_args", Message: "polygon needs at least 3 points"}
+	}
+	if err := c.checkPoints(points); err != nil {
+		return err
+	}
+	if filled {
+		c.scanlineFill(points, value, rule)
+	}
+	for i := range points {
+		next := points[(i+1)%len(points)]
+		c.line(points[i].X, points[i].Y, next.X, next.Y, value)
+	}
+	c.dirty = true
+	return nil
+}
+
+func (c *Canvas) checkPoints(points []image.Point) error {
+	for _, p := range points {
+		if _, err := c.index(p.X, p.Y); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type crossing struct {
+	x       float64
+	winding int
+}
+
+// scanlineFill fills pixels whose centers lie inside the polygon, sampling each row at
+// its pixel centers with half-open edge rules so shared vertices are counted once.
+func (c *Canvas) scanlineFill(points []image.Point, value color.RGBA, rule FillRule) {
+	minY, maxY := points[0].Y, points[0].Y
+	for _, p := range points {
+		minY = min(minY, p.Y)
+		maxY = max(maxY, p.Y)
+	}
+
+	crossings := make([]crossing, 0, len(points))
+	for y := minY; y <= maxY; y++ {
+		crossings = crossings[:0]
+		for i, a := range points {
+			b := points[(i+1)%len(points)]
+			if a.Y == b.Y {
+				continue
+			}
+			winding := 1
+			if a.Y > b.Y {
+				a, b = b, a
+				winding = -1
+			}
+			if y < a.Y || y >= b.Y {
+				continue
+			}
+			t := float64(y-a.Y) / float64(b.Y-a.Y)
+			crossings = append(crossings, crossing{x: float64(a.X) + t*float64(b.X-a.X), winding: winding})
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+		inside := 0
+		for i := 0; i+1 < len(crossings); i++ {
+			if rule == NonZero {
+				inside += crossings[i].winding
+			} else {
+				inside ^= 1
+			}
+			if inside == 0 {
+				continue
+			}
+			start := int(math.Ceil(crossings[i].x))
+			end := int(math.Floor(crossings[i+1].x))
+			for x := start; x <= end; x++ {
+				c.plot(x, y, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCanvasRectOutline(t *testing.T) {
+	c, err := New(6, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	if err := c.Rect(1, 1, 4, 3, red); err != nil {
+		t.Fatalf("unexpected rect error: %v", err)
+	}
+	assertASCII(t, c, `
+......
+.####.
+.#..#.
+.####.
+......`)
+}
+
+func TestCanvasRectErrors(t *testing.T) {
+	c, err := New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Rect(0, 0, 0, 2, color.RGBA{}); err == nil {
+		t.Fatalf("expected invalid_args error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+	if err := c.Rect(2, 2, 3, 1, color.RGBA{}); err == nil {
+		t.Fatalf("expected out_of_bounds error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "out_of_bounds" {
+		t.Fatalf("expected out_of_bounds, got %v", err)
+	}
+}
+
+func TestCanvasPolyline(t *testing.T) {
+	c, err := New(7, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	if err := c.Polyline([]image.Point{{X: 0, Y: 4}, {X: 3, Y: 0}, {X: 6, Y: 4}}, red); err != nil {
+		t.Fatalf("unexpected polyline error: %v", err)
+	}
+	assertASCII(t, c, `
+...#...
+..#.#..
+.#..#..
+.#...#.
+#.....#`)
+}
+
+func TestCanvasPolygonOutline(t *testing.T) {
+	c, err := New(7, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	diamond := []image.Point{{X: 3, Y: 0}, {X: 6, Y: 3}, {X: 3, Y: 6}, {X: 0, Y: 3}}
+	if err := c.Polygon(diamond, red, false, EvenOdd); err != nil {
+		t.Fatalf("unexpected polygon error: %v", err)
+	}
+	assertASCII(t, c, `
+...#...
+..#.#..
+.#...#.
+#.....#
+.#...#.
+..#.#..
+...#...`)
+}
+
+func TestCanvasPolygonFillRules(t *testing.T) {
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	// Outer and inner squares wound in the same direction, joined by a bridge.
+	frame := []image.Point{
+		{X: 0, Y: 0}, {X: 8, Y: 0}, {X: 8, Y: 8}, {X: 0, Y: 8}, {X: 0, Y: 0},
+		{X: 2, Y: 2}, {X: 6, Y: 2}, {X: 6, Y: 6}, {X: 2, Y: 6}, {X: 2, Y: 2},
+	}
+
+	tests := []struct {
+		name string
+		rule FillRule
+		want string
+	}{
+		{
+			name: "evenodd leaves the inner square open",
+			rule: EvenOdd,
+			want: `
+#########
+#########
+#########
+###...###
+###...###
+###...###
+#########
+#########
+#########`,
+		},
+		{
+			name: "nonzero fills the doubly wound square",
+			rule: NonZero,
+			want: `
+#########
+#########
+#########
+#########
+#########
+#########
+#########
+#########
+#########`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(9, 9)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Polygon(frame, red, true, tt.rule); err != nil {
+				t.Fatalf("unexpected polygon error: %v", err)
+			}
+			assertASCII(t, c, tt.want)
+		})
+	}
+}
+
+func TestCanvasPolygonErrors(t *testing.T) {
+	c, err := New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Polygon([]image.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}, color.RGBA{}, false, EvenOdd); err == nil {
+		t.Fatalf("expected invalid_args error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+	if err := c.Polyline([]image.Point{{X: 0, Y: 0}}, color.RGBA{}); err == nil {
+		t.Fatalf("expected invalid_args error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args, got %v", err)
+	}
+	if err := c.Polygon([]image.Point{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 0, Y: 4}}, color.RGBA{}, true, NonZero); err == nil {
+		t.Fatalf("expected out_of_bounds error")
+	} else if canvasErr, ok := err.(Error); !ok || canvasErr.Code != "out_of_bounds" {
+		t.Fatalf("expected out_of_bounds, got %v", err)
+	}
+	if _, err := ParseFillRule("winding"); err == nil {
+		t.Fatalf("expected invalid fill rule error")
+	}
+}
@@ -0,0 +1,293 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// MaxRadius bounds circle, ellipse and arc radii. Shapes are rasterized one canvas row at
+// a time, so the limit only keeps the span arithmetic within integer range.
+const MaxRadius = 1 << 24
+
+type point struct {
+	x int
+	y int
+}
+
+// Circle draws a circle of radius r centered on (cx,cy), clipping pixels outside the canvas.
+func (c *Canvas) Circle(cx, cy, r int, value color.RGBA, filled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if err := checkRadius(r); err != nil {
+		return err
+	}
+	c.drawQuadrant(cx, cy, newCircleQuadrant(r), value, filled, nil)
+	return nil
+}
+
+// Ellipse draws an axis-aligned ellipse with radii rx and ry centered on (cx,cy),
+// clipping pixels outside the canvas.
+func (c *Canvas) Ellipse(cx, cy, rx, ry int, value color.RGBA, filled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if rx < 0 || ry < 0 {
+		return Error{Code: "invalid_args", Message: "radii must be >= 0"}
+	}
+	if rx > MaxRadius || ry > MaxRadius {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("radii must be <= %d", MaxRadius)}
+	}
+	c.drawQuadrant(cx, cy, newEllipseQuadrant(rx, ry), value, filled, nil)
+	return nil
+}
+
+// Arc draws the part of a circle running counterclockwise from startDeg to endDeg.
+// Angles are in degrees with 0 pointing right and 90 pointing up; equal angles draw
+// the full circle. When filled, the pie slice between the two angles is drawn.
+func (c *Canvas) Arc(cx, cy, r, startDeg, endDeg int, value color.RGBA, filled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
+	value, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if err := checkRadius(r); err != nil {
+		return err
+	}
+	sweep := ((endDeg-startDeg)%360 + 360) % 360
+	if sweep == 0 {
+		sweep = 360
+	}
+	start := float64((startDeg%360 + 360) % 360)
+
+	c.drawQuadrant(cx, cy, newCircleQuadrant(r), value, filled, func(p point) bool {
+		return (p.x == 0 && p.y == 0) || inSweep(p, start, float64(sweep))
+	})
+	return nil
+}
+
+func checkRadius(r int) error {
+	if r < 0 {
+		return Error{Code: "invalid_args", Message: "radius must be >= 0"}
+	}
+	if r > MaxRadius {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("radius must be <= %d", MaxRadius)}
+	}
+	return nil
+}
+
+// drawQuadrant mirrors q into all four quadrants around (cx,cy) and plots the result one
+// canvas row at a time, so the work is bounded by the canvas rather than the radius. When
+// filled, each row is filled between its outermost outline pixels. keep, when set, filters
+// pixels by their offset from the center.
+func (c *Canvas) drawQuadrant(cx, cy int, q quadrant, value color.RGBA, filled bool, keep func(point) bool) {
+	c.dirty = true
+	right, top := q.size()
+	if cx < -right || cx >= c.width+right || cy < -top || cy >= c.height+top {
+		return
+	}
+	plotRun := func(dy, from, to int) {
+		for x := max(from, 0); x <= min(to, c.width-1); x++ {
+			if keep == nil || keep(point{x: x - cx, y: dy}) {
+				c.plot(x, cy+dy, value)
+			}
+		}
+	}
+	for y := max(cy-top, 0); y <= min(cy+top, c.height-1); y++ {
+		dy := y - cy
+		lo, hi := q.run(absInt(dy))
+		if lo > hi {
+			continue
+		}
+		if filled {
+			plotRun(dy, cx-hi, cx+hi)
+			continue
+		}
+		plotRun(dy, cx+lo, cx+hi)
+		plotRun(dy, cx-hi, cx-lo)
+	}
+}
+
+// quadrant describes one quadrant of a symmetric outline by the columns it covers in each
+// row. size returns the largest column and row; run returns lo > hi for a row with no pixels.
+type quadrant interface {
+	size() (int, int)
+	run(row int) (lo, hi int)
+}
+
+// circleQuadrant is the midpoint circle of radius r. The octant from (r,0) towards the
+// diagonal has exactly one pixel per row, given in closed form by column; the rest of the
+// quadrant is its reflection.
+type circleQuadrant struct {
+	r    int
+	last int  // last octant row
+	seam bool // the diagonal pixel (last,last) is an L-shaped corner and is dropped
+}
+
+func newCircleQuadrant(r int) circleQuadrant {
+	q := circleQuadrant{r: r}
+	q.last = sort.Search(r+1, func(y int) bool { return q.column(y) < y }) - 1
+	q.seam = q.last > 0 && q.column(q.last) == q.last && q.column(q.last-1) == q.last
+	return q
+}
+
+// column returns the octant pixel in row y: the largest x with (x-1/2)² + y² < r².
+func (q circleQuadrant) column(y int) int {
+	m := 4 * (q.r*q.r - y*y)
+	if m <= 0 {
+		return 0
+	}
+	return (isqrt(m-1) + 1) / 2
+}
+
+func (q circleQuadrant) size() (int, int) {
+	return q.r, q.r
+}
+
+func (q circleQuadrant) run(row int) (int, int) {
+	lo, hi := 1, 0
+	if row <= q.last {
+		lo, hi = q.column(row), q.column(row)
+	}
+	// Reflected octant: the octant rows whose column equals row become columns here.
+	from := sort.Search(q.last+1, func(y int) bool { return q.column(y) <= row })
+	to := sort.Search(q.last+1, func(y int) bool { return q.column(y) < row }) - 1
+	if from <= to {
+		lo, hi = mergeRun(lo, hi, from, to)
+	}
+	if q.seam && row == q.last && hi == q.last {
+		hi--
+	}
+	return lo, hi
+}
+
+// ellipseQuadrant is the two-region midpoint ellipse with radii rx and ry. Region one
+// covers columns [0,split) with one pixel per column; region two covers rows [0,splitRow]
+// with one pixel per row.
+type ellipseQuadrant struct {
+	rx, ry   int
+	rx2, ry2 float64
+	split    int
+	splitRow int
+	seam     bool // the first region-two pixel is an L-shaped corner and is dropped
+}
+
+func newEllipseQuadrant(rx, ry int) ellipseQuadrant {
+	q := ellipseQuadrant{rx: rx, ry: ry, rx2: float64(rx) * float64(rx), ry2: float64(ry) * float64(ry)}
+	if ry == 0 {
+		return q
+	}
+	if rx > 0 {
+		q.split = sort.Search(rx+1, func(x int) bool {
+			return q.ry2*float64(x) >= q.rx2*float64(q.rowAt(x))
+		})
+	}
+	q.splitRow = q.rowAt(q.split)
+	q.seam = q.split > 0 && q.splitRow > 0 &&
+		q.upperRow(q.split-1) == q.splitRow && q.lowerColumn(q.splitRow-1) == q.split
+	return q
+}
+
+// level evaluates the ellipse equation at (x,y): negative inside, positive outside.
+func (q ellipseQuadrant) level(x, y float64) float64 {
+	return q.ry2*x*x + q.rx2*y*y - q.rx2*q.ry2
+}
+
+// upperRow returns the region-one pixel in column x: the largest y whose midpoint
+// (x, y-1/2) lies inside the ellipse.
+func (q ellipseQuadrant) upperRow(x int) int {
+	return sort.Search(q.ry+1, func(y int) bool {
+		return q.level(float64(x), float64(y)+0.5) >= 0
+	})
+}
+
+// rowAt returns the row the midpoint walk reaches in column x. The walk steps down at most
+// one row per column, so it can stop above upperRow where region one ends.
+func (q ellipseQuadrant) rowAt(x int) int {
+	if x == 0 {
+		return q.ry
+	}
+	y := q.upperRow(x - 1)
+	if q.level(float64(x), float64(y)-0.5) >= 0 {
+		y--
+	}
+	return y
+}
+
+// lowerColumn returns the region-two pixel in row y: the smallest x from split on whose
+// midpoint (x+1/2, y) lies outside the ellipse.
+func (q ellipseQuadrant) lowerColumn(y int) int {
+	if y >= q.splitRow {
+		return q.split
+	}
+	return q.split + sort.Search(q.rx+1-q.split, func(i int) bool {
+		return q.level(float64(q.split+i)+0.5, float64(y)) > 0
+	})
+}
+
+func (q ellipseQuadrant) size() (int, int) {
+	return q.rx, q.ry
+}
+
+func (q ellipseQuadrant) run(row int) (int, int) {
+	if q.ry == 0 {
+		return 0, q.rx
+	}
+	lo, hi := 1, 0
+	if row <= q.splitRow {
+		lo, hi = q.lowerColumn(row), q.lowerColumn(row)
+	}
+	from := sort.Search(q.split, func(x int) bool { return q.upperRow(x) <= row })
+	to := sort.Search(q.split, func(x int) bool { return q.upperRow(x) < row }) - 1
+	if from <= to {
+		lo, hi = mergeRun(lo, hi, from, to)
+	}
+	if q.seam && row == q.splitRow && hi == q.split {
+		hi--
+	}
+	return lo, hi
+}
+
+func mergeRun(lo, hi, from, to int) (int, int) {
+	if lo > hi {
+		return from, to
+	}
+	return min(lo, from), max(hi, to)
+}
+
+// isqrt returns the largest s with s*s <= n.
+func isqrt(n int) int {
+	s := int(math.Sqrt(float64(n)))
+	for s*s > n {
+		s--
+	}
+	for (s+1)*(s+1) <= n {
+		s++
+	}
+	return s
+}
+
+// inSweep reports whether p lies within the counterclockwise sweep starting at start degrees.
+func inSweep(p point, start, sweep float64) bool {
+	angle := math.Atan2(float64(-p.y), float64(p.x)) * 180 / math.Pi
+	rel := math.Mod(angle-start+720, 360)
+	return rel <= sweep+1e-9
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/client"
+	"pxcli/internal/config"
+	"pxcli/internal/daemon"
+	"pxcli/internal/protocol"
+)
+
+// inspectRequests report on a daemon rather than use it, so they never start
+// one: a probe that started the daemon would hide that it was down.
+var inspectRequests = map[string]bool{
+	"status":       true,
+	"hello":        true,
+	"capabilities": true,
+}
+
+// requestClient returns a client for the command's daemon that, when
+// auto-start is enabled, starts the daemon and retries once if none is running.
+func requestClient(cmd *cobra.Command, command string) (requestSender, error) {
+	socketPath, pidPath, err := daemonPaths(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// The arguments have been checked, so later errors are not about usage.
+	cmd.SilenceUsage = true
+	cli, err := drawNewClient(socketPath)
+	if err != nil || inspectRequests[command] {
+		return cli, err
+	}
+	enabled, err := autoStartEnabled(cmd)
+	if err != nil || !enabled {
+		return cli, err
+	}
+	return &autoStartClient{
+		requestSender: cli,
+		start: func() error {
+			pid, err := startDaemon(socketPath, pidPath, autoStartOptions())
+			var daemonErr daemon.Error
+			if errors.As(err, &daemonErr) && daemonErr.Code == "daemon_already_running" {
+				// Another command started it first.
+				return nil
+			}
+			if err != nil {
+				return formatDaemonError(err)
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "started daemon pid=%d\n", pid)
+			return nil
+		},
+	}, nil
+}
+
+// autoStartEnabled reports whether --auto-start, or else the environment, asks
+// for the daemon to be started on demand.
+func autoStartEnabled(cmd *cobra.Command) (bool, error) {
+	if cmd.Flags().Changed("auto-start") {
+		return cmd.Flags().GetBool("auto-start")
+	}
+	value, ok := os.LookupEnv(config.AutoStartEnv)
+	if !ok || value == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, invalidArgsf("%s must be true or false, got %q", config.AutoStartEnv, value)
+	}
+	return enabled, nil
+}
+
+// autoStartOptions are the default daemon settings, headless when this build
+// cannot open a window.
+func autoStartOptions() daemonOptions {
+	return daemonOptions{
+		size:           fmt.Sprintf("%dx%d", config.DefaultCanvasWidth, config.DefaultCanvasHeight),
+		scale:          config.DefaultScale,
+		headless:       config.DefaultHeadless || daemon.ValidateRenderer(false) != nil,
+		historyEntries: config.DefaultHistoryEntries,
+		historyBytes:   config.DefaultHistoryBytes,
+	}
+}
+
+// autoStartClient starts the daemon the first time a request finds none
+// running, then sends the request again.
+type autoStartClient struct {
+	requestSender
+	start func() error
+}
+
+func (c *autoStartClient) Send(request string) (client.Response, error) {
+	resp, err := c.requestSender.Send(request)
+	if !daemonNotRunning(err) {
+		return resp, err
+	}
+	if err := c.start(); err != nil {
+		return client.Response{}, err
+	}
+	return c.requestSender.Send(request)
+}
+
+func (c *autoStartClient) SendBatch(operations []string, options ...string) (client.Response, error) {
+	resp, err := c.requestSender.SendBatch(operations, options...)
+	if !daemonNotRunning(err) {
+		return resp, err
+	}
+	if err := c.start(); err != nil {
+		return client.Response{}, err
+	}
+	return c.requestSender.SendBatch(operations, options...)
+}
+
+func (c *autoStartClient) SendJSON(request protocol.JSONRequest) (protocol.JSONResponse, error) {
+	resp, err := c.requestSender.SendJSON(request)
+	if !daemonNotRunning(err) {
+		return resp, err
+	}
+	if err := c.start(); err != nil {
+		return protocol.JSONResponse{}, err
+	}
+	return c.requestSender.SendJSON(request)
+}
+
+func daemonNotRunning(err error) bool {
+	var clientErr client.Error
+	return errors.As(err, &clientErr) && clientErr.Code == "daemon_not_running"
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pxcli/internal/client"
+	"pxcli/internal/config"
+)
+
+// stubAutoStart makes the stub client fail with daemon_not_running until a
+// daemon is "spawned", and records the spawn arguments.
+func stubAutoStart(t *testing.T, stub *stubClient) *[][]string {
+	t.Helper()
+	stub.err = client.Error{Code: "daemon_not_running", Message: "no socket"}
+	var spawned [][]string
+
+	restoreClient, restoreSpawn := drawNewClient, startSpawnDaemon
+	restoreEnsure, restoreWait := startEnsureReady, startWaitForReady
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	startSpawnDaemon = func(binary string, args []string) (daemonProcess, error) {
+		spawned = append(spawned, args)
+		stub.err = nil
+		return daemonProcess{pid: 4242}, nil
+	}
+	startEnsureReady = func(pidPath, socketPath string) error { return nil }
+	startWaitForReady = func(socketPath string, timeout time.Duration) error { return nil }
+	t.Cleanup(func() {
+		drawNewClient, startSpawnDaemon = restoreClient, restoreSpawn
+		startEnsureReady, startWaitForReady = restoreEnsure, restoreWait
+	})
+	return &spawned
+}
+
+func TestAutoStart_StartsDaemonAndRetries(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		env  string
+	}{
+		{name: "flag", args: []string{"--auto-start", "set_pixel", "0", "0", "red"}},
+		{name: "env", args: []string{"set_pixel", "0", "0", "red"}, env: "1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.AutoStartEnv, tt.env)
+			stub := &stubClient{response: client.Response{Raw: "ok"}}
+			spawned := stubAutoStart(t, stub)
+
+			out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(out)
+			cmd.SetErr(errOut)
+			cmd.SetArgs(append([]string{"--socket", "/tmp/auto.sock"}, tt.args...))
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := []string{"set_pixel 0 0 red", "set_pixel 0 0 red"}; !reflect.DeepEqual(stub.requests, want) {
+				t.Fatalf("expected the request to be retried, got %q", stub.requests)
+			}
+			if len(*spawned) != 1 {
+				t.Fatalf("expected one daemon to be spawned, got %q", *spawned)
+			}
+			if args := strings.Join((*spawned)[0], " "); !strings.Contains(args, "--socket /tmp/auto.sock") {
+				t.Fatalf("expected the daemon to serve the command's socket, got %q", args)
+			}
+			if out.String() != "ok\n" {
+				t.Fatalf("unexpected output %q", out.String())
+			}
+			if errOut.String() != "started daemon pid=4242\n" {
+				t.Fatalf("unexpected notice %q", errOut.String())
+			}
+		})
+	}
+}
+
+func TestAutoStart_DisabledByDefault(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		env  string
+	}{
+		{name: "unset", args: []string{"set_pixel", "0", "0", "red"}},
+		{name: "env false", args: []string{"set_pixel", "0", "0", "red"}, env: "false"},
+		{name: "flag overrides env", args: []string{"--auto-start=false", "set_pixel", "0", "0", "red"}, env: "true"},
+		{name: "capabilities probe", args: []string{"--auto-start", "capabilities"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.AutoStartEnv, tt.env)
+			stub := &stubClient{}
+			spawned := stubAutoStart(t, stub)
+
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), "err daemon_not_running") {
+				t.Fatalf("expected daemon_not_running, got %v", err)
+			}
+			if len(*spawned) != 0 {
+				t.Fatalf("expected no daemon to be spawned, got %q", *spawned)
+			}
+		})
+	}
+}
+
+func TestAutoStart_InvalidEnv(t *testing.T) {
+	t.Setenv(config.AutoStartEnv, "sometimes")
+	stub := &stubClient{}
+	stubAutoStart(t, stub)
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"clear"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "err invalid_args "+config.AutoStartEnv) {
+		t.Fatalf("expected invalid_args for %s, got %v", config.AutoStartEnv, err)
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/command"
+	"pxcli/internal/protocol"
+)
+
+// newBatchCmd creates the batch command, which reads protocol operations from
+// stdin and counts them itself. Its flags become batch header options.
+func newBatchCmd(spec command.Spec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   spec.Name,
+		Short: spec.Short,
+		Long:  spec.Long,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			operations, err := readBatchOperations(cmd)
+			if err != nil {
+				return err
+			}
+			cli, err := requestClient(cmd, spec.Name)
+			if err != nil {
+				return err
+			}
+			options := changedSpecFlags(cmd, spec)
+			if jsonOutput(cmd) {
+				request := protocol.NewJSONRequest("batch", options...)
+				for i, operation := range operations {
+					parsed, err := protocol.ParseLine(operation)
+					if err != nil {
+						return batchLineError(i, err)
+					}
+					request.Batch = append(request.Batch, protocol.NewJSONRequest(parsed.Command, parsed.Args...))
+				}
+				resp, err := cli.SendJSON(request)
+				return reportJSON(cmd, resp, err)
+			}
+			resp, err := cli.SendBatch(operations, options...)
+			if err == nil {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), resp.Raw)
+			}
+			for _, line := range resp.Lines {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), line)
+			}
+			return formatClientError(err)
+		},
+	}
+	cmd.Flags().SetInterspersed(false)
+	addSpecFlags(cmd, spec)
+
+	return cmd
+}
+
+// batchLineError reports an operation line that cannot be parsed, naming the
+// line the way a failed batch does.
+func batchLineError(index int, err error) error {
+	var protoErr protocol.Error
+	if errors.As(err, &protoErr) {
+		return fmt.Errorf("err %s line %d: %s", protoErr.Code, index+1, protoErr.Message)
+	}
+	return fmt.Errorf("line %d: %w", index+1, err)
+}
+
+func readBatchOperations(cmd *cobra.Command) ([]string, error) {
+	var operations []string
+	scanner := bufio.NewScanner(cmd.InOrStdin())
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		operations = append(operations, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read operations: %w", err)
+	}
+	if len(operations) == 0 {
+		return nil, invalidArgsf("batch needs at least one operation on stdin")
+	}
+	return operations, nil
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pxcli/internal/client"
+)
+
+func TestBatchCmd_SendsStdinOperations(t *testing.T) {
+	stub := &stubClient{
+		response: client.Response{Raw: "ok 2", Lines: []string{"ok", "ok"}},
+	}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetIn(strings.NewReader("# outline\nset_pixel 0 0 red\n\n  fill_rect 1 1 2 2 blue  \n"))
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"batch"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := [][]string{{"set_pixel 0 0 red", "fill_rect 1 1 2 2 blue"}}
+	if !reflect.DeepEqual(stub.batches, want) {
+		t.Fatalf("expected batches %q, got %q", want, stub.batches)
+	}
+	if got := buf.String(); got != "ok 2\nok\nok\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
+
+func TestBatchCmd_DocOption(t *testing.T) {
+	stub := &stubClient{response: client.Response{Raw: "ok 1", Lines: []string{"ok"}}}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetIn(strings.NewReader("clear\n"))
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"batch", "--doc", "tiles"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := [][]string{{"--doc=tiles"}}; !reflect.DeepEqual(stub.batchOptions, want) {
+		t.Fatalf("expected batch options %q, got %q", want, stub.batchOptions)
+	}
+}
+
+func TestBatchCmd_ReportsRolledBackBatch(t *testing.T) {
+	stub := &stubClient{
+		response: client.Response{
+			Raw:   "err batch_failed line 2 failed; batch rolled back",
+			Lines: []string{"ok", "err out_of_bounds pixel (9,9) outside canvas"},
+		},
+		err: client.Error{Code: "batch_failed", Message: "line 2 failed; batch rolled back"},
+	}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetIn(strings.NewReader("set_pixel 0 0 red\nset_pixel 9 9 red\n"))
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"batch"})
+
+	err := cmd.Execute()
+	if err == nil || err.Error() != "err batch_failed line 2 failed; batch rolled back" {
+		t.Fatalf("expected batch_failed error, got %v", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "ok\nerr out_of_bounds pixel (9,9) outside canvas\n") {
+		t.Fatalf("expected per-line results, got %q", got)
+	}
+}
+
+func TestBatchCmd_RejectsEmptyInput(t *testing.T) {
+	stub := &stubClient{}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetIn(strings.NewReader("# nothing\n\n"))
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"batch"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "at least one operation") {
+		t.Fatalf("expected empty batch error, got %v", err)
+	}
+	if len(stub.batches) != 0 {
+		t.Fatalf("expected no batch to be sent, got %q", stub.batches)
+	}
+}
+
+func TestBatchCmd_JSONReportsMalformedLine(t *testing.T) {
+	stub := &stubClient{}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetIn(strings.NewReader("set_pixel 0 0 red\nlayer rename 0 \"unterminated\n"))
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--json", "batch"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.HasPrefix(err.Error(), "err invalid_args line 2: ") {
+		t.Fatalf("expected invalid_args error for line 2, got %v", err)
+	}
+	if len(stub.jsonRequests) != 0 {
+		t.Fatalf("expected no request to be sent, got %+v", stub.jsonRequests)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, `{"status":"error","error":{"code":"invalid_args","message":"line 2: `) {
+		t.Fatalf("expected a JSON error record, got %q", got)
+	}
+}
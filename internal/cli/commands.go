@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/command"
+	"pxcli/internal/daemon"
+)
+
+// specOverrides builds the request commands whose CLI form differs from their
+// wire form. The mode and transaction requests are per connection, and every
+// CLI command is a connection of its own, so they have no CLI command.
+var specOverrides = map[string]func(command.Spec) *cobra.Command{
+	"hello":    newHelloCmd,
+	"batch":    newBatchCmd,
+	"stop":     newStopCmd,
+	"status":   newStatusCmd,
+	"ramp":     newRampCmd,
+	"mode":     nil,
+	"begin":    nil,
+	"commit":   nil,
+	"rollback": nil,
+}
+
+// addSpecCmds adds a command for every daemon request, and the group commands
+// that hold them, to root.
+func addSpecCmds(root *cobra.Command) {
+	groups := map[string]*cobra.Command{}
+	for _, group := range daemon.Groups() {
+		groups[group.Name] = &cobra.Command{Use: group.Name, Short: group.Short}
+		root.AddCommand(groups[group.Name])
+	}
+	for _, spec := range daemon.Specs() {
+		newCmd := newSpecCmd
+		if override, ok := specOverrides[spec.Name]; ok {
+			if override == nil {
+				continue
+			}
+			newCmd = override
+		}
+		parent := root
+		if group, _ := spec.Group(); group != "" {
+			parent = groups[group]
+		}
+		parent.AddCommand(newCmd(spec))
+	}
+}
+
+// newSpecCmd creates a command that checks its arguments against spec and
+// sends them as a request.
+func newSpecCmd(spec command.Spec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   spec.Usage(),
+		Short: spec.Short,
+		Long:  spec.Long,
+		// Flags are parsed by parseSpecArgs, so that a negative coordinate
+		// is not taken for a shorthand flag.
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args, err := parseSpecArgs(cmd, args)
+			if err != nil {
+				return err
+			}
+			if help, _ := cmd.Flags().GetBool("help"); help {
+				return cmd.Help()
+			}
+			request, err := specRequest(cmd, spec, args)
+			if err != nil {
+				return err
+			}
+			if group, sub := spec.Group(); group != "" {
+				return sendGroupRequest(cmd, group, sub, request...)
+			}
+			return sendCommandRequest(cmd, spec.Name, request...)
+		},
+	}
+	cmd.Flags().SetInterspersed(false)
+	addSpecFlags(cmd, spec)
+
+	return cmd
+}
+
+// negativeNumber matches an argument that starts a negative number.
+var negativeNumber = regexp.MustCompile(`^-\d`)
+
+// parseSpecArgs parses the flags at the start of args and returns the
+// positionals. The first argument that looks like a negative number ends the
+// flags, as "--" would, unless it is the value of the flag before it.
+func parseSpecArgs(cmd *cobra.Command, args []string) ([]string, error) {
+	flags := cmd.Flags()
+	flags.AddFlagSet(cmd.InheritedFlags())
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" || !strings.HasPrefix(args[i], "-") {
+			break
+		}
+		if negativeNumber.MatchString(args[i]) {
+			args = slices.Insert(slices.Clone(args), i, "--")
+			break
+		}
+		if name, ok := strings.CutPrefix(args[i], "--"); ok && !strings.Contains(name, "=") {
+			if flag := flags.Lookup(name); flag != nil && flag.NoOptDefVal == "" {
+				i++
+			}
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, cmd.FlagErrorFunc()(cmd, err)
+	}
+	return flags.Args(), nil
+}
+
+// addSpecFlags adds spec's options to cmd as flags.
+func addSpecFlags(cmd *cobra.Command, spec command.Spec) {
+	for _, flag := range spec.Flags {
+		if flag.Kind == command.KindBool {
+			cmd.Flags().Bool(flag.Name, false, flag.Help)
+		} else {
+			cmd.Flags().Var(&specFlag{value: flag.Default, kind: flag.Kind}, flag.Name, flag.Help)
+		}
+	}
+}
+
+// changedSpecFlags returns the spec options set on cmd as "--name=value" args.
+func changedSpecFlags(cmd *cobra.Command, spec command.Spec) []string {
+	var options []string
+	for _, flag := range spec.Flags {
+		if !cmd.Flags().Changed(flag.Name) {
+			continue
+		}
+		value := cmd.Flags().Lookup(flag.Name).Value.String()
+		options = append(options, "--"+flag.Name+"="+value)
+	}
+	return options
+}
+
+// specFlag holds a flag value as given, leaving checks to the spec, and names
+// the spec's value type in help output.
+type specFlag struct {
+	value string
+	kind  command.Kind
+}
+
+func (f *specFlag) String() string { return f.value }
+
+func (f *specFlag) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *specFlag) Type() string { return string(f.kind) }
+
+// specRequest binds the positional args and the flags set on cmd to spec, and
+// returns them as request arguments: positionals with paths made absolute,
+// then "--name=value" options.
+func specRequest(cmd *cobra.Command, spec command.Spec, args []string) ([]string, error) {
+	bound, err := spec.Bind(append(slices.Clone(args), changedSpecFlags(cmd, spec)...))
+	if err != nil {
+		return nil, usageError(err)
+	}
+
+	var request []string
+	for _, param := range spec.Params {
+		for _, value := range bound.Strings(param.Name) {
+			if param.Kind == command.KindPath {
+				absPath, err := filepath.Abs(value)
+				if err != nil {
+					return nil, invalidArgsf("invalid path: %v", err)
+				}
+				value = absPath
+			}
+			request = append(request, value)
+		}
+	}
+	for _, flag := range spec.Flags {
+		if !bound.Has(flag.Name) {
+			continue
+		}
+		switch {
+		case flag.Kind != command.KindBool:
+			request = append(request, "--"+flag.Name+"="+bound.String(flag.Name))
+		case bound.Bool(flag.Name):
+			request = append(request, "--"+flag.Name)
+		}
+	}
+	return request, nil
+}
+
+// usageError formats a spec binding error the way the daemon would report it.
+func usageError(err error) error {
+	var cmdErr command.Error
+	if errors.As(err, &cmdErr) {
+		return fmt.Errorf("err %s %s", cmdErr.Code, cmdErr.Message)
+	}
+	return err
+}
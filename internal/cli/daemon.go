@@ -2,6 +2,9 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -9,14 +12,18 @@ import (
 	"pxcli/internal/daemon"
 )
 
-var daemonPIDPath = config.DefaultPIDPath
-
 // NewDaemonCmd creates the hidden daemon entrypoint skeleton with shared flags.
 func NewDaemonCmd() *cobra.Command {
 	var (
-		size     string
-		scale    int
-		headless bool
+		size           string
+		scale          int
+		headless       bool
+		from           string
+		indexed        bool
+		historyEntries int
+		historyBytes   int64
+		idleTimeout    time.Duration
+		autosaveDir    string
 	)
 
 	cmd := &cobra.Command{
@@ -25,7 +32,7 @@ func NewDaemonCmd() *cobra.Command {
 		Hidden: true,
 		Args:   cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			socketPath, err := SocketPath(cmd)
+			socketPath, pidPath, err := daemonPaths(cmd)
 			if err != nil {
 				return err
 			}
@@ -36,16 +43,34 @@ func NewDaemonCmd() *cobra.Command {
 			if scale <= 0 {
 				return fmt.Errorf("invalid scale %d: must be > 0", scale)
 			}
+			if err := validateHistoryLimits(historyEntries, historyBytes); err != nil {
+				return err
+			}
+			if idleTimeout < 0 {
+				return fmt.Errorf("invalid idle timeout %s: must be >= 0", idleTimeout)
+			}
+			if autosaveDir == "" && idleTimeout > 0 {
+				autosaveDir = config.DefaultAutosaveDir()
+			}
 			if err := daemon.ValidateRenderer(headless); err != nil {
 				return formatDaemonError(err)
 			}
 
+			if err := os.MkdirAll(filepath.Dir(socketPath), 0o700); err != nil {
+				return err
+			}
+
 			cfg := config.New(
 				config.WithSocketPath(socketPath),
-				config.WithPIDPath(daemonPIDPath),
+				config.WithPIDPath(pidPath),
 				config.WithCanvasSize(width, height),
 				config.WithScale(scale),
 				config.WithHeadless(headless),
+				config.WithInitialImage(from),
+				config.WithIndexed(indexed),
+				config.WithHistoryLimits(historyEntries, historyBytes),
+				config.WithIdleTimeout(idleTimeout),
+				config.WithAutosaveDir(autosaveDir),
 			)
 
 			if headless {
@@ -58,6 +83,10 @@ func NewDaemonCmd() *cobra.Command {
 	cmd.Flags().StringVar(&size, "size", fmt.Sprintf("%dx%d", config.DefaultCanvasWidth, config.DefaultCanvasHeight), "Canvas size in WxH")
 	cmd.Flags().IntVar(&scale, "scale", config.DefaultScale, "Canvas scale (reserved for windowed mode)")
 	cmd.Flags().BoolVar(&headless, "headless", config.DefaultHeadless, "Run without a GUI")
+	cmd.Flags().StringVar(&from, "from", "", "Initialize the canvas from a PNG file, overriding --size")
+	cmd.Flags().BoolVar(&indexed, "indexed", false, "Store palette indices instead of colors")
+	addHistoryLimitFlags(cmd, &historyEntries, &historyBytes)
+	addIdleFlags(cmd, &idleTimeout, &autosaveDir)
 
 	return cmd
 }
@@ -78,11 +78,6 @@ func TestDaemonCmd_StartsHeadlessRuntime(t *testing.T) {
 	socketPath := filepath.Join(dir, "pxcli.sock")
 	pidPath := filepath.Join(dir, "pxcli.pid")
 
-	restorePID := daemonPIDPath
-	daemonPIDPath = pidPath
-	t.Cleanup(func() {
-		daemonPIDPath = restorePID
-	})
 	t.Cleanup(func() {
 		if _, err := os.Stat(socketPath); err == nil {
 			_, _ = sendRequest(socketPath, "stop\n")
@@ -100,6 +95,8 @@ func TestDaemonCmd_StartsHeadlessRuntime(t *testing.T) {
 	}()
 
 	waitForPath(t, socketPath)
+	// The PID file sits beside the overridden socket, not at the default path.
+	waitForPath(t, pidPath)
 
 	response := mustSendRequest(t, socketPath, "clear\n")
 	if response != "ok\n" {
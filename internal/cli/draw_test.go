@@ -8,12 +8,18 @@ import (
 	"testing"
 
 	"pxcli/internal/client"
+	"pxcli/internal/protocol"
 )
 
 type stubClient struct {
-	requests []string
-	response client.Response
-	err      error
+	requests     []string
+	batches      [][]string
+	batchOptions [][]string
+	jsonRequests []protocol.JSONRequest
+	response     client.Response
+	jsonResponse protocol.JSONResponse
+	err          error
+	helloErr     error
 }
 
 func (s *stubClient) Send(request string) (client.Response, error) {
@@ -21,6 +27,21 @@ func (s *stubClient) Send(request string) (client.Response, error) {
 	return s.response, s.err
 }
 
+func (s *stubClient) SendBatch(operations []string, options ...string) (client.Response, error) {
+	s.batches = append(s.batches, operations)
+	s.batchOptions = append(s.batchOptions, options)
+	return s.response, s.err
+}
+
+func (s *stubClient) Hello() (client.Response, error) {
+	return client.Response{}, s.helloErr
+}
+
+func (s *stubClient) SendJSON(request protocol.JSONRequest) (protocol.JSONResponse, error) {
+	s.jsonRequests = append(s.jsonRequests, request)
+	return s.jsonResponse, s.err
+}
+
 func TestDrawCommands_FormatRequests(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -42,6 +63,76 @@ func TestDrawCommands_FormatRequests(t *testing.T) {
 			args:        []string{"line", "0", "0", "3", "0", "blue"},
 			wantRequest: "line 0 0 3 0 blue",
 		},
+		{
+			name:        "rect",
+			args:        []string{"rect", "0", "1", "4", "3", "#00ff00"},
+			wantRequest: "rect 0 1 4 3 #00ff00",
+		},
+		{
+			name:        "polyline",
+			args:        []string{"polyline", "0,0", "3,4", "-1,2", "black"},
+			wantRequest: "polyline 0,0 3,4 -1,2 black",
+		},
+		{
+			name:        "polygon",
+			args:        []string{"polygon", "0,0", "4,0", "2,3", "red"},
+			wantRequest: "polygon 0,0 4,0 2,3 red",
+		},
+		{
+			name:        "polygon_filled_nonzero",
+			args:        []string{"polygon", "--fill", "--rule", "nonzero", "0,0", "4,0", "2,3", "red"},
+			wantRequest: "polygon 0,0 4,0 2,3 red --fill --rule=nonzero",
+		},
+		{
+			name:        "fill",
+			args:        []string{"fill", "1", "2", "red"},
+			wantRequest: "fill 1 2 red",
+		},
+		{
+			name:        "fill_with_options",
+			args:        []string{"fill", "--tolerance", "12", "--connectivity", "8", "--global", "1", "2", "red"},
+			wantRequest: "fill 1 2 red --tolerance=12 --connectivity=8 --global",
+		},
+		{
+			name:        "circle",
+			args:        []string{"circle", "4", "4", "3", "yellow"},
+			wantRequest: "circle 4 4 3 yellow",
+		},
+		{
+			name:        "circle_filled",
+			args:        []string{"circle", "--fill", "4", "-1", "3", "yellow"},
+			wantRequest: "circle 4 -1 3 yellow --fill",
+		},
+		{
+			name:        "circle_negative_center",
+			args:        []string{"circle", "-2", "5", "4", "red"},
+			wantRequest: "circle -2 5 4 red",
+		},
+		{
+			name:        "circle_filled_negative_center",
+			args:        []string{"circle", "--fill", "-2", "-5", "4", "red"},
+			wantRequest: "circle -2 -5 4 red --fill",
+		},
+		{
+			name:        "fill_flag_value_before_negative",
+			args:        []string{"fill", "--connectivity", "8", "-1", "0", "red"},
+			wantRequest: "fill -1 0 red --connectivity=8",
+		},
+		{
+			name:        "polyline_negative_first_point",
+			args:        []string{"polyline", "-1,2", "3,4", "black"},
+			wantRequest: "polyline -1,2 3,4 black",
+		},
+		{
+			name:        "ellipse",
+			args:        []string{"ellipse", "--fill", "8", "4", "6", "3", "#fff"},
+			wantRequest: "ellipse 8 4 6 3 #fff --fill",
+		},
+		{
+			name:        "arc",
+			args:        []string{"arc", "8", "8", "5", "0", "180", "white"},
+			wantRequest: "arc 8 8 5 0 180 white",
+		},
 		{
 			name:        "clear",
 			args:        []string{"clear"},
@@ -90,6 +181,29 @@ func TestDrawCommands_FormatRequests(t *testing.T) {
 	}
 }
 
+func TestDrawCmd_Help(t *testing.T) {
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return nil, fmt.Errorf("client should not be created")
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"circle", "--help"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "--fill") {
+		t.Fatalf("expected circle help, got %q", buf.String())
+	}
+}
+
 func TestFillRectCmd_InvalidSize(t *testing.T) {
 	called := false
 	restore := drawNewClient
@@ -145,3 +259,100 @@ func TestLineCmd_InvalidInteger(t *testing.T) {
 		t.Fatalf("expected client not to be created for invalid args")
 	}
 }
+
+func TestFillCmd_InvalidConnectivity(t *testing.T) {
+	called := false
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		called = true
+		return nil, fmt.Errorf("client should not be created")
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"fill", "--connectivity", "6", "0", "0", "red"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected error for invalid connectivity")
+	}
+	if !strings.Contains(err.Error(), "err invalid_args") {
+		t.Fatalf("expected invalid_args error, got %q", err.Error())
+	}
+	if called {
+		t.Fatalf("expected client not to be created for invalid args")
+	}
+}
+
+func TestShapeCmds_NegativeRadius(t *testing.T) {
+	cases := [][]string{
+		{"circle", "0", "0", "-1", "red"},
+		{"ellipse", "0", "0", "2", "-1", "red"},
+		{"arc", "0", "0", "-3", "0", "90", "red"},
+	}
+	for _, args := range cases {
+		called := false
+		restore := drawNewClient
+		drawNewClient = func(socketPath string) (requestSender, error) {
+			called = true
+			return nil, fmt.Errorf("client should not be created")
+		}
+
+		cmd := NewRootCmd("dev")
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+		drawNewClient = restore
+		if err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+		if !strings.Contains(err.Error(), "err invalid_args") {
+			t.Fatalf("expected invalid_args error for %v, got %q", args, err.Error())
+		}
+		if called {
+			t.Fatalf("expected client not to be created for %v", args)
+		}
+	}
+}
+
+func TestPolygonCmds_InvalidPoints(t *testing.T) {
+	cases := [][]string{
+		{"polyline", "0,0", "red"},
+		{"polyline", "0,0", "1;1", "red"},
+		{"polygon", "0,0", "1,1", "red"},
+		{"polygon", "0,0", "1,1", "2,x", "red"},
+		{"polygon", "--rule", "winding", "0,0", "1,1", "2,2", "red"},
+		{"rect", "0", "0", "0", "2", "red"},
+	}
+	for _, args := range cases {
+		called := false
+		restore := drawNewClient
+		drawNewClient = func(socketPath string) (requestSender, error) {
+			called = true
+			return nil, fmt.Errorf("client should not be created")
+		}
+
+		cmd := NewRootCmd("dev")
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+		drawNewClient = restore
+		if err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+		if !strings.Contains(err.Error(), "err invalid_args") {
+			t.Fatalf("expected invalid_args error for %v, got %q", args, err.Error())
+		}
+		if called {
+			t.Fatalf("expected client not to be created for %v", args)
+		}
+	}
+}
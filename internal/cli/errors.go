@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"pxcli/internal/canvas"
 	"pxcli/internal/daemon"
 )
 
@@ -19,5 +20,9 @@ func formatDaemonError(err error) error {
 		}
 		return fmt.Errorf("err %s %s", daemonErr.Code, daemonErr.Message)
 	}
+	var canvasErr canvas.Error
+	if errors.As(err, &canvasErr) {
+		return fmt.Errorf("err %s %s", canvasErr.Code, canvasErr.Message)
+	}
 	return err
 }
@@ -28,6 +28,11 @@ func TestHistoryCommands_FormatRequests(t *testing.T) {
 			args:        []string{"redo"},
 			wantRequest: "redo",
 		},
+		{
+			name:        "history stats",
+			args:        []string{"history", "stats"},
+			wantRequest: "history stats",
+		},
 	}
 
 	for _, tt := range tests {
@@ -69,13 +74,6 @@ func TestHistoryCommands_FormatRequests(t *testing.T) {
 func TestUndoCmd_RevertsCanvasState(t *testing.T) {
 	dir := testutil.TempDir(t)
 	socketPath := filepath.Join(dir, "pxcli.sock")
-	pidPath := filepath.Join(dir, "pxcli.pid")
-
-	restorePID := daemonPIDPath
-	daemonPIDPath = pidPath
-	t.Cleanup(func() {
-		daemonPIDPath = restorePID
-	})
 
 	daemonCmd := NewRootCmd("dev")
 	daemonCmd.SetOut(io.Discard)
@@ -140,13 +138,6 @@ func TestUndoCmd_RevertsCanvasState(t *testing.T) {
 func TestRedoCmd_NoHistory(t *testing.T) {
 	dir := testutil.TempDir(t)
 	socketPath := filepath.Join(dir, "pxcli.sock")
-	pidPath := filepath.Join(dir, "pxcli.pid")
-
-	restorePID := daemonPIDPath
-	daemonPIDPath = pidPath
-	t.Cleanup(func() {
-		daemonPIDPath = restorePID
-	})
 
 	daemonCmd := NewRootCmd("dev")
 	daemonCmd.SetOut(io.Discard)
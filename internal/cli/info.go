@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/command"
+	"pxcli/internal/daemon"
+	"pxcli/internal/protocol"
+)
+
+// newHelloCmd creates the hello command, which asks for this pxcli's protocol
+// version.
+func newHelloCmd(spec command.Spec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   spec.Name,
+		Short: spec.Short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sendCommandRequest(cmd, spec.Name, strconv.Itoa(protocol.Version))
+		},
+	}
+	cmd.Flags().SetInterspersed(false)
+
+	return cmd
+}
+
+// Schema describes every daemon request for tools that generate their own
+// bindings.
+type Schema struct {
+	Protocol int             `json:"protocol"`
+	Groups   []command.Group `json:"groups"`
+	Commands []command.Spec  `json:"commands"`
+}
+
+// NewSchemaCmd creates the schema command.
+func NewSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the command registry as JSON without contacting the daemon",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encoded, err := json.MarshalIndent(Schema{
+				Protocol: protocol.Version,
+				Groups:   daemon.Groups(),
+				Commands: daemon.Specs(),
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(encoded))
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"pxcli/internal/client"
+	"pxcli/internal/command"
+	"pxcli/internal/daemon"
+	"pxcli/internal/protocol"
+)
+
+func TestInfoCommands_FormatRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantRequest string
+	}{
+		{name: "hello", args: []string{"hello"}, wantRequest: fmt.Sprintf("hello %d", protocol.Version)},
+		{name: "capabilities", args: []string{"capabilities"}, wantRequest: "capabilities"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubClient{response: client.Response{Raw: "ok"}}
+			restore := drawNewClient
+			drawNewClient = func(socketPath string) (requestSender, error) {
+				return stub, nil
+			}
+			t.Cleanup(func() {
+				drawNewClient = restore
+			})
+
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(stub.requests) != 1 || stub.requests[0] != tt.wantRequest {
+				t.Fatalf("expected request %q, got %v", tt.wantRequest, stub.requests)
+			}
+		})
+	}
+}
+
+func TestCommand_ReportsOlderDaemon(t *testing.T) {
+	unknown := client.Error{Code: "invalid_command", Message: `unknown command "frame"`}
+	older := client.Error{Code: "unsupported_protocol", Message: "daemon predates protocol 1; restart the daemon with this pxcli"}
+	tests := []struct {
+		name     string
+		helloErr error
+		json     bool
+		want     string
+	}{
+		{name: "older daemon", helloErr: older, want: "err unsupported_protocol "},
+		{name: "older daemon json", helloErr: older, json: true, want: `"code":"unsupported_protocol"`},
+		{name: "current daemon", want: "err invalid_command "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubClient{
+				response:     client.Response{Raw: "err invalid_command " + unknown.Message},
+				jsonResponse: protocol.JSONErrorResponse(nil, unknown.Code, unknown.Message),
+				err:          unknown,
+				helloErr:     tt.helloErr,
+			}
+			restore := drawNewClient
+			drawNewClient = func(socketPath string) (requestSender, error) {
+				return stub, nil
+			}
+			t.Cleanup(func() {
+				drawNewClient = restore
+			})
+
+			buf := &bytes.Buffer{}
+			args := []string{"frame", "list"}
+			if tt.json {
+				args = append([]string{"--json"}, args...)
+			}
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(args)
+			err := cmd.Execute()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			got := err.Error()
+			if tt.json {
+				got = buf.String()
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Fatalf("expected %q in %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSchemaCmd_ListsRegistry(t *testing.T) {
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		t.Fatal("schema must not contact the daemon")
+		return nil, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"schema"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if schema.Protocol != protocol.Version || len(schema.Groups) == 0 {
+		t.Fatalf("unexpected schema header %+v", schema)
+	}
+	var polygon *command.Spec
+	for i := range schema.Commands {
+		if schema.Commands[i].Name == "polygon" {
+			polygon = &schema.Commands[i]
+		}
+	}
+	if polygon == nil || len(polygon.Params) != 2 || polygon.Params[0].MinCount != 3 {
+		t.Fatalf("unexpected polygon spec %+v", polygon)
+	}
+	if rule, ok := polygon.Flag("rule"); !ok || rule.Default != "evenodd" || len(rule.Values) != 2 {
+		t.Fatalf("unexpected rule flag %+v", rule)
+	}
+}
+
+func TestRootCmd_HasCommandForEverySpec(t *testing.T) {
+	root := NewRootCmd("dev")
+	for _, spec := range daemon.Specs() {
+		if override, ok := specOverrides[spec.Name]; ok && override == nil {
+			if found, _, err := root.Find(strings.Fields(spec.Name)); err == nil && found != root {
+				t.Fatalf("unexpected CLI command for per-connection request %q", spec.Name)
+			}
+			continue
+		}
+		found, _, err := root.Find(strings.Fields(spec.Name))
+		if err != nil || found.CommandPath() != "pxcli "+spec.Name {
+			t.Fatalf("no CLI command for %q", spec.Name)
+		}
+		if found.Short != spec.Short {
+			t.Fatalf("%s: expected summary %q, got %q", spec.Name, spec.Short, found.Short)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"pxcli/internal/client"
+)
+
+func TestLayerAndFrameCommands_FormatRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantRequest string
+	}{
+		{name: "list", args: []string{"layer", "list"}, wantRequest: "layer list"},
+		{name: "add_default", args: []string{"layer", "add"}, wantRequest: "layer add"},
+		{name: "add_named", args: []string{"layer", "add", "ink"}, wantRequest: "layer add ink"},
+		{name: "add_spaced", args: []string{"layer", "add", "line art"}, wantRequest: `layer add "line art"`},
+		{name: "remove", args: []string{"layer", "remove", "ink"}, wantRequest: "layer remove ink"},
+		{name: "move", args: []string{"layer", "move", "ink", "0"}, wantRequest: "layer move ink 0"},
+		{name: "rename", args: []string{"layer", "rename", "1", "shading"}, wantRequest: "layer rename 1 shading"},
+		{name: "select", args: []string{"layer", "select", "background"}, wantRequest: "layer select background"},
+		{name: "hide", args: []string{"layer", "hide", "ink"}, wantRequest: "layer hide ink"},
+		{name: "unlock", args: []string{"layer", "unlock", "ink"}, wantRequest: "layer unlock ink"},
+		{name: "opacity", args: []string{"layer", "opacity", "ink", "40"}, wantRequest: "layer opacity ink 40"},
+		{name: "blend", args: []string{"layer", "blend", "ink", "screen"}, wantRequest: "layer blend ink screen"},
+		{name: "get_pixel_layer", args: []string{"get_pixel", "--layer", "ink", "1", "2"}, wantRequest: "get_pixel 1 2 --layer=ink"},
+		{name: "get_pixel_spaced_layer", args: []string{"get_pixel", "--layer", "line art", "1", "2"}, wantRequest: `get_pixel 1 2 "--layer=line art"`},
+		{name: "frame_add", args: []string{"frame", "add"}, wantRequest: "frame add"},
+		{name: "frame_dup", args: []string{"frame", "dup", "0"}, wantRequest: "frame dup 0"},
+		{name: "frame_move", args: []string{"frame", "move", "2", "0"}, wantRequest: "frame move 2 0"},
+		{name: "frame_duration", args: []string{"frame", "duration", "1", "250"}, wantRequest: "frame duration 1 250"},
+		{name: "frame_play", args: []string{"frame", "play"}, wantRequest: "frame play"},
+		{name: "doc_new", args: []string{"doc", "new", "tiles", "16x16"}, wantRequest: "doc new tiles 16x16"},
+		{name: "doc_select", args: []string{"doc", "select", "tiles"}, wantRequest: "doc select tiles"},
+		{name: "doc_close", args: []string{"doc", "close", "tiles"}, wantRequest: "doc close tiles"},
+		{name: "palette_add", args: []string{"palette", "add", "#ff0000", "ember"}, wantRequest: "palette add #ff0000 ember"},
+		{name: "palette_remove", args: []string{"palette", "remove", "deep blue"}, wantRequest: `palette remove "deep blue"`},
+		{name: "palette_lock", args: []string{"palette", "lock", "snap"}, wantRequest: "palette lock snap"},
+		{name: "ramp", args: []string{"ramp", "--steps", "7", "--add", "brick", "#c83c28"}, wantRequest: "ramp #c83c28 --steps=7 --add=brick"},
+		{name: "ramp_swatch_base", args: []string{"ramp", "--hue-shift", "-20", "@ember"}, wantRequest: "ramp @ember --hue-shift=-20"},
+		{name: "set_pixel_swatch", args: []string{"set_pixel", "1", "2", "@ember"}, wantRequest: "set_pixel 1 2 @ember"},
+		{name: "set_pixel_doc", args: []string{"set_pixel", "--doc", "tiles", "1", "2", "red"}, wantRequest: "set_pixel 1 2 red --doc=tiles"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubClient{
+				response: client.Response{Raw: "ok"},
+			}
+			restore := drawNewClient
+			drawNewClient = func(socketPath string) (requestSender, error) {
+				return stub, nil
+			}
+			t.Cleanup(func() {
+				drawNewClient = restore
+			})
+
+			buf := &bytes.Buffer{}
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(stub.requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(stub.requests))
+			}
+			if stub.requests[0] != tt.wantRequest {
+				t.Fatalf("expected request %q, got %q", tt.wantRequest, stub.requests[0])
+			}
+			if strings.TrimSpace(buf.String()) != "ok" {
+				t.Fatalf("expected ok output, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestLayerOpacityCmd_OutOfRange(t *testing.T) {
+	stub := &stubClient{}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"layer", "opacity", "ink", "150"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.HasPrefix(err.Error(), "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %v", err)
+	}
+	if len(stub.requests) != 0 {
+		t.Fatalf("expected no requests, got %d", len(stub.requests))
+	}
+}
+
+func TestFrameCmd_InvalidIndex(t *testing.T) {
+	stub := &stubClient{}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"frame", "select", "first"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.HasPrefix(err.Error(), "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %v", err)
+	}
+	if len(stub.requests) != 0 {
+		t.Fatalf("expected no requests, got %d", len(stub.requests))
+	}
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/config"
+	"pxcli/internal/daemon"
+)
+
+var listInstances = func() ([]daemon.Instance, error) {
+	return daemon.ListInstances(config.InstanceDir(), nil)
+}
+
+// instanceInfo is a live daemon as reported by list. Width and Height are
+// zero when the daemon did not answer.
+type instanceInfo struct {
+	Name   string `json:"name"`
+	PID    int    `json:"pid"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Socket string `json:"socket"`
+}
+
+// NewListCmd creates the list command.
+func NewListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List running daemon instances with their canvas sizes and PIDs",
+		Long: "List running daemon instances with their canvas sizes and PIDs.\n\n" +
+			"Daemons are found by their PID files: the default daemon and every --instance.\n" +
+			"A daemon started with a custom --socket is not listed; check it with status --socket.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			instances, err := listInstances()
+			if err != nil {
+				return err
+			}
+			infos := make([]instanceInfo, len(instances))
+			for i, instance := range instances {
+				infos[i] = instanceInfo{Name: instance.Name, PID: instance.PID, Socket: instance.SocketPath}
+				infos[i].Width, infos[i].Height = canvasSize(instance.SocketPath)
+			}
+			if jsonOutput(cmd) {
+				printJSONResult(cmd, infos)
+				return nil
+			}
+			for _, info := range infos {
+				size := "unknown"
+				if info.Width > 0 {
+					size = fmt.Sprintf("%dx%d", info.Width, info.Height)
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s pid=%d size=%s socket=%s\n", info.Name, info.PID, size, info.Socket)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// canvasSize asks the daemon on socketPath for its canvas size, returning
+// zeros when it does not answer.
+func canvasSize(socketPath string) (int, int) {
+	cli, err := drawNewClient(socketPath)
+	if err != nil {
+		return 0, 0
+	}
+	resp, err := cli.Send("capabilities")
+	if err != nil {
+		return 0, 0
+	}
+	var caps daemon.Capabilities
+	if err := json.Unmarshal([]byte(resp.Payload), &caps); err != nil {
+		return 0, 0
+	}
+	return caps.Canvas.Width, caps.Canvas.Height
+}
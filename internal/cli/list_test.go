@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/client"
+	"pxcli/internal/daemon"
+	"pxcli/internal/testutil"
+)
+
+func TestListCmd_ReportsInstances(t *testing.T) {
+	restoreList := listInstances
+	listInstances = func() ([]daemon.Instance, error) {
+		return []daemon.Instance{
+			{Name: "hero", PID: 41, SocketPath: "/run/pxcli/hero.sock"},
+			{Name: "tiles", PID: 42, SocketPath: "/run/pxcli/tiles.sock"},
+		}, nil
+	}
+	restoreClient := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		if socketPath == "/run/pxcli/tiles.sock" {
+			return nil, errors.New("connection refused")
+		}
+		return &stubClient{response: client.Response{Raw: `ok {"protocol":1,"canvas":{"width":16,"height":8}}`, Payload: `{"protocol":1,"canvas":{"width":16,"height":8}}`}}, nil
+	}
+	t.Cleanup(func() {
+		listInstances = restoreList
+		drawNewClient = restoreClient
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "line",
+			args: []string{"list"},
+			want: "hero pid=41 size=16x8 socket=/run/pxcli/hero.sock\n" +
+				"tiles pid=42 size=unknown socket=/run/pxcli/tiles.sock\n",
+		},
+		{
+			name: "json",
+			args: []string{"--json", "list"},
+			want: `{"status":"ok","result":[{"name":"hero","pid":41,"width":16,"height":8,"socket":"/run/pxcli/hero.sock"},` +
+				`{"name":"tiles","pid":42,"width":0,"height":0,"socket":"/run/pxcli/tiles.sock"}]}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestInstanceFlag_DerivesPaths(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	var socketPath, pidPath string
+	root := NewRootCmd("dev")
+	root.AddCommand(&cobra.Command{
+		Use: "client",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			socketPath, pidPath, err = daemonPaths(cmd)
+			return err
+		},
+	})
+	root.SetArgs([]string{"client", "--instance", "hero"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(runtimeDir, "pxcli", "hero.sock"); socketPath != want {
+		t.Fatalf("expected socket %q, got %q", want, socketPath)
+	}
+	if want := filepath.Join(runtimeDir, "pxcli", "hero.pid"); pidPath != want {
+		t.Fatalf("expected PID path %q, got %q", want, pidPath)
+	}
+}
+
+func TestInstanceFlag_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"undo", "--instance", "hero", "--socket", "/tmp/other.sock"}, want: "cannot be combined"},
+		{args: []string{"undo", "--instance", "../hero"}, want: "invalid instance name"},
+	} {
+		cmd := NewRootCmd("dev")
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%v: expected %q error, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestListCmd_FindsInstanceDaemons(t *testing.T) {
+	runtimeDir := testutil.TempDir(t)
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	socketPath := filepath.Join(runtimeDir, "pxcli", "sprite.sock")
+
+	t.Cleanup(func() {
+		if _, err := os.Stat(socketPath); err == nil {
+			_, _ = sendRequest(socketPath, "stop\n")
+		}
+	})
+
+	daemonCmd := NewRootCmd("dev")
+	daemonCmd.SetOut(io.Discard)
+	daemonCmd.SetErr(io.Discard)
+	daemonCmd.SetArgs([]string{"daemon", "--headless", "--size", "8x4", "--instance", "sprite"})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- daemonCmd.Execute()
+	}()
+	waitForPath(t, socketPath)
+	waitForPath(t, filepath.Join(runtimeDir, "pxcli", "sprite.pid"))
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"list"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := fmt.Sprintf("sprite pid=%d size=8x4 socket=%s\n", os.Getpid(), socketPath)
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected %q in %q", want, buf.String())
+	}
+
+	if response := mustSendRequest(t, socketPath, "stop\n"); response != "ok\n" {
+		t.Fatalf("expected ok stop response, got %q", response)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected daemon error: %v", err)
+	}
+}
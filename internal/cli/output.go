@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/protocol"
+)
+
+// printedError wraps an error whose JSON response has already been printed.
+type printedError struct {
+	error
+}
+
+func (e printedError) Unwrap() error {
+	return e.error
+}
+
+// jsonOutput reports whether --json was requested for the command.
+func jsonOutput(cmd *cobra.Command) bool {
+	enabled, err := cmd.Flags().GetBool("json")
+	return err == nil && enabled
+}
+
+// printJSON writes a JSON response line to stdout.
+func printJSON(cmd *cobra.Command, response protocol.JSONResponse) {
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), protocol.FormatJSON(response))
+}
+
+// printJSONResult writes a successful JSON response carrying result.
+func printJSONResult(cmd *cobra.Command, result any) {
+	response := protocol.JSONResponse{Status: protocol.StatusOK}
+	if result != nil {
+		encoded, err := json.Marshal(result)
+		if err == nil {
+			response.Result = encoded
+		}
+	}
+	printJSON(cmd, response)
+}
+
+// reportJSON prints a daemon JSON response and converts a failure into the
+// command error, so it is not printed twice.
+func reportJSON(cmd *cobra.Command, response protocol.JSONResponse, err error) error {
+	if response.Status == "" {
+		return formatClientError(err)
+	}
+	printJSON(cmd, response)
+	if err != nil {
+		return printedError{formatClientError(err)}
+	}
+	return nil
+}
+
+// enableJSONErrors makes every command in the tree print failures as JSON error
+// responses on stdout when --json is set.
+func enableJSONErrors(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return printJSONError(cmd, run(cmd, args))
+		}
+	}
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			return printJSONError(cmd, validate(cmd, args))
+		}
+	}
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return printJSONError(cmd, err)
+	})
+	for _, sub := range cmd.Commands() {
+		enableJSONErrors(sub)
+	}
+}
+
+func printJSONError(cmd *cobra.Command, err error) error {
+	var printed printedError
+	if err == nil || !jsonOutput(cmd) || errors.As(err, &printed) {
+		return err
+	}
+	code, message := "error", err.Error()
+	if rest, ok := strings.CutPrefix(message, "err "); ok {
+		code, message, _ = strings.Cut(rest, " ")
+	}
+	printJSON(cmd, protocol.JSONErrorResponse(nil, code, message))
+	return printedError{err}
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pxcli/internal/client"
+	"pxcli/internal/protocol"
+)
+
+func TestJSONFlag_SendsJSONRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		response protocol.JSONResponse
+		err      error
+		want     protocol.JSONRequest
+		wantOut  string
+		wantErr  bool
+	}{
+		{
+			name:     "drawing command",
+			args:     []string{"--json", "set_pixel", "1", "2", "red"},
+			response: protocol.JSONResponse{Status: protocol.StatusOK},
+			want:     protocol.NewJSONRequest("set_pixel", "1", "2", "red"),
+			wantOut:  `{"status":"ok"}`,
+		},
+		{
+			name:     "group command",
+			args:     []string{"layer", "--json", "list"},
+			response: protocol.JSONResponse{Status: protocol.StatusOK, Result: json.RawMessage(`[]`)},
+			want:     protocol.NewJSONRequest("layer", "list"),
+			wantOut:  `{"status":"ok","result":[]}`,
+		},
+		{
+			name:     "daemon error",
+			args:     []string{"--json", "get_pixel", "9", "9"},
+			response: protocol.JSONErrorResponse(nil, "out_of_bounds", "pixel (9,9) outside canvas"),
+			err:      client.Error{Code: "out_of_bounds", Message: "pixel (9,9) outside canvas"},
+			want:     protocol.NewJSONRequest("get_pixel", "9", "9"),
+			wantOut:  `{"status":"error","error":{"code":"out_of_bounds","message":"pixel (9,9) outside canvas"}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "batch",
+			args:     []string{"--json", "batch"},
+			stdin:    "set_pixel 0 0 red\nclear\n",
+			response: protocol.JSONResponse{Status: protocol.StatusOK},
+			want: protocol.JSONRequest{Command: "batch", Batch: []protocol.JSONRequest{
+				protocol.NewJSONRequest("set_pixel", "0", "0", "red"),
+				protocol.NewJSONRequest("clear"),
+			}},
+			wantOut: `{"status":"ok"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubClient{jsonResponse: tt.response, err: tt.err}
+			restore := drawNewClient
+			drawNewClient = func(socketPath string) (requestSender, error) {
+				return stub, nil
+			}
+			t.Cleanup(func() {
+				drawNewClient = restore
+			})
+
+			buf := &bytes.Buffer{}
+			cmd := NewRootCmd("dev")
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%t, got %v", tt.wantErr, err)
+			}
+			if len(stub.jsonRequests) != 1 || !reflect.DeepEqual(stub.jsonRequests[0], tt.want) {
+				t.Fatalf("expected request %+v, got %+v", tt.want, stub.jsonRequests)
+			}
+			if got := strings.SplitN(buf.String(), "\n", 2)[0]; got != tt.wantOut {
+				t.Fatalf("expected output %s, got %q", tt.wantOut, buf.String())
+			}
+			if strings.Count(buf.String(), `"status"`) != 1 {
+				t.Fatalf("expected a single JSON response, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestJSONFlag_ReportsLocalErrors(t *testing.T) {
+	stub := &stubClient{}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	buf := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--json", "set_pixel", "x", "1", "red"})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected validation error")
+	}
+	want := `{"status":"error","error":{"code":"invalid_args","message":"x must be an integer"}}`
+	if got := strings.SplitN(buf.String(), "\n", 2)[0]; got != want {
+		t.Fatalf("expected %s, got %q", want, buf.String())
+	}
+	if len(stub.jsonRequests) != 0 {
+		t.Fatalf("expected no request, got %+v", stub.jsonRequests)
+	}
+}
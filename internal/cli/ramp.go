@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pxcolor "pxcli/internal/color"
+	"pxcli/internal/command"
+	"pxcli/internal/protocol"
+)
+
+// newRampCmd creates the ramp command. A ramp around a plain color is
+// computed here, so printing one needs no daemon; only a base that names a
+// swatch or --add, which changes the palette, is sent to the daemon.
+func newRampCmd(spec command.Spec) *cobra.Command {
+	cmd := newSpecCmd(spec)
+	send := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if done, err := printLocalRamp(cmd, spec, args); done {
+			return err
+		}
+		return send(cmd, args)
+	}
+
+	return cmd
+}
+
+// printLocalRamp prints the ramp the args ask for and reports true, unless it
+// needs the daemon's palette.
+func printLocalRamp(cmd *cobra.Command, spec command.Spec, args []string) (bool, error) {
+	positionals, err := parseSpecArgs(cmd, args)
+	if err != nil {
+		return true, err
+	}
+	if help, _ := cmd.Flags().GetBool("help"); help {
+		return false, nil
+	}
+	bound, err := spec.Bind(append(slices.Clone(positionals), changedSpecFlags(cmd, spec)...))
+	if err != nil {
+		return true, usageError(err)
+	}
+	if bound.Has("add") || strings.Contains(bound.String("base"), "@") {
+		return false, nil
+	}
+	base, err := pxcolor.Parse(bound.String("base"))
+	if err != nil {
+		var colorErr pxcolor.Error
+		if errors.As(err, &colorErr) {
+			return true, fmt.Errorf("err %s %s", colorErr.Code, colorErr.Message)
+		}
+		return true, err
+	}
+
+	ramp := pxcolor.Ramp(base, pxcolor.RampOptions{
+		Steps:    bound.Int("steps"),
+		HueShift: float64(bound.Int("hue-shift")),
+		SatCurve: float64(bound.Int("sat-curve")),
+	})
+	colors := make([]string, len(ramp))
+	for i, value := range ramp {
+		colors[i] = pxcolor.Format(value)
+	}
+	if jsonOutput(cmd) {
+		records := make([]map[string]string, len(colors))
+		for i, value := range colors {
+			records[i] = map[string]string{"color": value}
+		}
+		printJSONResult(cmd, records)
+		return true, nil
+	}
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), protocol.FormatOK(strings.Join(colors, "; ")))
+	return true, nil
+}
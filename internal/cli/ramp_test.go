@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRampCmd_ComputesWithoutDaemon(t *testing.T) {
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return nil, fmt.Errorf("client should not be created")
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"ramp", "--steps", "3", "--hue-shift", "0", "red"}, want: "ok #330000ff; #ff0000ff; #ffccccff\n"},
+		{
+			args: []string{"--json", "ramp", "--steps", "3", "--hue-shift", "0", "red"},
+			want: `{"status":"ok","result":[{"color":"#330000ff"},{"color":"#ff0000ff"},{"color":"#ffccccff"}]}` + "\n",
+		},
+	} {
+		buf := &bytes.Buffer{}
+		cmd := NewRootCmd("dev")
+		cmd.SetOut(buf)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(tt.args)
+
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if buf.String() != tt.want {
+			t.Fatalf("%v: expected %q, got %q", tt.args, tt.want, buf.String())
+		}
+	}
+}
+
+func TestRampCmd_RejectsInvalidArgsLocally(t *testing.T) {
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return nil, fmt.Errorf("client should not be created")
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	for args, want := range map[string]string{
+		"ramp nocolor":            "err invalid_color ",
+		"ramp --steps 1 #c83c28":  "err invalid_args ",
+		"ramp --hue-shift 999 #f": "err invalid_args ",
+	} {
+		cmd := NewRootCmd("dev")
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(strings.Fields(args))
+
+		if err := cmd.Execute(); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Fatalf("%s: expected %q error, got %v", args, want, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/client"
+	"pxcli/internal/protocol"
+)
+
+type requestSender interface {
+	Send(request string) (client.Response, error)
+	SendBatch(operations []string, options ...string) (client.Response, error)
+	SendJSON(request protocol.JSONRequest) (protocol.JSONResponse, error)
+	Hello() (client.Response, error)
+}
+
+type clientFactory func(socketPath string) (requestSender, error)
+
+var drawNewClient clientFactory = func(socketPath string) (requestSender, error) {
+	return client.New(socketPath)
+}
+
+// sendCommandRequest sends a command to the daemon, quoting arguments that
+// need it, and prints the response.
+func sendCommandRequest(cmd *cobra.Command, command string, args ...string) error {
+	cli, err := requestClient(cmd, command)
+	if err != nil {
+		return err
+	}
+	if jsonOutput(cmd) {
+		resp, err := cli.SendJSON(protocol.NewJSONRequest(command, args...))
+		if helloErr, ok := olderDaemon(cli, err); ok {
+			resp, err = protocol.JSONErrorResponse(resp.ID, helloErr.Code, helloErr.Message), helloErr
+		}
+		return reportJSON(cmd, resp, err)
+	}
+	resp, err := cli.Send(protocol.FormatLine(command, args...))
+	if helloErr, ok := olderDaemon(cli, err); ok {
+		err = helloErr
+	}
+	if err != nil {
+		return formatClientError(err)
+	}
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), resp.Raw)
+	return nil
+}
+
+// olderDaemon runs the protocol handshake after an invalid_command error, and
+// reports the handshake error if the command is unknown only because the daemon
+// is older than this pxcli.
+func olderDaemon(cli requestSender, err error) (client.Error, bool) {
+	var clientErr client.Error
+	if !errors.As(err, &clientErr) || clientErr.Code != "invalid_command" {
+		return client.Error{}, false
+	}
+	_, err = cli.Hello()
+	if !errors.As(err, &clientErr) || clientErr.Code != "unsupported_protocol" {
+		return client.Error{}, false
+	}
+	return clientErr, true
+}
+
+// sendGroupRequest sends a "<group> <sub> args..." request for a command group.
+func sendGroupRequest(cmd *cobra.Command, group, sub string, args ...string) error {
+	return sendCommandRequest(cmd, group, append([]string{sub}, args...)...)
+}
+
+func invalidArgsf(format string, args ...any) error {
+	message := strings.TrimSpace(fmt.Sprintf(format, args...))
+	if message == "" {
+		return fmt.Errorf("err invalid_args")
+	}
+	return fmt.Errorf("err invalid_args %s", message)
+}
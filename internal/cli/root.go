@@ -8,7 +8,12 @@ import (
 
 // NewRootCmd returns the root pxcli command.
 func NewRootCmd(version string) *cobra.Command {
-	var socketPath string
+	var (
+		socketPath string
+		instance   string
+		jsonMode   bool
+		autoStart  bool
+	)
 
 	cmd := &cobra.Command{
 		Use:           "pxcli",
@@ -26,21 +31,20 @@ func NewRootCmd(version string) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVar(&socketPath, "socket", config.DefaultSocketPath, "Unix socket path")
+	cmd.PersistentFlags().StringVar(&instance, "instance", "", "Named daemon instance; derives the socket and PID paths under the runtime dir")
+	cmd.PersistentFlags().BoolVar(&jsonMode, "json", false, "Print results and errors as JSON responses")
+	cmd.PersistentFlags().BoolVar(&autoStart, "auto-start", false, "Start a daemon with default settings if none is running (or set "+config.AutoStartEnv+"=1)")
 
 	cmd.Version = version
 	cmd.SetVersionTemplate("{{.Version}}\n")
 
 	cmd.AddCommand(NewStartCmd())
 	cmd.AddCommand(NewDaemonCmd())
-	cmd.AddCommand(NewStopCmd())
-	cmd.AddCommand(NewSetPixelCmd())
-	cmd.AddCommand(NewFillRectCmd())
-	cmd.AddCommand(NewLineCmd())
-	cmd.AddCommand(NewClearCmd())
-	cmd.AddCommand(NewGetPixelCmd())
-	cmd.AddCommand(NewExportCmd())
-	cmd.AddCommand(NewUndoCmd())
-	cmd.AddCommand(NewRedoCmd())
+	cmd.AddCommand(NewListCmd())
+	addSpecCmds(cmd)
+	cmd.AddCommand(NewSchemaCmd())
+
+	enableJSONErrors(cmd)
 
 	return cmd
 }
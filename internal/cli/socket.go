@@ -5,16 +5,35 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"pxcli/internal/config"
 )
 
 // SocketPath returns the resolved socket path for a command.
 func SocketPath(cmd *cobra.Command) (string, error) {
+	socketPath, _, err := daemonPaths(cmd)
+	return socketPath, err
+}
+
+// daemonPaths returns the socket and PID file paths for a command: those of
+// the --instance it names, or --socket and the PID file beside it.
+func daemonPaths(cmd *cobra.Command) (string, string, error) {
+	instance, err := cmd.Flags().GetString("instance")
+	if err != nil {
+		return "", "", err
+	}
+	if instance != "" {
+		if cmd.Flags().Changed("socket") {
+			return "", "", fmt.Errorf("--instance and --socket cannot be combined")
+		}
+		return config.InstancePaths(instance)
+	}
 	socketPath, err := cmd.Flags().GetString("socket")
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	if strings.TrimSpace(socketPath) == "" {
-		return "", fmt.Errorf("socket path must not be empty")
+		return "", "", fmt.Errorf("socket path must not be empty")
 	}
-	return socketPath, nil
+	return socketPath, config.PIDPathForSocket(socketPath), nil
 }
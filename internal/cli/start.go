@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -12,10 +13,24 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"pxcli/internal/canvas"
 	"pxcli/internal/config"
 	"pxcli/internal/daemon"
 )
 
+// daemonOptions are the settings start forwards to the daemon process.
+type daemonOptions struct {
+	size           string
+	scale          int
+	headless       bool
+	from           string
+	indexed        bool
+	historyEntries int
+	historyBytes   int64
+	idleTimeout    time.Duration
+	autosaveDir    string
+}
+
 type daemonProcess struct {
 	pid     int
 	release func() error
@@ -33,9 +48,15 @@ var (
 // NewStartCmd creates the start command with shared flags.
 func NewStartCmd() *cobra.Command {
 	var (
-		size     string
-		scale    int
-		headless bool
+		size           string
+		scale          int
+		headless       bool
+		from           string
+		indexed        bool
+		historyEntries int
+		historyBytes   int64
+		idleTimeout    time.Duration
+		autosaveDir    string
 	)
 
 	cmd := &cobra.Command{
@@ -43,7 +64,7 @@ func NewStartCmd() *cobra.Command {
 		Short: "Start the pxcli daemon",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			socketPath, err := SocketPath(cmd)
+			socketPath, pidPath, err := daemonPaths(cmd)
 			if err != nil {
 				return err
 			}
@@ -54,32 +75,56 @@ func NewStartCmd() *cobra.Command {
 			if scale <= 0 {
 				return fmt.Errorf("invalid scale %d: must be > 0", scale)
 			}
-			if err := daemon.ValidateRenderer(headless); err != nil {
-				return formatDaemonError(err)
+			if err := validateHistoryLimits(historyEntries, historyBytes); err != nil {
+				return err
 			}
-			if err := startEnsureReady(daemonPIDPath, socketPath); err != nil {
-				return formatDaemonError(err)
+			if idleTimeout < 0 {
+				return fmt.Errorf("invalid idle timeout %s: must be >= 0", idleTimeout)
 			}
-
-			daemonArgs := buildDaemonArgs(socketPath, fmt.Sprintf("%dx%d", width, height), scale, headless)
-			executable, err := os.Executable()
-			if err != nil {
-				return err
+			if autosaveDir != "" {
+				if autosaveDir, err = filepath.Abs(autosaveDir); err != nil {
+					return invalidArgsf("invalid path: %v", err)
+				}
 			}
-
-			proc, err := startSpawnDaemon(executable, daemonArgs)
-			if err != nil {
-				return err
+			if from != "" {
+				if from, err = filepath.Abs(from); err != nil {
+					return invalidArgsf("invalid path: %v", err)
+				}
+				img, err := canvas.ReadPNG(from)
+				if err != nil {
+					return formatDaemonError(err)
+				}
+				if indexed {
+					if _, err := canvas.NewIndexedFromImage(img); err != nil {
+						return formatDaemonError(err)
+					}
+				}
+				bounds := img.Bounds()
+				width, height = bounds.Dx(), bounds.Dy()
 			}
-			if proc.release != nil {
-				defer proc.release()
+			if err := daemon.ValidateRenderer(headless); err != nil {
+				return formatDaemonError(err)
 			}
-
-			if err := startWaitForReady(socketPath, startWaitTimeout); err != nil {
-				return err
+			pid, err := startDaemon(socketPath, pidPath, daemonOptions{
+				size:           fmt.Sprintf("%dx%d", width, height),
+				scale:          scale,
+				headless:       headless,
+				from:           from,
+				indexed:        indexed,
+				historyEntries: historyEntries,
+				historyBytes:   historyBytes,
+				idleTimeout:    idleTimeout,
+				autosaveDir:    autosaveDir,
+			})
+			if err != nil {
+				return formatDaemonError(err)
 			}
 
-			_, _ = fmt.Fprintln(cmd.OutOrStdout(), proc.pid)
+			if jsonOutput(cmd) {
+				printJSONResult(cmd, map[string]int{"pid": pid})
+				return nil
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), pid)
 			return nil
 		},
 	}
@@ -87,23 +132,90 @@ func NewStartCmd() *cobra.Command {
 	cmd.Flags().StringVar(&size, "size", fmt.Sprintf("%dx%d", config.DefaultCanvasWidth, config.DefaultCanvasHeight), "Canvas size in WxH")
 	cmd.Flags().IntVar(&scale, "scale", config.DefaultScale, "Canvas scale (reserved for windowed mode)")
 	cmd.Flags().BoolVar(&headless, "headless", config.DefaultHeadless, "Run without a GUI")
+	cmd.Flags().StringVar(&from, "from", "", "Size the canvas to a PNG file and initialize it from the image")
+	cmd.Flags().BoolVar(&indexed, "indexed", false, "Store palette indices instead of colors, so palette edits recolor the canvas; with --from the palette starts as the image's colors")
+	addHistoryLimitFlags(cmd, &historyEntries, &historyBytes)
+	addIdleFlags(cmd, &idleTimeout, &autosaveDir)
 
 	return cmd
 }
 
-func buildDaemonArgs(socketPath, size string, scale int, headless bool) []string {
+// startDaemon spawns a daemon process serving socketPath and waits until it
+// accepts connections, returning its PID.
+func startDaemon(socketPath, pidPath string, opts daemonOptions) (int, error) {
+	if err := startEnsureReady(pidPath, socketPath); err != nil {
+		return 0, err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	proc, err := startSpawnDaemon(executable, buildDaemonArgs(socketPath, opts))
+	if err != nil {
+		return 0, err
+	}
+	if proc.release != nil {
+		defer proc.release()
+	}
+	if err := startWaitForReady(socketPath, startWaitTimeout); err != nil {
+		return 0, err
+	}
+	return proc.pid, nil
+}
+
+func buildDaemonArgs(socketPath string, opts daemonOptions) []string {
 	args := []string{
 		"daemon",
-		"--size", size,
-		"--scale", strconv.Itoa(scale),
-		fmt.Sprintf("--headless=%t", headless),
+		"--size", opts.size,
+		"--scale", strconv.Itoa(opts.scale),
+		fmt.Sprintf("--headless=%t", opts.headless),
 	}
 	if strings.TrimSpace(socketPath) != "" {
 		args = append(args, "--socket", socketPath)
 	}
+	if opts.from != "" {
+		args = append(args, "--from", opts.from)
+	}
+	if opts.indexed {
+		args = append(args, "--indexed")
+	}
+	if opts.historyEntries != config.DefaultHistoryEntries {
+		args = append(args, "--history-entries", strconv.Itoa(opts.historyEntries))
+	}
+	if opts.historyBytes != config.DefaultHistoryBytes {
+		args = append(args, "--history-bytes", strconv.FormatInt(opts.historyBytes, 10))
+	}
+	if opts.idleTimeout > 0 {
+		args = append(args, "--idle-timeout", opts.idleTimeout.String())
+	}
+	if opts.autosaveDir != "" {
+		args = append(args, "--autosave-dir", opts.autosaveDir)
+	}
 	return args
 }
 
+// addHistoryLimitFlags registers the undo history cap flags shared by start and daemon.
+func addHistoryLimitFlags(cmd *cobra.Command, entries *int, bytes *int64) {
+	cmd.Flags().IntVar(entries, "history-entries", config.DefaultHistoryEntries, "Maximum undo/redo entries to keep (0 for no limit)")
+	cmd.Flags().Int64Var(bytes, "history-bytes", config.DefaultHistoryBytes, "Maximum bytes of undo/redo history to keep (0 for no limit)")
+}
+
+// addIdleFlags registers the idle shutdown and autosave flags shared by start and daemon.
+func addIdleFlags(cmd *cobra.Command, timeout *time.Duration, autosaveDir *string) {
+	cmd.Flags().DurationVar(timeout, "idle-timeout", 0, "Stop the daemon after this long without requests, e.g. 30m (0 never stops)")
+	cmd.Flags().StringVar(autosaveDir, "autosave-dir", "", "Save documents here when the daemon stops idle or on a signal (default with --idle-timeout: $XDG_STATE_HOME/pxcli/autosave)")
+}
+
+func validateHistoryLimits(entries int, bytes int64) error {
+	if entries < 0 {
+		return fmt.Errorf("invalid history entries %d: must be >= 0", entries)
+	}
+	if bytes < 0 {
+		return fmt.Errorf("invalid history bytes %d: must be >= 0", bytes)
+	}
+	return nil
+}
+
 func spawnDaemonProcess(binary string, args []string) (daemonProcess, error) {
 	cmd := exec.Command(binary, args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
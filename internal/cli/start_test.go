@@ -10,13 +10,20 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"pxcli/internal/client"
+	"pxcli/internal/config"
 	"pxcli/internal/testutil"
 )
 
 func TestBuildDaemonArgs(t *testing.T) {
-	got := buildDaemonArgs("/tmp/pxcli.sock", "8x8", 12, false)
+	got := buildDaemonArgs("/tmp/pxcli.sock", daemonOptions{
+		size:           "8x8",
+		scale:          12,
+		historyEntries: config.DefaultHistoryEntries,
+		historyBytes:   config.DefaultHistoryBytes,
+	})
 	want := []string{
 		"daemon",
 		"--size", "8x8",
@@ -30,6 +37,124 @@ func TestBuildDaemonArgs(t *testing.T) {
 	}
 }
 
+func TestBuildDaemonArgs_From(t *testing.T) {
+	got := buildDaemonArgs("/tmp/pxcli.sock", daemonOptions{
+		size:           "3x2",
+		scale:          10,
+		headless:       true,
+		from:           "/tmp/hero.png",
+		historyEntries: config.DefaultHistoryEntries,
+		historyBytes:   config.DefaultHistoryBytes,
+	})
+	want := []string{
+		"daemon",
+		"--size", "3x2",
+		"--scale", "10",
+		"--headless=true",
+		"--socket", "/tmp/pxcli.sock",
+		"--from", "/tmp/hero.png",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected args %v, got %v", want, got)
+	}
+}
+
+func TestBuildDaemonArgs_Indexed(t *testing.T) {
+	got := buildDaemonArgs("", daemonOptions{size: "8x8", scale: 10, indexed: true, historyEntries: config.DefaultHistoryEntries, historyBytes: config.DefaultHistoryBytes})
+	want := []string{
+		"daemon",
+		"--size", "8x8",
+		"--scale", "10",
+		"--headless=false",
+		"--indexed",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected args %v, got %v", want, got)
+	}
+}
+
+func TestBuildDaemonArgs_HistoryLimits(t *testing.T) {
+	got := buildDaemonArgs("", daemonOptions{size: "8x8", scale: 10, historyEntries: 0, historyBytes: 4096})
+	want := []string{
+		"daemon",
+		"--size", "8x8",
+		"--scale", "10",
+		"--headless=false",
+		"--history-entries", "0",
+		"--history-bytes", "4096",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected args %v, got %v", want, got)
+	}
+}
+
+func TestBuildDaemonArgs_IdleTimeout(t *testing.T) {
+	got := buildDaemonArgs("", daemonOptions{
+		size:           "8x8",
+		scale:          10,
+		historyEntries: config.DefaultHistoryEntries,
+		historyBytes:   config.DefaultHistoryBytes,
+		idleTimeout:    30 * time.Minute,
+		autosaveDir:    "/tmp/saves",
+	})
+	want := []string{
+		"daemon",
+		"--size", "8x8",
+		"--scale", "10",
+		"--headless=false",
+		"--idle-timeout", "30m0s",
+		"--autosave-dir", "/tmp/saves",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected args %v, got %v", want, got)
+	}
+}
+
+func TestStartCmd_NegativeIdleTimeout(t *testing.T) {
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"start", "--headless", "--idle-timeout=-1m"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "invalid idle timeout") {
+		t.Fatalf("expected idle timeout validation error, got %v", err)
+	}
+}
+
+func TestStartCmd_NegativeHistoryLimit(t *testing.T) {
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"start", "--headless", "--history-entries=-1"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "invalid history entries") {
+		t.Fatalf("expected history limit validation error, got %v", err)
+	}
+}
+
+func TestStartCmd_FromInvalidPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.png")
+	if err := os.WriteFile(path, []byte("not a png"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"start", "--headless", "--from", path})
+
+	err := cmd.Execute()
+	if err == nil || !strings.HasPrefix(err.Error(), "err io ") {
+		t.Fatalf("expected io error, got %v", err)
+	}
+}
+
 func TestStartCmd_InvalidScale(t *testing.T) {
 	cases := []string{"0", "-1"}
 	for _, scale := range cases {
@@ -66,13 +191,6 @@ func TestStartCmd_WindowedUnsupported(t *testing.T) {
 func TestStartCmd_StartsDaemonAndDetectsRunning(t *testing.T) {
 	dir := testutil.TempDir(t)
 	socketPath := filepath.Join(dir, "pxcli.sock")
-	pidPath := filepath.Join(dir, "pxcli.pid")
-
-	restorePID := daemonPIDPath
-	daemonPIDPath = pidPath
-	t.Cleanup(func() {
-		daemonPIDPath = restorePID
-	})
 
 	restoreSpawn := startSpawnDaemon
 	startSpawnDaemon = func(binary string, args []string) (daemonProcess, error) {
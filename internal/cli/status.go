@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"pxcli/internal/command"
+	"pxcli/internal/protocol"
+)
+
+// newStatusCmd creates the status command, which reports a daemon that is not
+// running as running=false instead of failing.
+func newStatusCmd(spec command.Spec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   spec.Name,
+		Short: spec.Short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cli, err := requestClient(cmd, spec.Name)
+			if err != nil {
+				return err
+			}
+			if jsonOutput(cmd) {
+				resp, err := cli.SendJSON(protocol.NewJSONRequest(spec.Name))
+				if daemonNotRunning(err) {
+					printJSONResult(cmd, map[string]bool{"running": false})
+					return nil
+				}
+				return reportJSON(cmd, resp, err)
+			}
+			resp, err := cli.Send(spec.Name)
+			if daemonNotRunning(err) {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), protocol.FormatOK("running=false"))
+				return nil
+			}
+			if err != nil {
+				return formatClientError(err)
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), resp.Raw)
+			return nil
+		},
+	}
+
+	return cmd
+}
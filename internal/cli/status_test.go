@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pxcli/internal/client"
+	"pxcli/internal/protocol"
+)
+
+func TestStatusCmd_ReportsStoppedDaemon(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "text", args: []string{"status"}, want: "ok running=false\n"},
+		{name: "json", args: []string{"--json", "status"}, want: `{"status":"ok","result":{"running":false}}` + "\n"},
+		{name: "no auto-start", args: []string{"--auto-start", "status"}, want: "ok running=false\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubClient{}
+			spawned := stubAutoStart(t, stub)
+
+			out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(out)
+			cmd.SetErr(errOut)
+			cmd.SetArgs(tt.args)
+
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, out.String())
+			}
+			if errOut.Len() != 0 {
+				t.Fatalf("expected nothing on stderr, got %q", errOut.String())
+			}
+			if len(*spawned) != 0 {
+				t.Fatalf("expected no daemon to be spawned, got %q", *spawned)
+			}
+		})
+	}
+}
+
+func TestStatusCmd_PrintsRunningDaemon(t *testing.T) {
+	stub := &stubClient{
+		response:     client.Response{Raw: "ok running=true pid=7 uptime=1"},
+		jsonResponse: protocol.JSONResponse{Status: protocol.StatusOK, Result: []byte(`{"running":true,"pid":7}`)},
+	}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	for args, want := range map[string]string{
+		"status":        "ok running=true pid=7 uptime=1\n",
+		"--json status": `{"status":"ok","result":{"running":true,"pid":7}}` + "\n",
+	} {
+		out := &bytes.Buffer{}
+		cmd := NewRootCmd("dev")
+		cmd.SetOut(out)
+		cmd.SetArgs(strings.Fields(args))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", args, err)
+		}
+		if out.String() != want {
+			t.Fatalf("%s: expected %q, got %q", args, want, out.String())
+		}
+	}
+}
+
+func TestSpecCmd_RuntimeErrorSkipsUsage(t *testing.T) {
+	stub := &stubClient{err: client.Error{Code: "daemon_not_running", Message: "no socket"}}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	errOut := &bytes.Buffer{}
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(errOut)
+	cmd.SetErr(errOut)
+	cmd.SetArgs([]string{"set_pixel", "0", "0", "red"})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "err daemon_not_running") {
+		t.Fatalf("expected daemon_not_running, got %v", err)
+	}
+	if strings.Contains(errOut.String(), "Usage:") {
+		t.Fatalf("expected no usage for a runtime error, got %q", errOut.String())
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"pxcli/internal/client"
+	"pxcli/internal/command"
 )
 
 var (
@@ -19,14 +20,14 @@ var (
 	stopWaitForShutdown = waitForShutdown
 )
 
-// NewStopCmd creates the stop command.
-func NewStopCmd() *cobra.Command {
+// newStopCmd creates the stop command, which also waits for the daemon to exit.
+func newStopCmd(spec command.Spec) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "stop",
-		Short: "Stop the pxcli daemon",
+		Use:   spec.Name,
+		Short: spec.Short,
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			socketPath, err := SocketPath(cmd)
+			socketPath, pidPath, err := daemonPaths(cmd)
 			if err != nil {
 				return err
 			}
@@ -38,9 +39,13 @@ func NewStopCmd() *cobra.Command {
 			if err != nil {
 				return formatClientError(err)
 			}
-			if err := stopWaitForShutdown(socketPath, daemonPIDPath, stopWaitTimeout); err != nil {
+			if err := stopWaitForShutdown(socketPath, pidPath, stopWaitTimeout); err != nil {
 				return err
 			}
+			if jsonOutput(cmd) {
+				printJSONResult(cmd, nil)
+				return nil
+			}
 			_, _ = fmt.Fprintln(cmd.OutOrStdout(), resp.Raw)
 			return nil
 		},
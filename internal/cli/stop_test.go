@@ -18,12 +18,6 @@ func TestStopCmd_StopsDaemonAndRemovesFiles(t *testing.T) {
 	socketPath := filepath.Join(dir, "pxcli.sock")
 	pidPath := filepath.Join(dir, "pxcli.pid")
 
-	restorePID := daemonPIDPath
-	daemonPIDPath = pidPath
-	t.Cleanup(func() {
-		daemonPIDPath = restorePID
-	})
-
 	daemonCmd := NewRootCmd("dev")
 	daemonCmd.SetOut(io.Discard)
 	daemonCmd.SetErr(io.Discard)
@@ -16,13 +16,7 @@ import (
 func TestGetPixelCmd_PrintsColor(t *testing.T) {
 	dir := testutil.TempDir(t)
 	socketPath := filepath.Join(dir, "pxcli.sock")
-	pidPath := filepath.Join(dir, "pxcli.pid")
 
-	restorePID := daemonPIDPath
-	daemonPIDPath = pidPath
-	t.Cleanup(func() {
-		daemonPIDPath = restorePID
-	})
 	t.Cleanup(func() {
 		if _, err := os.Stat(socketPath); err == nil {
 			_, _ = sendRequest(socketPath, "stop\n")
@@ -123,6 +117,126 @@ func TestExportCmd_ResolvesAbsolutePath(t *testing.T) {
 	}
 }
 
+func TestExportCmd_SheetOption(t *testing.T) {
+	stub := &stubClient{response: client.Response{Raw: "ok /tmp/walk.json"}}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"export", "--sheet", "2x4", "/tmp/walk.png"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	if want := "export /tmp/walk.png --sheet=2x4"; len(stub.requests) != 1 || stub.requests[0] != want {
+		t.Fatalf("expected request %q, got %v", want, stub.requests)
+	}
+
+	cmd = NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"export", "--sheet", "0x4", "/tmp/walk.png"})
+	if err := cmd.Execute(); err == nil || !strings.HasPrefix(err.Error(), "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %v", err)
+	}
+}
+
+func TestExportCmd_QuotesPathWithSpaces(t *testing.T) {
+	stub := &stubClient{response: client.Response{Raw: "ok"}}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"export", "/tmp/my art/out file.png"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	if want := `export "/tmp/my art/out file.png"`; len(stub.requests) != 1 || stub.requests[0] != want {
+		t.Fatalf("expected request %q, got %v", want, stub.requests)
+	}
+}
+
+func TestImportCmd_FormatsRequest(t *testing.T) {
+	stub := &stubClient{response: client.Response{Raw: "ok"}}
+	restore := drawNewClient
+	drawNewClient = func(socketPath string) (requestSender, error) {
+		return stub, nil
+	}
+	t.Cleanup(func() {
+		drawNewClient = restore
+	})
+
+	cmd := NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"import", "/tmp/hero.png", "4", "-2"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected import error: %v", err)
+	}
+	if want := "import /tmp/hero.png 4 -2"; len(stub.requests) != 1 || stub.requests[0] != want {
+		t.Fatalf("expected request %q, got %v", want, stub.requests)
+	}
+
+	cmd = NewRootCmd("dev")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"import", "/tmp/hero.png", "4"})
+	if err := cmd.Execute(); err == nil || !strings.HasPrefix(err.Error(), "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %v", err)
+	}
+}
+
+func TestProjectCmds_FormatRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantRequest string
+	}{
+		{name: "save", args: []string{"save", "/tmp/hero.pxp"}, wantRequest: "save /tmp/hero.pxp"},
+		{name: "save_history", args: []string{"save", "--history", "/tmp/hero.pxp"}, wantRequest: "save /tmp/hero.pxp --history"},
+		{name: "open", args: []string{"open", "/tmp/hero.pxp"}, wantRequest: "open /tmp/hero.pxp"},
+		{name: "open_quoted", args: []string{"open", `/tmp/my "hero".pxp`}, wantRequest: `open "/tmp/my \"hero\".pxp"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubClient{response: client.Response{Raw: "ok"}}
+			restore := drawNewClient
+			drawNewClient = func(socketPath string) (requestSender, error) {
+				return stub, nil
+			}
+			t.Cleanup(func() {
+				drawNewClient = restore
+			})
+
+			cmd := NewRootCmd("dev")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(stub.requests) != 1 || stub.requests[0] != tt.wantRequest {
+				t.Fatalf("expected request %q, got %v", tt.wantRequest, stub.requests)
+			}
+		})
+	}
+}
+
 func TestExportCmd_PropagatesIOError(t *testing.T) {
 	stub := &stubClient{err: client.Error{Code: "io", Message: "permission denied"}}
 	restore := drawNewClient
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"pxcli/internal/protocol"
 )
 
 const (
@@ -31,10 +34,12 @@ func (e Error) Error() string {
 	return e.Code + ": " + e.Message
 }
 
-// Response represents a parsed daemon response line.
+// Response represents a parsed daemon response line. Lines holds the
+// per-operation results of a batch request.
 type Response struct {
 	Raw     string
 	Payload string
+	Lines   []string
 }
 
 // Client sends protocol requests to the daemon socket.
@@ -99,38 +104,195 @@ func (c *Client) Send(request string) (Response, error) {
 	if c == nil {
 		return Response{}, Error{Code: "invalid_client", Message: "client is nil"}
 	}
+	trimmed, err := requestLine(request)
+	if err != nil {
+		return Response{}, err
+	}
+	return c.exchange(trimmed, 0)
+}
+
+// Hello performs the protocol handshake, asking the daemon for this client's
+// protocol version. A daemon that is too old, including one that predates the
+// handshake, fails with unsupported_protocol.
+func (c *Client) Hello() (Response, error) {
+	resp, err := c.Send(fmt.Sprintf("hello %d", protocol.Version))
+	var clientErr Error
+	if errors.As(err, &clientErr) && clientErr.Code == "invalid_command" {
+		return resp, Error{Code: "unsupported_protocol", Message: fmt.Sprintf(
+			"daemon predates protocol %d; restart the daemon with this pxcli", protocol.Version)}
+	}
+	return resp, err
+}
+
+// SendBatch sends operation lines as one batch request, executed by the daemon
+// as a single atomic undo step. The returned response holds the batch header,
+// with one result line per operation in Lines; a failed batch returns both the
+// results and a batch_failed error. Options such as "--doc=name" go on the
+// batch header.
+func (c *Client) SendBatch(operations []string, options ...string) (Response, error) {
+	if c == nil {
+		return Response{}, Error{Code: "invalid_client", Message: "client is nil"}
+	}
+	request, err := batchRequest(operations, options)
+	if err != nil {
+		return Response{}, err
+	}
+	return c.exchange(request, len(operations))
+}
+
+// SendJSON sends a request in JSON mode over a fresh connection and returns the
+// decoded response; a failed request also returns its error.
+func (c *Client) SendJSON(request protocol.JSONRequest) (protocol.JSONResponse, error) {
+	if c == nil {
+		return protocol.JSONResponse{}, Error{Code: "invalid_client", Message: "client is nil"}
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return protocol.JSONResponse{}, err
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	if err := c.switchToJSON(conn, reader); err != nil {
+		return protocol.JSONResponse{}, err
+	}
+	return c.roundTripJSON(conn, reader, request)
+}
+
+// switchToJSON negotiates JSON mode on a connection.
+func (c *Client) switchToJSON(conn net.Conn, reader *bufio.Reader) error {
+	resp, err := c.roundTrip(conn, reader, "mode "+protocol.ModeJSON, 0)
+	if err != nil {
+		return err
+	}
+	if resp.Payload != protocol.ModeJSON {
+		return Error{Code: "invalid_response", Message: fmt.Sprintf("unexpected mode response %q", resp.Raw)}
+	}
+	return nil
+}
+
+// roundTripJSON writes a JSON request line and decodes the JSON response line.
+func (c *Client) roundTripJSON(conn net.Conn, reader *bufio.Reader, request protocol.JSONRequest) (protocol.JSONResponse, error) {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return protocol.JSONResponse{}, Error{Code: "invalid_request", Message: err.Error()}
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return protocol.JSONResponse{}, Error{Code: "connection_failed", Message: err.Error()}
+	}
+	if _, err := conn.Write(append(encoded, '\n')); err != nil {
+		return protocol.JSONResponse{}, Error{Code: "io", Message: err.Error()}
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+		return protocol.JSONResponse{}, Error{Code: "connection_failed", Message: err.Error()}
+	}
+	line, err := readLine(reader)
+	if err != nil {
+		return protocol.JSONResponse{}, err
+	}
+	var response protocol.JSONResponse
+	if err := json.Unmarshal([]byte(line), &response); err != nil {
+		return protocol.JSONResponse{}, Error{Code: "invalid_response", Message: fmt.Sprintf("unexpected response %q", line)}
+	}
+	if response.Status != protocol.StatusOK {
+		if response.Error == nil {
+			return response, Error{Code: "error", Message: "unknown error"}
+		}
+		return response, Error{Code: response.Error.Code, Message: response.Error.Message}
+	}
+	return response, nil
+}
+
+// requestLine validates a request for Send. A trailing line ending is dropped,
+// but a request must otherwise fit on one line, and batch and mode requests
+// need the extra framing SendBatch and SendJSON provide.
+func requestLine(request string) (string, error) {
 	trimmed := strings.TrimRight(request, "\r\n")
 	if strings.TrimSpace(trimmed) == "" {
-		return Response{}, Error{Code: "invalid_request", Message: "request is required"}
+		return "", Error{Code: "invalid_request", Message: "request is required"}
 	}
+	if strings.ContainsAny(trimmed, "\r\n") {
+		return "", Error{Code: "invalid_request", Message: "request contains a line break"}
+	}
+	if parsed, err := protocol.ParseLine(trimmed); err == nil {
+		switch parsed.Command {
+		case "batch":
+			return "", Error{Code: "invalid_request", Message: "use SendBatch for batch requests"}
+		case "mode":
+			return "", Error{Code: "invalid_request", Message: "use SendJSON for JSON mode"}
+		}
+	}
+	return trimmed, nil
+}
+
+func batchRequest(operations, options []string) (string, error) {
+	for i, operation := range operations {
+		if strings.ContainsAny(operation, "\r\n") {
+			return "", Error{Code: "invalid_request", Message: fmt.Sprintf("operation %d contains a line break", i+1)}
+		}
+	}
+	return protocol.FormatBatchRequest(operations, options...), nil
+}
 
+// exchange writes a request over a fresh connection and reads its response.
+func (c *Client) exchange(request string, results int) (Response, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return Response{}, err
+	}
+	defer conn.Close()
+	return c.roundTrip(conn, bufio.NewReader(conn), request, results)
+}
+
+func (c *Client) dial() (net.Conn, error) {
 	dialer := net.Dialer{Timeout: c.dialTimeout}
 	conn, err := dialer.Dial("unix", c.socketPath)
 	if err != nil {
-		return Response{}, classifyDialError(err)
+		return nil, classifyDialError(err)
 	}
-	defer conn.Close()
+	return conn, nil
+}
 
+// roundTrip writes a request and reads the response line, followed by up to
+// results batch result lines when the header has them.
+func (c *Client) roundTrip(conn net.Conn, reader *bufio.Reader, request string, results int) (Response, error) {
 	if err := conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
 		return Response{}, Error{Code: "connection_failed", Message: err.Error()}
 	}
-	if _, err := io.WriteString(conn, trimmed+"\n"); err != nil {
+	if _, err := io.WriteString(conn, request+"\n"); err != nil {
 		return Response{}, Error{Code: "io", Message: err.Error()}
 	}
 
 	if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
 		return Response{}, Error{Code: "connection_failed", Message: err.Error()}
 	}
-	reader := bufio.NewReader(conn)
+	line, err := readLine(reader)
+	if err != nil {
+		return Response{}, err
+	}
+	response, respErr := parseResponse(line)
+	if results == 0 || !protocol.HasBatchResults(strings.TrimSpace(line)) {
+		return response, respErr
+	}
+	response.Lines = make([]string, 0, results)
+	for range results {
+		result, err := readLine(reader)
+		if err != nil {
+			return response, err
+		}
+		response.Lines = append(response.Lines, result)
+	}
+	return response, respErr
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
 	line, err := reader.ReadString('\n')
 	if err != nil {
 		if isTimeout(err) {
-			return Response{}, Error{Code: "timeout", Message: "timed out waiting for response"}
+			return "", Error{Code: "timeout", Message: "timed out waiting for response"}
 		}
-		return Response{}, Error{Code: "io", Message: err.Error()}
+		return "", Error{Code: "io", Message: err.Error()}
 	}
-	line = strings.TrimRight(line, "\r\n")
-	return parseResponse(line)
+	return strings.TrimRight(line, "\r\n"), nil
 }
 
 func parseResponse(line string) (Response, error) {
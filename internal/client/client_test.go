@@ -7,10 +7,12 @@ import (
 	"io"
 	"net"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"pxcli/internal/protocol"
 	"pxcli/internal/testutil"
 )
 
@@ -74,6 +76,157 @@ func TestClientSendOK(t *testing.T) {
 	}
 }
 
+func TestClientHello(t *testing.T) {
+	client, err := New(startDaemonServer(t))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	resp, err := client.Hello()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := fmt.Sprintf("protocol=%d ", protocol.Version); !strings.HasPrefix(resp.Payload, want) {
+		t.Fatalf("expected payload starting with %q, got %q", want, resp.Payload)
+	}
+}
+
+func TestClientHelloOlderDaemon(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen on unix socket: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+			return
+		}
+		_, _ = io.WriteString(conn, "err invalid_command unknown command \"hello\"\n")
+	}()
+
+	client, err := New(socketPath, WithReadTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	var clientErr Error
+	if _, err := client.Hello(); !errors.As(err, &clientErr) || clientErr.Code != "unsupported_protocol" {
+		t.Fatalf("expected unsupported_protocol, got %v", err)
+	}
+}
+
+func TestClientSendBatchReadsResultLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		wantRaw   string
+		wantLines []string
+		wantCode  string
+	}{
+		{
+			name:      "success",
+			response:  "ok 2\nok\nok #ff0000ff\n",
+			wantRaw:   "ok 2",
+			wantLines: []string{"ok", "ok #ff0000ff"},
+		},
+		{
+			name:      "rolled back",
+			response:  "err batch_failed line 2 failed; batch rolled back\nok\nerr out_of_bounds pixel (9,9) outside canvas\n",
+			wantRaw:   "err batch_failed line 2 failed; batch rolled back",
+			wantLines: []string{"ok", "err out_of_bounds pixel (9,9) outside canvas"},
+			wantCode:  "batch_failed",
+		},
+		{
+			name:     "rejected",
+			response: "err invalid_args bad count\n",
+			wantRaw:  "err invalid_args bad count",
+			wantCode: "invalid_args",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+			listener, err := net.Listen("unix", socketPath)
+			if err != nil {
+				t.Fatalf("failed to listen on unix socket: %v", err)
+			}
+			t.Cleanup(func() { _ = listener.Close() })
+
+			requests := make(chan []string, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				var lines []string
+				for range 3 {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						break
+					}
+					lines = append(lines, strings.TrimRight(line, "\n"))
+				}
+				requests <- lines
+				_, _ = io.WriteString(conn, tt.response)
+			}()
+
+			client, err := New(socketPath, WithReadTimeout(time.Second))
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			resp, err := client.SendBatch([]string{"set_pixel 0 0 red", "get_pixel 0 0"})
+			if got := <-requests; !reflect.DeepEqual(got, []string{"batch 2", "set_pixel 0 0 red", "get_pixel 0 0"}) {
+				t.Fatalf("unexpected request lines %q", got)
+			}
+			var clientErr Error
+			if tt.wantCode == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantCode != "" && (!errors.As(err, &clientErr) || clientErr.Code != tt.wantCode) {
+				t.Fatalf("expected %s error, got %v", tt.wantCode, err)
+			}
+			if resp.Raw != tt.wantRaw {
+				t.Fatalf("expected raw %q, got %q", tt.wantRaw, resp.Raw)
+			}
+			if !reflect.DeepEqual(resp.Lines, tt.wantLines) {
+				t.Fatalf("expected lines %q, got %q", tt.wantLines, resp.Lines)
+			}
+		})
+	}
+}
+
+func TestClientSendBatchRejectsLineBreaks(t *testing.T) {
+	client, err := New("/tmp/unused.sock")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	_, err = client.SendBatch([]string{"clear\nstop"})
+	var clientErr Error
+	if !errors.As(err, &clientErr) || clientErr.Code != "invalid_request" {
+		t.Fatalf("expected invalid_request, got %v", err)
+	}
+}
+
+func TestClientSendRejectsFramedRequests(t *testing.T) {
+	client, err := New("/tmp/unused.sock")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	for _, request := range []string{"clear\nstop", "clear\rstop", "batch 2", "mode json"} {
+		var clientErr Error
+		if _, err := client.Send(request); !errors.As(err, &clientErr) || clientErr.Code != "invalid_request" {
+			t.Fatalf("%q: expected invalid_request, got %v", request, err)
+		}
+	}
+}
+
 func TestClientSendMissingSocket(t *testing.T) {
 	socketPath := filepath.Join(testutil.TempDir(t), "missing.sock")
 	client, err := New(socketPath, WithDialTimeout(50*time.Millisecond))
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+
+	"pxcli/internal/protocol"
+)
+
+// Session is a long-lived daemon connection that carries many requests, avoiding
+// a reconnect per request. Requests on a session are answered in order; it is
+// safe for concurrent use, though callers then share one stream. The daemon
+// closes connections left idle past its idle timeout, after which the session
+// reports errors and a new one must be opened.
+type Session struct {
+	client *Client
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	// json is set once the session has switched to JSON mode.
+	json bool
+	// err is the transport failure that ended the session, if any.
+	err error
+}
+
+// Open connects a new session to the daemon.
+func (c *Client) Open() (*Session, error) {
+	if c == nil {
+		return nil, Error{Code: "invalid_client", Message: "client is nil"}
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{client: c, conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Send sends a single request line over the session and returns the parsed response.
+func (s *Session) Send(request string) (Response, error) {
+	trimmed, err := requestLine(request)
+	if err != nil {
+		return Response{}, err
+	}
+	return s.exchange(trimmed, 0)
+}
+
+// SendBatch sends operation lines as one atomic batch request over the session,
+// with the same results as Client.SendBatch.
+func (s *Session) SendBatch(operations []string, options ...string) (Response, error) {
+	request, err := batchRequest(operations, options)
+	if err != nil {
+		return Response{}, err
+	}
+	return s.exchange(request, len(operations))
+}
+
+// SendJSON sends a request in JSON mode over the session, switching the
+// session to JSON mode on first use. Once switched, Send and SendBatch are
+// rejected.
+func (s *Session) SendJSON(request protocol.JSONRequest) (protocol.JSONResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return protocol.JSONResponse{}, s.err
+	}
+	if !s.json {
+		if err := s.client.switchToJSON(s.conn, s.reader); err != nil {
+			s.fail(err)
+			return protocol.JSONResponse{}, err
+		}
+		s.json = true
+	}
+	response, err := s.client.roundTripJSON(s.conn, s.reader, request)
+	s.fail(err)
+	return response, err
+}
+
+// Close closes the session connection.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = Error{Code: "session_closed", Message: "session is closed"}
+	}
+	return s.conn.Close()
+}
+
+func (s *Session) exchange(request string, results int) (Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return Response{}, s.err
+	}
+	if s.json {
+		return Response{}, Error{Code: "invalid_request", Message: "session is in JSON mode"}
+	}
+	response, err := s.client.roundTrip(s.conn, s.reader, request, results)
+	s.fail(err)
+	return response, err
+}
+
+// fail ends the session after a transport error, since the stream may be out
+// of step with the daemon; callers must hold the lock.
+func (s *Session) fail(err error) {
+	var clientErr Error
+	if err != nil && (!errors.As(err, &clientErr) || isTransportError(clientErr.Code)) {
+		s.err = err
+		_ = s.conn.Close()
+	}
+}
+
+func isTransportError(code string) bool {
+	switch code {
+	case "io", "timeout", "connection_failed", "invalid_response":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"pxcli/internal/canvas"
+	"pxcli/internal/daemon"
+	"pxcli/internal/history"
+	"pxcli/internal/protocol"
+	"pxcli/internal/testutil"
+)
+
+func TestSessionSendsManyRequestsOnOneConnection(t *testing.T) {
+	socketPath := startDaemonServer(t)
+	client, err := New(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	session, err := client.Open()
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	defer session.Close()
+
+	for x := range 8 {
+		if _, err := session.Send(fmt.Sprintf("set_pixel %d 0 red", x)); err != nil {
+			t.Fatalf("unexpected set_pixel error: %v", err)
+		}
+	}
+	resp, err := session.Send("get_pixel 7 0")
+	if err != nil {
+		t.Fatalf("unexpected get_pixel error: %v", err)
+	}
+	if resp.Payload != "#ff0000ff" {
+		t.Fatalf("expected red pixel, got %q", resp.Payload)
+	}
+
+	resp, err = session.SendBatch([]string{"set_pixel 0 1 blue", "get_pixel 0 1"})
+	if err != nil {
+		t.Fatalf("unexpected batch error: %v", err)
+	}
+	if want := []string{"ok", "ok #0000ffff"}; !reflect.DeepEqual(resp.Lines, want) {
+		t.Fatalf("expected batch results %q, got %q", want, resp.Lines)
+	}
+
+	// A daemon error leaves the session usable.
+	var clientErr Error
+	if _, err := session.Send("set_pixel 99 0 red"); !errors.As(err, &clientErr) || clientErr.Code != "out_of_bounds" {
+		t.Fatalf("expected out_of_bounds, got %v", err)
+	}
+	if _, err := session.Send("get_pixel 0 0"); err != nil {
+		t.Fatalf("expected the session to survive a daemon error, got %v", err)
+	}
+}
+
+func TestSessionFailsAfterConnectionCloses(t *testing.T) {
+	socketPath := startDaemonServer(t, daemon.WithIdleTimeout(20*time.Millisecond))
+	client, err := New(socketPath, WithReadTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	session, err := client.Open()
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	defer session.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	_, first := session.Send("get_pixel 0 0")
+	var clientErr Error
+	if !errors.As(first, &clientErr) || clientErr.Code != "io" {
+		t.Fatalf("expected io error after the idle timeout, got %v", first)
+	}
+	if _, err := session.Send("get_pixel 0 0"); !errors.Is(err, first) {
+		t.Fatalf("expected the session to keep reporting %v, got %v", first, err)
+	}
+}
+
+func TestSessionClose(t *testing.T) {
+	socketPath := startDaemonServer(t)
+	client, err := New(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	session, err := client.Open()
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	var clientErr Error
+	if _, err := session.Send("get_pixel 0 0"); !errors.As(err, &clientErr) || clientErr.Code != "session_closed" {
+		t.Fatalf("expected session_closed, got %v", err)
+	}
+}
+
+func TestSessionSendRejectsFramedRequests(t *testing.T) {
+	socketPath := startDaemonServer(t)
+	client, err := New(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	session, err := client.Open()
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	defer session.Close()
+
+	for _, request := range []string{
+		"set_pixel 0 0 red\nclear",
+		"set_pixel 0 0 red\rclear",
+		"batch 1",
+		"batch 1\nclear",
+		"mode json",
+	} {
+		var clientErr Error
+		if _, err := session.Send(request); !errors.As(err, &clientErr) || clientErr.Code != "invalid_request" {
+			t.Fatalf("%q: expected invalid_request, got %v", request, err)
+		}
+	}
+	resp, err := session.Send("get_pixel 0 0\n")
+	if err != nil {
+		t.Fatalf("expected the session to stay usable, got %v", err)
+	}
+	if resp.Payload != "#00000000" {
+		t.Fatalf("expected an untouched pixel, got %q", resp.Payload)
+	}
+}
+
+func TestSendJSON(t *testing.T) {
+	socketPath := startDaemonServer(t)
+	client, err := New(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	request := protocol.NewJSONRequest("set_pixel", "1", "1", "red")
+	request.ID = json.RawMessage("1")
+	resp, err := client.SendJSON(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.ID) != "1" || resp.Status != protocol.StatusOK {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	session, err := client.Open()
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	defer session.Close()
+	resp, err = session.SendJSON(protocol.NewJSONRequest("get_pixel", "1", "1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Result) != `"#ff0000ff"` {
+		t.Fatalf("expected red result, got %s", resp.Result)
+	}
+
+	resp, err = session.SendJSON(protocol.NewJSONRequest("get_pixel", "9", "9"))
+	var clientErr Error
+	if !errors.As(err, &clientErr) || clientErr.Code != "out_of_bounds" {
+		t.Fatalf("expected out_of_bounds, got %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != "out_of_bounds" {
+		t.Fatalf("expected the error in the response, got %+v", resp)
+	}
+	if _, err := session.Send("get_pixel 0 0"); !errors.As(err, &clientErr) || clientErr.Code != "invalid_request" {
+		t.Fatalf("expected line requests to be rejected in JSON mode, got %v", err)
+	}
+}
+
+// BenchmarkSendThroughput compares streaming set_pixel requests over one session
+// with dialing a new connection for every request.
+func BenchmarkSendThroughput(b *testing.B) {
+	socketPath := startDaemonServer(b)
+	client, err := New(socketPath)
+	if err != nil {
+		b.Fatalf("failed to create client: %v", err)
+	}
+	requests := make([]string, 64)
+	for i := range requests {
+		requests[i] = fmt.Sprintf("set_pixel %d %d red", i%8, i/8)
+	}
+
+	b.Run("connection per request", func(b *testing.B) {
+		for i := 0; b.Loop(); i++ {
+			if _, err := client.Send(requests[i%len(requests)]); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+	b.Run("session", func(b *testing.B) {
+		session, err := client.Open()
+		if err != nil {
+			b.Fatalf("failed to open session: %v", err)
+		}
+		defer session.Close()
+		for i := 0; b.Loop(); i++ {
+			if _, err := session.Send(requests[i%len(requests)]); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func startDaemonServer(tb testing.TB, opts ...daemon.ServerOption) string {
+	tb.Helper()
+	grid, err := canvas.New(8, 8)
+	if err != nil {
+		tb.Fatalf("failed to create canvas: %v", err)
+	}
+	socketPath := filepath.Join(testutil.TempDir(tb), "pxcli.sock")
+	server, err := daemon.NewServer(socketPath, daemon.NewHandler(history.New(grid), nil), opts...)
+	if err != nil {
+		tb.Fatalf("failed to start server: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve()
+	}()
+	tb.Cleanup(func() {
+		_ = server.Close()
+		if err := <-done; err != nil {
+			tb.Errorf("unexpected server error: %v", err)
+		}
+	})
+	return socketPath
+}
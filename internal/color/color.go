@@ -3,25 +3,10 @@ package color
 import (
 	"fmt"
 	"image/color"
+	"sort"
 	"strings"
 )
 
-var namedColors = map[string]color.RGBA{
-	"black":       {R: 0, G: 0, B: 0, A: 255},
-	"white":       {R: 255, G: 255, B: 255, A: 255},
-	"red":         {R: 255, G: 0, B: 0, A: 255},
-	"green":       {R: 0, G: 128, B: 0, A: 255},
-	"blue":        {R: 0, G: 0, B: 255, A: 255},
-	"yellow":      {R: 255, G: 255, B: 0, A: 255},
-	"orange":      {R: 255, G: 165, B: 0, A: 255},
-	"purple":      {R: 128, G: 0, B: 128, A: 255},
-	"cyan":        {R: 0, G: 255, B: 255, A: 255},
-	"magenta":     {R: 255, G: 0, B: 255, A: 255},
-	"gray":        {R: 128, G: 128, B: 128, A: 255},
-	"grey":        {R: 128, G: 128, B: 128, A: 255},
-	"transparent": {R: 0, G: 0, B: 0, A: 0},
-}
-
 // Error represents a color parsing error with a code and message.
 type Error struct {
 	Code    string
@@ -36,20 +21,54 @@ func (e Error) Error() string {
 }
 
 // Parse converts a color string into RGBA.
-// Supported formats: #rgb, #rrggbb, #rrggbbaa, and named colors.
+// Supported formats: #rgb, #rrggbb, #rrggbbaa, CSS named colors, the rgb(),
+// rgba(), hsl(), hsla() and hsv() functions, and any of these followed by
+// ":"-separated modifiers such as "red:darken(20%)".
 func Parse(input string) (color.RGBA, error) {
+	return ParseWith(input, nil)
+}
+
+// ParseWith converts a color string into RGBA like Parse, and also resolves
+// "@<index>" and "@<name>" references to the palette's swatches.
+func ParseWith(input string, palette Palette) (color.RGBA, error) {
 	trimmed := strings.TrimSpace(input)
 	if trimmed == "" {
 		return color.RGBA{}, Error{Code: "invalid_color", Message: "color is required"}
 	}
+	base, modifiers := splitModifiers(trimmed, palette)
+	value, err := parseBase(base, palette)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	for _, modifier := range modifiers {
+		if value, err = applyModifier(value, modifier, palette); err != nil {
+			return color.RGBA{}, err
+		}
+	}
+	return value, nil
+}
+
+// parseBase parses a color without modifiers.
+func parseBase(input string, palette Palette) (color.RGBA, error) {
+	trimmed := strings.TrimSpace(input)
+	if ref, ok := strings.CutPrefix(trimmed, "@"); ok {
+		index, err := palette.Resolve(ref)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		return palette[index].Color, nil
+	}
 
 	lower := strings.ToLower(trimmed)
 	if named, ok := namedColors[lower]; ok {
 		return named, nil
 	}
+	if name, args, ok := splitCall(lower); ok {
+		return parseFunction(trimmed, name, args)
+	}
 
 	if !strings.HasPrefix(lower, "#") {
-		return color.RGBA{}, Error{Code: "invalid_color", Message: "expected hex color or named color"}
+		return color.RGBA{}, Error{Code: "invalid_color", Message: "expected hex color, named color or color function"}
 	}
 
 	hex := lower[1:]
@@ -105,6 +124,25 @@ func Parse(input string) (color.RGBA, error) {
 	}
 }
 
+// Formats lists the color syntaxes accepted by ParseWith.
+func Formats() []string {
+	return []string{
+		"#rgb", "#rrggbb", "#rrggbbaa", "name", "@index", "@swatch",
+		"rgb(r,g,b)", "rgba(r,g,b,a)", "hsl(h,s%,l%)", "hsla(h,s%,l%,a)", "hsv(h,s%,v%)",
+		"color:modifier(...)",
+	}
+}
+
+// Names returns the supported color names in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(namedColors))
+	for name := range namedColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Format returns the canonical #rrggbbaa lowercase format for a color.
 func Format(c color.RGBA) string {
 	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
@@ -3,6 +3,9 @@ package color
 import (
 	"errors"
 	"image/color"
+	"math"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -48,3 +51,150 @@ func TestFormatTransparent(t *testing.T) {
 		t.Fatalf("expected #00000000, got %q", got)
 	}
 }
+
+func TestNamesSorted(t *testing.T) {
+	names := Names()
+	if len(names) != len(namedColors) || !sort.StringsAreSorted(names) {
+		t.Fatalf("expected %d sorted names, got %q", len(namedColors), names)
+	}
+	for _, name := range names {
+		if _, err := Parse(name); err != nil {
+			t.Fatalf("listed name %q does not parse: %v", name, err)
+		}
+	}
+}
+
+func TestParseCSSNamedColors(t *testing.T) {
+	// The 148 CSS named colors, plus transparent.
+	if len(namedColors) != 149 {
+		t.Fatalf("expected 149 names, got %d", len(namedColors))
+	}
+	for input, want := range map[string]string{
+		"green":         "#008000ff",
+		"Lime":          "#00ff00ff",
+		"rebeccapurple": "#663399ff",
+		"transparent":   "#00000000",
+	} {
+		got, err := Parse(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if Format(got) != want {
+			t.Fatalf("%s: expected %s, got %s", input, want, Format(got))
+		}
+	}
+}
+
+func TestParseColorFunctions(t *testing.T) {
+	for input, want := range map[string]string{
+		"rgb(255,128,0)":           "#ff8000ff",
+		"RGB(255, 128, 0)":         "#ff8000ff",
+		"rgb(100% 0% 50%)":         "#ff0080ff",
+		"rgb(255 0 0 / 50%)":       "#ff000080",
+		"rgba(0,0,255,0.5)":        "#0000ff80",
+		"hsl(120,100%,25%)":        "#008000ff",
+		"hsl(-120deg 100% 50%)":    "#0000ffff",
+		"hsla(0, 100%, 50%, 0.25)": "#ff000040",
+		"hsv(60,100%,100%)":        "#ffff00ff",
+		"hsv(0 0% 50% / 1)":        "#808080ff",
+	} {
+		got, err := Parse(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if Format(got) != want {
+			t.Fatalf("%s: expected %s, got %s", input, want, Format(got))
+		}
+	}
+}
+
+func TestParseColorModifiers(t *testing.T) {
+	palette := Palette{{Name: "ink", Color: color.RGBA{A: 255}}, {Name: "skin:light", Color: color.RGBA{R: 230, G: 180, B: 140, A: 255}}}
+	for input, want := range map[string]string{
+		"red:darken(20%)":              "#990000ff",
+		"black:lighten(50)":            "#808080ff",
+		"red:desaturate(100%)":         "#808080ff",
+		"#808080:saturate(100%)":       "#ff0101ff",
+		"#336699:mix(white,30%)":       "#7094b8ff",
+		"white:mix(black)":             "#808080ff",
+		"white:mix(rgb(0,0,0),25%)":    "#bfbfbfff",
+		"@1:alpha(50%)":                "#e6b48c80",
+		"@skin:light":                  "#e6b48cff",
+		"@skin:light:alpha(0)":         "#e6b48c00",
+		"@ink:mix(@skin:light,100%)":   "#e6b48cff",
+		"red:darken(20%):alpha(0.5)":   "#99000080",
+		"#ff000080:darken(20%)":        "#99000080",
+		"red:mix(blue:darken(50%),0%)": "#ff0000ff",
+	} {
+		got, err := ParseWith(input, palette)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if Format(got) != want {
+			t.Fatalf("%s: expected %s, got %s", input, want, Format(got))
+		}
+	}
+}
+
+func TestParseColorFunctionErrors(t *testing.T) {
+	for _, input := range []string{
+		"rgb(256,0,0)",
+		"rgb(1,2)",
+		"rgba(0,0,0,2)",
+		"rgb(0 0 0 / 1 2)",
+		"hsl(0,120%,50%)",
+		"hsl(red,50%,50%)",
+		"cmyk(0,0,0)",
+		"red:shade(10%)",
+		"red:darken(x)",
+		"red:darken",
+		"red:mix(nope)",
+		"red:alpha(150%)",
+		"rgb(nan,0,0)",
+		"hsl(0,nan%,50%)",
+		"red:alpha(nan)",
+		"red:darken(nan%)",
+		"#fff:mix(black,nan%)",
+		"rgb(inf,0,0)",
+		"red:lighten(-inf%)",
+	} {
+		var cerr Error
+		if _, err := Parse(input); !errors.As(err, &cerr) || cerr.Code != "invalid_color" {
+			t.Fatalf("%s: expected invalid_color, got %v", input, err)
+		}
+	}
+}
+
+func TestRampShiftsHues(t *testing.T) {
+	base := color.RGBA{R: 200, G: 60, B: 40, A: 255}
+	var got []string
+	for _, c := range Ramp(base, RampOptions{Steps: 5, HueShift: 15}) {
+		got = append(got, Format(c))
+	}
+	want := []string{"#28080cff", "#781818ff", "#c83c28ff", "#e49378ff", "#f6e0d2ff"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// Shadows turn from red toward blue and highlights toward yellow.
+	baseHue, _, _ := rgbToHSL(base)
+	if h, _, _ := rgbToHSL(Ramp(base, RampOptions{Steps: 3, HueShift: 20})[0]); math.Abs(h-(baseHue-20+360)) > 1 {
+		t.Fatalf("expected the shadow hue to turn 20 degrees toward blue, got %.1f from %.1f", h, baseHue)
+	}
+	if h, _, _ := rgbToHSL(Ramp(base, RampOptions{Steps: 3, HueShift: 20})[2]); math.Abs(h-(baseHue+20)) > 1 {
+		t.Fatalf("expected the highlight hue to turn 20 degrees toward yellow, got %.1f from %.1f", h, baseHue)
+	}
+
+	_, flat, _ := rgbToHSL(Ramp(base, RampOptions{Steps: 3})[0])
+	_, curved, _ := rgbToHSL(Ramp(base, RampOptions{Steps: 3, SatCurve: 30})[0])
+	if math.Abs(flat-curved-0.3) > 0.02 {
+		t.Fatalf("expected the sat curve to take 30 points from the ends, got %.2f and %.2f", flat, curved)
+	}
+
+	translucent := color.RGBA{R: 255, A: 128}
+	for _, c := range Ramp(translucent, RampOptions{Steps: 4}) {
+		if c.A != 128 {
+			t.Fatalf("expected the ramp to keep alpha, got %v", c)
+		}
+	}
+}
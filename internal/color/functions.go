@@ -0,0 +1,216 @@
+package color
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// splitCall splits "name(args)" into its lowercased name and its arguments.
+func splitCall(input string) (name, args string, ok bool) {
+	open := strings.IndexByte(input, '(')
+	if open <= 0 || !strings.HasSuffix(input, ")") {
+		return "", "", false
+	}
+	name = strings.ToLower(strings.TrimSpace(input[:open]))
+	if strings.ContainsFunc(name, func(r rune) bool { return r < 'a' || r > 'z' }) {
+		return "", "", false
+	}
+	return name, input[open+1 : len(input)-1], true
+}
+
+// parseFunction parses the CSS functional notations rgb(), rgba(), hsl() and
+// hsla(), and an hsv() form. Arguments are separated by commas or spaces, and
+// the alpha may be a fourth argument or follow a "/", as in "rgb(0 0 0 / 50%)".
+func parseFunction(input, name, args string) (color.RGBA, error) {
+	invalid := func(format string, a ...any) (color.RGBA, error) {
+		return color.RGBA{}, Error{Code: "invalid_color", Message: fmt.Sprintf("invalid color %q: ", input) + fmt.Sprintf(format, a...)}
+	}
+	values, alpha, err := functionArgs(args)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	if len(values) != 3 {
+		return invalid("%s() takes 3 values and an optional alpha", name)
+	}
+
+	var out color.RGBA
+	switch name {
+	case "rgb", "rgba":
+		channels := [3]uint8{}
+		for i, value := range values {
+			if channels[i], err = parseChannel(value); err != nil {
+				return invalid("%v", err)
+			}
+		}
+		out = color.RGBA{R: channels[0], G: channels[1], B: channels[2]}
+	case "hsl", "hsla", "hsv":
+		hue, err := parseHue(values[0])
+		if err != nil {
+			return invalid("%v", err)
+		}
+		s, err := parsePercent(values[1])
+		if err != nil {
+			return invalid("%v", err)
+		}
+		lv, err := parsePercent(values[2])
+		if err != nil {
+			return invalid("%v", err)
+		}
+		if name == "hsv" {
+			out = hsvToRGB(hue, s, lv)
+		} else {
+			out = hslToRGB(hue, s, lv)
+		}
+	default:
+		return invalid("unknown color function %s()", name)
+	}
+
+	out.A = 255
+	if alpha != "" {
+		if out.A, err = parseAlpha(alpha); err != nil {
+			return invalid("%v", err)
+		}
+	}
+	return out, nil
+}
+
+// functionArgs splits function arguments into values and an optional alpha.
+func functionArgs(args string) (values []string, alpha string, err error) {
+	fields := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	}
+	main, slashed, hasSlash := strings.Cut(args, "/")
+	values = fields(main)
+	if hasSlash {
+		after := fields(slashed)
+		if len(after) != 1 {
+			return nil, "", fmt.Errorf("expected one alpha value after /")
+		}
+		return values, after[0], nil
+	}
+	if len(values) == 4 {
+		return values[:3], values[3], nil
+	}
+	return values, "", nil
+}
+
+// parseChannel parses an RGB channel, 0-255 or 0%-100%.
+func parseChannel(value string) (uint8, error) {
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := parseNumber(percent, 0, 100)
+		if err != nil {
+			return 0, fmt.Errorf("channel %q must be 0-255 or 0%%-100%%", value)
+		}
+		return uint8(math.Round(p / 100 * 255)), nil
+	}
+	n, err := parseNumber(value, 0, 255)
+	if err != nil {
+		return 0, fmt.Errorf("channel %q must be 0-255 or 0%%-100%%", value)
+	}
+	return uint8(math.Round(n)), nil
+}
+
+// parseAlpha parses an alpha, 0-1 or 0%-100%.
+func parseAlpha(value string) (uint8, error) {
+	max := 1.0
+	trimmed, percent := strings.CutSuffix(value, "%")
+	if percent {
+		max = 100
+	}
+	a, err := parseNumber(trimmed, 0, max)
+	if err != nil {
+		return 0, fmt.Errorf("alpha %q must be 0-1 or 0%%-100%%", value)
+	}
+	return uint8(math.Round(a / max * 255)), nil
+}
+
+// parseHue parses a hue in degrees, with an optional "deg" unit, wrapped to
+// [0, 360).
+func parseHue(value string) (float64, error) {
+	h, err := strconv.ParseFloat(strings.TrimSuffix(value, "deg"), 64)
+	if err != nil || math.IsInf(h, 0) || math.IsNaN(h) {
+		return 0, fmt.Errorf("hue %q must be a number of degrees", value)
+	}
+	return math.Mod(math.Mod(h, 360)+360, 360), nil
+}
+
+// parsePercent parses a 0%-100% amount, the "%" being optional, as a fraction.
+func parsePercent(value string) (float64, error) {
+	p, err := parseNumber(strings.TrimSuffix(value, "%"), 0, 100)
+	if err != nil {
+		return 0, fmt.Errorf("%q must be a percentage from 0%% to 100%%", value)
+	}
+	return p / 100, nil
+}
+
+func parseNumber(value string, min, max float64) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < min || n > max {
+		return 0, fmt.Errorf("%q out of range", value)
+	}
+	return n, nil
+}
+
+// hslToRGB converts a hue in degrees and saturation and lightness fractions
+// to an opaque color.
+func hslToRGB(h, s, l float64) color.RGBA {
+	chroma := (1 - math.Abs(2*l-1)) * s
+	return hueToRGB(h, chroma, l-chroma/2)
+}
+
+// hsvToRGB converts a hue in degrees and saturation and value fractions to
+// an opaque color.
+func hsvToRGB(h, s, v float64) color.RGBA {
+	chroma := v * s
+	return hueToRGB(h, chroma, v-chroma)
+}
+
+func hueToRGB(h, chroma, m float64) color.RGBA {
+	x := chroma * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = chroma, x, 0
+	case h < 120:
+		r, g, b = x, chroma, 0
+	case h < 180:
+		r, g, b = 0, chroma, x
+	case h < 240:
+		r, g, b = 0, x, chroma
+	case h < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	return color.RGBA{R: unitByte(r + m), G: unitByte(g + m), B: unitByte(b + m), A: 255}
+}
+
+// rgbToHSL returns a color's hue in degrees and its saturation and lightness
+// as fractions.
+func rgbToHSL(c color.RGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := max(r, g, b), min(r, g, b)
+	l = (hi + lo) / 2
+	chroma := hi - lo
+	if chroma == 0 {
+		return 0, 0, l
+	}
+	s = chroma / (1 - math.Abs(2*l-1))
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/chroma+6, 6)
+	case g:
+		h = (b-r)/chroma + 2
+	default:
+		h = (r-g)/chroma + 4
+	}
+	return h * 60, s, l
+}
+
+func unitByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
@@ -0,0 +1,81 @@
+package color
+
+import (
+	"image/color"
+	"math"
+)
+
+// Lab is a color in CIELAB space under the D65 white point. L runs from 0 to
+// 100; A and B are roughly -128 to 127.
+type Lab struct {
+	L, A, B float64
+}
+
+// D65 reference white in XYZ.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// ToLab converts an sRGB color to CIELAB, ignoring alpha.
+func ToLab(c color.RGBA) Lab {
+	r, g, b := linearize(c.R), linearize(c.G), linearize(c.B)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / whiteX
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / whiteY
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / whiteZ
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// FromLab converts a CIELAB color to opaque sRGB, clamping colors outside the
+// sRGB gamut.
+func FromLab(lab Lab) color.RGBA {
+	fy := (lab.L + 16) / 116
+	fx := fy + lab.A/500
+	fz := fy - lab.B/200
+	x, y, z := labFInv(fx)*whiteX, labFInv(fy)*whiteY, labFInv(fz)*whiteZ
+	r := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b := 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return color.RGBA{R: delinearize(r), G: delinearize(g), B: delinearize(b), A: 255}
+}
+
+// Distance is the CIE76 color difference between two colors, the Euclidean
+// distance in CIELAB. A difference around 2.3 is just noticeable.
+func (l Lab) Distance(other Lab) float64 {
+	return math.Sqrt((l.L-other.L)*(l.L-other.L) + (l.A-other.A)*(l.A-other.A) + (l.B-other.B)*(l.B-other.B))
+}
+
+func linearize(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func delinearize(c float64) uint8 {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Max(0, math.Min(1, c)) * 255))
+}
+
+const labEpsilon = 216.0 / 24389.0
+
+func labF(t float64) float64 {
+	if t > labEpsilon {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27.0*t + 16) / 116
+}
+
+func labFInv(t float64) float64 {
+	if cube := t * t * t; cube > labEpsilon {
+		return cube
+	}
+	return (116*t - 16) * 27.0 / 24389.0
+}
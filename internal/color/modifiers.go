@@ -0,0 +1,121 @@
+package color
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// splitModifiers splits "base:modifier(...):modifier(...)" at the colons
+// outside parentheses. A swatch reference keeps the longest run of parts that
+// names a swatch, so swatch names may contain colons.
+func splitModifiers(input string, palette Palette) (string, []string) {
+	parts := splitTopLevel(input)
+	if strings.HasPrefix(input, "@") {
+		for n := len(parts); n > 1; n-- {
+			if _, err := palette.Resolve(strings.Join(parts[:n], ":")[1:]); err == nil {
+				return strings.Join(parts[:n], ":"), parts[n:]
+			}
+		}
+	}
+	return parts[0], parts[1:]
+}
+
+// splitTopLevel splits input at the colons outside parentheses.
+func splitTopLevel(input string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range input {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == ':' && depth == 0:
+			parts = append(parts, input[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, input[start:])
+}
+
+// applyModifier applies one modifier to c:
+//
+//	darken(p) lighten(p)       move HSL lightness down or up by p points
+//	desaturate(p) saturate(p)  move HSL saturation down or up by p points
+//	mix(color[,p])             blend in p of color, 50% by default
+//	alpha(a)                   set the alpha to a, as 0-1 or 0%-100%
+func applyModifier(c color.RGBA, modifier string, palette Palette) (color.RGBA, error) {
+	invalid := func(format string, a ...any) (color.RGBA, error) {
+		return color.RGBA{}, Error{Code: "invalid_color", Message: fmt.Sprintf("invalid modifier %q: ", modifier) + fmt.Sprintf(format, a...)}
+	}
+	name, args, ok := splitCall(strings.TrimSpace(modifier))
+	if !ok {
+		return invalid("expected name(args)")
+	}
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case "darken", "lighten", "desaturate", "saturate":
+		amount, err := parsePercent(args)
+		if err != nil {
+			return invalid("%v", err)
+		}
+		if name == "darken" || name == "desaturate" {
+			amount = -amount
+		}
+		h, s, l := rgbToHSL(c)
+		if name == "darken" || name == "lighten" {
+			l = math.Max(0, math.Min(1, l+amount))
+		} else {
+			s = math.Max(0, math.Min(1, s+amount))
+		}
+		out := hslToRGB(h, s, l)
+		out.A = c.A
+		return out, nil
+	case "mix":
+		other, weight := args, 0.5
+		if comma := lastTopLevelComma(args); comma >= 0 {
+			var err error
+			if weight, err = parsePercent(strings.TrimSpace(args[comma+1:])); err != nil {
+				return invalid("%v", err)
+			}
+			other = args[:comma]
+		}
+		blend, err := ParseWith(other, palette)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		mix := func(a, b uint8) uint8 {
+			return uint8(math.Round(float64(a)*(1-weight) + float64(b)*weight))
+		}
+		return color.RGBA{R: mix(c.R, blend.R), G: mix(c.G, blend.G), B: mix(c.B, blend.B), A: mix(c.A, blend.A)}, nil
+	case "alpha":
+		a, err := parseAlpha(args)
+		if err != nil {
+			return invalid("%v", err)
+		}
+		c.A = a
+		return c, nil
+	default:
+		return invalid("unknown modifier %s(); use darken, lighten, saturate, desaturate, mix or alpha", name)
+	}
+}
+
+// lastTopLevelComma returns the index of the last comma outside parentheses,
+// or -1.
+func lastTopLevelComma(s string) int {
+	depth, last := 0, -1
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == ',' && depth == 0:
+			last = i
+		}
+	}
+	return last
+}
@@ -0,0 +1,118 @@
+package color
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Swatch is a palette entry. Name may be empty.
+type Swatch struct {
+	Name  string
+	Color color.RGBA
+}
+
+// Palette is an ordered list of swatches. A swatch is referenced by name or,
+// failing that, by its index from 0; in a color argument the reference
+// follows an "@", as in "@3" or "@skin-dark".
+type Palette []Swatch
+
+// Resolve returns the index of the swatch a reference names.
+func (p Palette) Resolve(ref string) (int, error) {
+	if index := p.find(ref); index >= 0 {
+		return index, nil
+	}
+	if index, err := strconv.Atoi(ref); err == nil && index >= 0 && index < len(p) {
+		return index, nil
+	}
+	return 0, Error{Code: "invalid_swatch", Message: fmt.Sprintf("no palette entry %q", ref)}
+}
+
+// Add appends a swatch and returns its index. A non-empty name must be
+// usable as a reference: unique, not a number, and free of surrounding
+// whitespace and control characters.
+func (p *Palette) Add(name string, c color.RGBA) (int, error) {
+	if name != "" {
+		if err := p.checkName(name); err != nil {
+			return 0, err
+		}
+	}
+	*p = append(*p, Swatch{Name: name, Color: c})
+	return len(*p) - 1, nil
+}
+
+// Remove deletes the swatch a reference names; later swatches move down one
+// index.
+func (p *Palette) Remove(ref string) error {
+	index, err := p.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	*p = append((*p)[:index], (*p)[index+1:]...)
+	return nil
+}
+
+// Index returns the index of the first swatch with exactly color c, or -1.
+func (p Palette) Index(c color.RGBA) int {
+	for i, swatch := range p {
+		if swatch.Color == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// Nearest returns the index of the swatch perceptually closest to c, by
+// distance in CIELAB, or -1 if the palette is empty. Alpha is ignored, and
+// ties go to the earlier swatch.
+func (p Palette) Nearest(c color.RGBA) int {
+	target := ToLab(c)
+	best, bestDistance := -1, 0.0
+	for i, swatch := range p {
+		if distance := ToLab(swatch.Color).Distance(target); best < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// Sanitize drops names that cannot serve as references, such as duplicates
+// and numbers read from a swatch file, keeping the colors.
+func (p Palette) Sanitize() Palette {
+	out := make(Palette, 0, len(p))
+	for _, swatch := range p {
+		name := strings.TrimSpace(swatch.Name)
+		if out.checkName(name) != nil {
+			name = ""
+		}
+		out = append(out, Swatch{Name: name, Color: swatch.Color})
+	}
+	return out
+}
+
+func (p Palette) find(name string) int {
+	for i, swatch := range p {
+		if swatch.Name != "" && swatch.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p Palette) checkName(name string) error {
+	if strings.TrimSpace(name) != name || name == "" {
+		return Error{Code: "invalid_args", Message: "swatch name must be non-empty with no leading or trailing whitespace"}
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return Error{Code: "invalid_args", Message: "swatch name must not contain control characters"}
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return Error{Code: "invalid_args", Message: "swatch name must not be a number"}
+	}
+	if p.find(name) >= 0 {
+		return Error{Code: "invalid_args", Message: fmt.Sprintf("swatch %q already exists", name)}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package color
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+)
+
+func TestParseWithPaletteReferences(t *testing.T) {
+	skin := color.RGBA{R: 200, G: 120, B: 90, A: 255}
+	palette := Palette{{Color: color.RGBA{A: 255}}, {Name: "skin-dark", Color: skin}, {Name: "deep blue", Color: color.RGBA{B: 80, A: 255}}}
+
+	for _, tt := range []struct {
+		input string
+		want  color.RGBA
+	}{
+		{input: "@1", want: skin},
+		{input: "@skin-dark", want: skin},
+		{input: "@deep blue", want: color.RGBA{B: 80, A: 255}},
+		{input: "red", want: color.RGBA{R: 255, A: 255}},
+	} {
+		got, err := ParseWith(tt.input, palette)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+
+	for _, input := range []string{"@3", "@-1", "@skin", "@"} {
+		_, err := ParseWith(input, palette)
+		var cerr Error
+		if !errors.As(err, &cerr) || cerr.Code != "invalid_swatch" {
+			t.Fatalf("%s: expected invalid_swatch, got %v", input, err)
+		}
+	}
+	if _, err := Parse("@0"); err == nil {
+		t.Fatalf("expected references to fail without a palette")
+	}
+}
+
+func TestPaletteAddAndRemove(t *testing.T) {
+	var palette Palette
+	if index, err := palette.Add("", color.RGBA{A: 255}); err != nil || index != 0 {
+		t.Fatalf("expected unnamed swatch at 0, got %d, %v", index, err)
+	}
+	if index, err := palette.Add("ink", color.RGBA{R: 1, A: 255}); err != nil || index != 1 {
+		t.Fatalf("expected ink at 1, got %d, %v", index, err)
+	}
+	for _, name := range []string{"ink", "7", " padded", "tab\tbed"} {
+		if _, err := palette.Add(name, color.RGBA{}); err == nil {
+			t.Fatalf("expected name %q to be rejected", name)
+		}
+	}
+
+	if err := palette.Remove("0"); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+	if index, err := palette.Resolve("ink"); err != nil || index != 0 {
+		t.Fatalf("expected ink to move down to 0, got %d, %v", index, err)
+	}
+	if err := palette.Remove("ink"); err != nil || len(palette) != 0 {
+		t.Fatalf("expected an empty palette, got %v, %v", palette, err)
+	}
+}
+
+func TestPaletteSanitizeDropsUnusableNames(t *testing.T) {
+	got := Palette{{Name: "sky"}, {Name: "sky"}, {Name: "12"}, {Name: " grass "}}.Sanitize()
+	want := Palette{{Name: "sky"}, {}, {}, {Name: "grass"}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestLabRoundTrip(t *testing.T) {
+	white := ToLab(color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	if white.L < 99.9 || white.L > 100.1 || white.A*white.A > 0.01 || white.B*white.B > 0.01 {
+		t.Fatalf("expected white to be L=100 a=0 b=0, got %+v", white)
+	}
+	for _, c := range []color.RGBA{{A: 255}, {R: 255, A: 255}, {R: 12, G: 200, B: 99, A: 255}, {R: 90, G: 60, B: 240, A: 255}} {
+		if got := FromLab(ToLab(c)); got != c {
+			t.Fatalf("expected %v to survive a Lab round trip, got %v", c, got)
+		}
+	}
+}
+
+func TestPaletteNearestUsesLabDistance(t *testing.T) {
+	gray := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	// The green swatch is closer in RGB, but the reddish one looks closer.
+	palette := Palette{{Color: color.RGBA{R: 128, G: 160, B: 128, A: 255}}, {Color: color.RGBA{R: 161, G: 128, B: 128, A: 255}}}
+	if got := palette.Nearest(gray); got != 1 {
+		t.Fatalf("expected the reddish swatch, got index %d", got)
+	}
+	if got := palette.Index(palette[1].Color); got != 1 {
+		t.Fatalf("expected exact match at index 1, got %d", got)
+	}
+	if got := palette.Index(gray); got != -1 {
+		t.Fatalf("expected no exact match, got %d", got)
+	}
+	if got := (Palette{}).Nearest(gray); got != -1 {
+		t.Fatalf("expected -1 for an empty palette, got %d", got)
+	}
+}
@@ -0,0 +1,72 @@
+package color
+
+import (
+	"image/color"
+	"math"
+)
+
+// Hues the ends of a ramp turn toward: shadows cool, highlights warm.
+const (
+	shadowHue    = 240.0
+	highlightHue = 60.0
+)
+
+// rampSpread is how far the ends of a ramp move from the base lightness
+// toward black and white.
+const rampSpread = 0.8
+
+// RampOptions shapes a shading ramp.
+type RampOptions struct {
+	// Steps is the number of colors; fewer than 2 yields just the base.
+	Steps int
+	// HueShift is how many degrees the darkest and lightest colors turn, the
+	// shadows toward blue and the highlights toward yellow. Steps between
+	// turn in proportion.
+	HueShift float64
+	// SatCurve is how many saturation points the darkest and lightest colors
+	// lose, growing with the square of the distance from the base so midtones
+	// stay saturated. A negative curve saturates the ends instead.
+	SatCurve float64
+}
+
+// Ramp returns a pixel-art shading ramp around base, ordered from shadow to
+// highlight. The colors are evenly spaced in HSL lightness from most of the
+// way to black to most of the way to white; with an odd number of steps the
+// middle color is base itself. Alpha is kept.
+func Ramp(base color.RGBA, options RampOptions) []color.RGBA {
+	if options.Steps < 2 {
+		return []color.RGBA{base}
+	}
+	h, s, l := rgbToHSL(base)
+	ramp := make([]color.RGBA, options.Steps)
+	for i := range ramp {
+		// t runs from -1 at the darkest color to 1 at the lightest.
+		t := 2*float64(i)/float64(options.Steps-1) - 1
+		if t == 0 {
+			ramp[i] = base
+			continue
+		}
+		hue, lightness := shadowHue, l+t*rampSpread*l
+		if t > 0 {
+			hue, lightness = highlightHue, l+t*rampSpread*(1-l)
+		}
+		saturation := math.Max(0, math.Min(1, s-options.SatCurve/100*t*t))
+		out := hslToRGB(turnHue(h, hue, options.HueShift*math.Abs(t)), saturation, lightness)
+		out.A = base.A
+		ramp[i] = out
+	}
+	return ramp
+}
+
+// turnHue turns hue by up to degrees toward target along the shorter way
+// around the color wheel, without passing it. A negative turn moves away.
+func turnHue(hue, target, degrees float64) float64 {
+	delta := math.Mod(target-hue+540, 360) - 180
+	if degrees > 0 && degrees > math.Abs(delta) {
+		degrees = math.Abs(delta)
+	}
+	if delta < 0 {
+		degrees = -degrees
+	}
+	return math.Mod(math.Mod(hue+degrees, 360)+360, 360)
+}
@@ -0,0 +1,496 @@
+// Package command describes daemon requests declaratively: each Spec names a
+// command, its typed positional parameters and flags, and its help text. The
+// daemon binds and dispatches requests with it, the CLI generates its
+// subcommands from it, and `pxcli schema` dumps it as JSON.
+package command
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of a parameter or flag value.
+type Kind string
+
+const (
+	KindInt    Kind = "int"
+	KindString Kind = "string"
+	KindColor  Kind = "color"
+	KindPoint  Kind = "point"
+	KindSize   Kind = "size"
+	KindPath   Kind = "path"
+	KindBool   Kind = "bool"
+)
+
+// Error is a usage error with a code and message.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+func invalidArgs(format string, args ...any) error {
+	return Error{Code: "invalid_args", Message: fmt.Sprintf(format, args...)}
+}
+
+// Param describes a positional parameter or a flag.
+type Param struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"type"`
+	Help string `json:"help,omitempty"`
+	// Optional parameters may be omitted from the end of the arguments.
+	Optional bool `json:"optional,omitempty"`
+	// Variadic parameters take every argument the fixed parameters leave,
+	// and at least MinCount of them.
+	Variadic bool     `json:"variadic,omitempty"`
+	MinCount int      `json:"min_count,omitempty"`
+	Min      *int     `json:"min,omitempty"`
+	Max      *int     `json:"max,omitempty"`
+	Values   []string `json:"values,omitempty"`
+	Default  string   `json:"default,omitempty"`
+	// Placeholder names the value in usage text; it defaults to Name.
+	Placeholder string `json:"-"`
+}
+
+// ParamOption configures a parameter.
+type ParamOption func(*Param)
+
+// Optional lets a trailing parameter be omitted.
+func Optional(p *Param) {
+	p.Optional = true
+}
+
+// Help sets the parameter's help text.
+func Help(text string) ParamOption {
+	return func(p *Param) {
+		p.Help = text
+	}
+}
+
+// AtLeast requires an integer of at least min.
+func AtLeast(min int) ParamOption {
+	return func(p *Param) {
+		p.Min = &min
+	}
+}
+
+// Between requires an integer from min to max inclusive.
+func Between(min, max int) ParamOption {
+	return func(p *Param) {
+		p.Min, p.Max = &min, &max
+	}
+}
+
+// OneOf restricts the value to the listed choices.
+func OneOf(values ...string) ParamOption {
+	return func(p *Param) {
+		p.Values = values
+	}
+}
+
+// Default sets the value an optional parameter or flag takes when it is not
+// given.
+func Default(value string) ParamOption {
+	return func(p *Param) {
+		p.Default = value
+	}
+}
+
+// Placeholder names the value in usage text.
+func Placeholder(name string) ParamOption {
+	return func(p *Param) {
+		p.Placeholder = name
+	}
+}
+
+func newParam(name string, kind Kind, opts []ParamOption) Param {
+	p := Param{Name: name, Kind: kind}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return p
+}
+
+// Int declares an integer parameter.
+func Int(name string, opts ...ParamOption) Param {
+	return newParam(name, KindInt, opts)
+}
+
+// String declares a free-form string parameter.
+func String(name string, opts ...ParamOption) Param {
+	return newParam(name, KindString, opts)
+}
+
+// Color declares a color parameter. Colors are parsed by the daemon, which may
+// know names the client does not.
+func Color(name string, opts ...ParamOption) Param {
+	return newParam(name, KindColor, opts)
+}
+
+// Size declares a "WxH" pair of positive integers.
+func Size(name string, opts ...ParamOption) Param {
+	return newParam(name, KindSize, opts)
+}
+
+// Path declares a file path. The CLI resolves it to an absolute path.
+func Path(name string, opts ...ParamOption) Param {
+	return newParam(name, KindPath, opts)
+}
+
+// Bool declares a boolean flag.
+func Bool(name string, opts ...ParamOption) Param {
+	return newParam(name, KindBool, opts)
+}
+
+// Points declares a variadic list of at least min "x,y" points.
+func Points(name string, min int, opts ...ParamOption) Param {
+	p := newParam(name, KindPoint, opts)
+	p.Variadic, p.MinCount = true, min
+	return p
+}
+
+// placeholder returns the name shown for the parameter's value in usage text.
+func (p Param) placeholder() string {
+	if p.Placeholder != "" {
+		return p.Placeholder
+	}
+	if len(p.Values) > 0 {
+		return strings.Join(p.Values, "|")
+	}
+	return p.Name
+}
+
+// check validates a single value of the parameter.
+func (p Param) check(value string) error {
+	switch p.Kind {
+	case KindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return invalidArgs("%s must be an integer", p.Name)
+		}
+		switch {
+		case p.Min != nil && p.Max != nil && (n < *p.Min || n > *p.Max):
+			return invalidArgs("%s must be between %d and %d", p.Name, *p.Min, *p.Max)
+		case p.Min != nil && n < *p.Min:
+			return invalidArgs("%s must be >= %d", p.Name, *p.Min)
+		case p.Max != nil && n > *p.Max:
+			return invalidArgs("%s must be <= %d", p.Name, *p.Max)
+		}
+	case KindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return invalidArgs("%s must be a boolean", p.Name)
+		}
+	case KindPoint:
+		if _, err := parsePoint(value); err != nil {
+			return err
+		}
+	case KindSize:
+		if _, _, err := parseSize(value); err != nil {
+			form := p.Placeholder
+			if form == "" {
+				form = "WxH"
+			}
+			return invalidArgs("%s must be %s with positive values", p.Name, form)
+		}
+	case KindPath:
+		if value == "" {
+			return invalidArgs("%s must not be empty", p.Name)
+		}
+	}
+	if len(p.Values) > 0 && !containsFold(p.Values, value) {
+		if len(p.Values) == 2 {
+			return invalidArgs("%s must be %s or %s", p.Name, p.Values[0], p.Values[1])
+		}
+		return invalidArgs("%s must be one of %s", p.Name, strings.Join(p.Values, ", "))
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePoint(value string) (image.Point, error) {
+	rawX, rawY, ok := strings.Cut(value, ",")
+	if !ok {
+		return image.Point{}, invalidArgs("point %q must be x,y", value)
+	}
+	x, errX := strconv.Atoi(rawX)
+	y, errY := strconv.Atoi(rawY)
+	if errX != nil || errY != nil {
+		return image.Point{}, invalidArgs("point %q must be x,y integers", value)
+	}
+	return image.Point{X: x, Y: y}, nil
+}
+
+func parseSize(value string) (int, int, error) {
+	rawA, rawB, ok := strings.Cut(strings.ToLower(value), "x")
+	if !ok {
+		return 0, 0, invalidArgs("size %q must be WxH", value)
+	}
+	a, errA := strconv.Atoi(rawA)
+	b, errB := strconv.Atoi(rawB)
+	if errA != nil || errB != nil || a <= 0 || b <= 0 {
+		return 0, 0, invalidArgs("size %q must be WxH with positive values", value)
+	}
+	return a, b, nil
+}
+
+// Group describes a command group such as "layer", whose commands are named
+// "<group> <sub>".
+type Group struct {
+	Name  string `json:"name"`
+	Short string `json:"summary"`
+}
+
+// Spec declares a command.
+type Spec struct {
+	// Name is the request command, or "<group> <sub>" for a group command.
+	Name   string  `json:"name"`
+	Short  string  `json:"summary"`
+	Long   string  `json:"description,omitempty"`
+	Params []Param `json:"params,omitempty"`
+	Flags  []Param `json:"flags,omitempty"`
+	// Check validates combinations of arguments after each one is checked.
+	Check func(Args) error `json:"-"`
+}
+
+// Group returns the group of a group command and its subcommand name, or an
+// empty group and the command name.
+func (s Spec) Group() (string, string) {
+	group, sub, ok := strings.Cut(s.Name, " ")
+	if !ok {
+		return "", s.Name
+	}
+	return group, sub
+}
+
+// Flag returns the named flag.
+func (s Spec) Flag(name string) (Param, bool) {
+	for _, f := range s.Flags {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Param{}, false
+}
+
+// Usage renders CLI usage, with flags before the positional parameters.
+func (s Spec) Usage() string {
+	_, sub := s.Group()
+	parts := []string{sub}
+	for _, f := range s.Flags {
+		if f.Kind == KindBool {
+			parts = append(parts, fmt.Sprintf("[--%s]", f.Name))
+		} else {
+			parts = append(parts, fmt.Sprintf("[--%s %s]", f.Name, f.placeholder()))
+		}
+	}
+	parts = append(parts, s.positionalUsage()...)
+	return strings.Join(parts, " ")
+}
+
+// Signature renders the request as sent on the wire, with "--name=value"
+// flags after the positional parameters.
+func (s Spec) Signature() string {
+	parts := append([]string{s.Name}, s.positionalUsage()...)
+	for _, f := range s.Flags {
+		if f.Kind == KindBool {
+			parts = append(parts, fmt.Sprintf("[--%s]", f.Name))
+		} else {
+			parts = append(parts, fmt.Sprintf("[--%s=<%s>]", f.Name, f.placeholder()))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s Spec) positionalUsage() []string {
+	var parts []string
+	for _, p := range s.Params {
+		switch {
+		case p.Variadic && p.Kind == KindPoint:
+			for i := 1; i <= p.MinCount; i++ {
+				parts = append(parts, fmt.Sprintf("<x%d,y%d>", i, i))
+			}
+			parts = append(parts, "[x,y...]")
+		case p.Variadic:
+			for range p.MinCount {
+				parts = append(parts, "<"+p.placeholder()+">")
+			}
+			parts = append(parts, "["+p.placeholder()+"...]")
+		case p.Optional:
+			parts = append(parts, "["+p.placeholder()+"]")
+		default:
+			parts = append(parts, "<"+p.placeholder()+">")
+		}
+	}
+	return parts
+}
+
+// argRange returns how many positional arguments the command takes; max is
+// -1 when a variadic parameter makes it unbounded.
+func (s Spec) argRange() (int, int) {
+	min, max := 0, 0
+	for _, p := range s.Params {
+		switch {
+		case p.Variadic:
+			min += p.MinCount
+			max = -1
+		case p.Optional:
+			if max >= 0 {
+				max++
+			}
+		default:
+			min++
+			if max >= 0 {
+				max++
+			}
+		}
+	}
+	return min, max
+}
+
+// Bind checks request arguments against the spec: "--name[=value]" flags may
+// appear anywhere, and the remaining arguments fill the positional parameters
+// in order.
+func (s Spec) Bind(args []string) (Args, error) {
+	bound := Args{values: map[string][]string{}, defaults: map[string]string{}}
+	for _, p := range append(slices.Clone(s.Params), s.Flags...) {
+		if p.Default != "" {
+			bound.defaults[p.Name] = p.Default
+		}
+	}
+	positional := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		flag, ok := s.Flag(name)
+		if !ok {
+			return Args{}, invalidArgs("unknown option --%s", name)
+		}
+		if !hasValue {
+			if flag.Kind != KindBool {
+				return Args{}, invalidArgs("option --%s needs a value", name)
+			}
+			value = "true"
+		}
+		if err := flag.check(value); err != nil {
+			return Args{}, err
+		}
+		bound.values[name] = []string{value}
+	}
+
+	min, max := s.argRange()
+	switch got := len(positional); {
+	case min == max && got != min:
+		return Args{}, invalidArgs("expected %d args, got %d", min, got)
+	case max < 0 && got < min:
+		return Args{}, invalidArgs("expected at least %d args, got %d", min, got)
+	case max >= 0 && (got < min || got > max):
+		return Args{}, invalidArgs("expected %d to %d args, got %d", min, max, got)
+	}
+
+	extra := len(positional) - min
+	rest := positional
+	for _, p := range s.Params {
+		n := 1
+		switch {
+		case p.Variadic:
+			n = p.MinCount + extra
+		case p.Optional:
+			if extra == 0 {
+				continue
+			}
+			extra--
+		}
+		values := rest[:n]
+		rest = rest[n:]
+		for _, value := range values {
+			if err := p.check(value); err != nil {
+				return Args{}, err
+			}
+		}
+		bound.values[p.Name] = values
+	}
+	if s.Check != nil {
+		if err := s.Check(bound); err != nil {
+			return Args{}, err
+		}
+	}
+	return bound, nil
+}
+
+// Args holds request arguments bound to a spec and already checked against
+// it, so the accessors do not report errors.
+type Args struct {
+	values   map[string][]string
+	defaults map[string]string
+	// Body holds the operation lines that follow a batch header.
+	Body []string
+}
+
+// Has reports whether a positional parameter or flag was given.
+func (a Args) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// String returns a parameter or flag value, or its default when it was not
+// given.
+func (a Args) String(name string) string {
+	if values := a.values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return a.defaults[name]
+}
+
+// Strings returns every value of a variadic parameter.
+func (a Args) Strings(name string) []string {
+	return a.values[name]
+}
+
+// Int returns an integer parameter or flag, or 0 when it was not given and
+// has no default.
+func (a Args) Int(name string) int {
+	n, _ := strconv.Atoi(a.String(name))
+	return n
+}
+
+// Bool returns a boolean flag, false when it was not given and has no default.
+func (a Args) Bool(name string) bool {
+	b, _ := strconv.ParseBool(a.String(name))
+	return b
+}
+
+// Size returns a "WxH" parameter or flag.
+func (a Args) Size(name string) (int, int) {
+	x, y, _ := parseSize(a.String(name))
+	return x, y
+}
+
+// Points returns a variadic point parameter.
+func (a Args) Points(name string) []image.Point {
+	values := a.values[name]
+	points := make([]image.Point, len(values))
+	for i, value := range values {
+		points[i], _ = parsePoint(value)
+	}
+	return points
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"testing"
+)
+
+var polygon = Spec{
+	Name:   "polygon",
+	Params: []Param{Points("points", 3), Color("color")},
+	Flags: []Param{
+		Bool("fill"),
+		String("rule", OneOf("evenodd", "nonzero"), Default("evenodd")),
+	},
+}
+
+func TestBindPointsAndFlags(t *testing.T) {
+	args, err := polygon.Bind([]string{"0,0", "--fill", "4,0", "2,3", "1,1", "red", "--rule=nonzero"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []image.Point{{0, 0}, {4, 0}, {2, 3}, {1, 1}}
+	if got := args.Points("points"); !slices.Equal(got, want) {
+		t.Fatalf("expected points %v, got %v", want, got)
+	}
+	if args.String("color") != "red" || !args.Bool("fill") || args.String("rule") != "nonzero" {
+		t.Fatalf("unexpected args %+v", args)
+	}
+
+	args, err = polygon.Bind([]string{"0,0", "4,0", "2,3", "red"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args.Has("rule") || args.String("rule") != "evenodd" || args.Bool("fill") {
+		t.Fatalf("expected flag defaults, got %+v", args)
+	}
+}
+
+func TestBindOptionalParams(t *testing.T) {
+	spec := Spec{Name: "import", Params: []Param{Path("filename"), Int("x", Optional), Int("y", Optional)}}
+	for _, tt := range []struct {
+		args []string
+		x, y int
+		hasY bool
+	}{
+		{args: []string{"a.png"}},
+		{args: []string{"a.png", "4"}, x: 4},
+		{args: []string{"a.png", "4", "-2"}, x: 4, y: -2, hasY: true},
+	} {
+		args, err := spec.Bind(tt.args)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if args.Int("x") != tt.x || args.Int("y") != tt.y || args.Has("y") != tt.hasY {
+			t.Fatalf("%v: unexpected args %+v", tt.args, args)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	opacity := Spec{Name: "layer opacity", Params: []Param{String("layer"), Int("opacity", Between(0, 100))}}
+	sheet := Spec{Name: "export", Params: []Param{Path("filename")}, Flags: []Param{Size("sheet", Placeholder("RxC"))}}
+	for _, tt := range []struct {
+		name string
+		spec Spec
+		args []string
+		want string
+	}{
+		{name: "too few", spec: polygon, args: []string{"0,0", "red"}, want: "expected at least 4 args, got 2"},
+		{name: "bad point", spec: polygon, args: []string{"0,0", "4", "2,3", "red"}, want: `point "4" must be x,y`},
+		{name: "bad choice", spec: polygon, args: []string{"0,0", "4,0", "2,3", "red", "--rule=odd"}, want: "rule must be evenodd or nonzero"},
+		{name: "unknown option", spec: polygon, args: []string{"0,0", "4,0", "2,3", "red", "--solid"}, want: "unknown option --solid"},
+		{name: "missing value", spec: polygon, args: []string{"0,0", "4,0", "2,3", "red", "--rule"}, want: "option --rule needs a value"},
+		{name: "exact count", spec: opacity, args: []string{"bg"}, want: "expected 2 args, got 1"},
+		{name: "not an integer", spec: opacity, args: []string{"bg", "half"}, want: "opacity must be an integer"},
+		{name: "out of range", spec: opacity, args: []string{"bg", "101"}, want: "opacity must be between 0 and 100"},
+		{name: "bad size", spec: sheet, args: []string{"a.png", "--sheet=2by2"}, want: "sheet must be RxC with positive values"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.spec.Bind(tt.args)
+			var cmdErr Error
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("expected a command Error, got %v", err)
+			}
+			if cmdErr.Code != "invalid_args" || cmdErr.Message != tt.want {
+				t.Fatalf("expected invalid_args %q, got %s %q", tt.want, cmdErr.Code, cmdErr.Message)
+			}
+		})
+	}
+}
+
+func TestBindRunsCheck(t *testing.T) {
+	spec := Spec{
+		Name:   "pair",
+		Params: []Param{Int("a", Optional), Int("b", Optional)},
+		Check: func(args Args) error {
+			if args.Has("a") != args.Has("b") {
+				return Error{Code: "invalid_args", Message: "give both or neither"}
+			}
+			return nil
+		},
+	}
+	if _, err := spec.Bind([]string{"1", "2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := spec.Bind([]string{"1"}); err == nil || err.Error() != "invalid_args: give both or neither" {
+		t.Fatalf("expected check error, got %v", err)
+	}
+}
+
+func TestUsageAndSignature(t *testing.T) {
+	if got, want := polygon.Usage(), "polygon [--fill] [--rule evenodd|nonzero] <x1,y1> <x2,y2> <x3,y3> [x,y...] <color>"; got != want {
+		t.Fatalf("expected usage %q, got %q", want, got)
+	}
+	if got, want := polygon.Signature(), "polygon <x1,y1> <x2,y2> <x3,y3> [x,y...] <color> [--fill] [--rule=<evenodd|nonzero>]"; got != want {
+		t.Fatalf("expected signature %q, got %q", want, got)
+	}
+
+	rename := Spec{Name: "layer rename", Params: []Param{String("layer"), String("name")}}
+	if group, sub := rename.Group(); group != "layer" || sub != "rename" {
+		t.Fatalf("expected layer rename, got %q %q", group, sub)
+	}
+	if got, want := rename.Usage(), "rename <layer> <name>"; got != want {
+		t.Fatalf("expected usage %q, got %q", want, got)
+	}
+	if got, want := rename.Signature(), "layer rename <layer> <name>"; got != want {
+		t.Fatalf("expected signature %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultAutosaveDir returns where an idle daemon saves its documents unless
+// told otherwise: $XDG_STATE_HOME/pxcli/autosave, or ~/.local/state/pxcli/autosave
+// when XDG_STATE_HOME is unset, or beside the instance sockets without a home.
+func DefaultAutosaveDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "pxcli", "autosave")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "pxcli", "autosave")
+	}
+	return filepath.Join(InstanceDir(), "autosave")
+}
@@ -1,5 +1,11 @@
 package config
 
+import (
+	"time"
+
+	"pxcli/internal/history"
+)
+
 const (
 	DefaultSocketPath   = "/tmp/pxcli.sock"
 	DefaultPIDPath      = "/tmp/pxcli.pid"
@@ -9,6 +15,16 @@ const (
 	DefaultHeadless     = false
 )
 
+// Default undo history caps; a zero cap is unlimited.
+const (
+	DefaultHistoryEntries = history.DefaultMaxEntries
+	DefaultHistoryBytes   = history.DefaultMaxBytes
+)
+
+// AutoStartEnv names the environment variable that, set to a true value, makes
+// commands start a daemon when none is running.
+const AutoStartEnv = "PXCLI_AUTOSTART"
+
 // Config holds shared defaults and overrides for CLI and daemon behavior.
 type Config struct {
 	SocketPath   string
@@ -17,17 +33,32 @@ type Config struct {
 	CanvasHeight int
 	Scale        int
 	Headless     bool
+	// InitialImage is a PNG path that, when set, sizes and initializes the canvas.
+	InitialImage string
+	// Indexed makes documents store palette indices instead of colors.
+	Indexed bool
+	// HistoryEntries and HistoryBytes cap undo history, evicting the oldest entries.
+	HistoryEntries int
+	HistoryBytes   int64
+	// IdleTimeout stops the daemon after this long without requests; zero
+	// never does.
+	IdleTimeout time.Duration
+	// AutosaveDir, when set, receives the daemon's documents when it stops on
+	// its idle timeout or a signal.
+	AutosaveDir string
 }
 
 // DefaultConfig returns the default configuration values.
 func DefaultConfig() Config {
 	return Config{
-		SocketPath:   DefaultSocketPath,
-		PIDPath:      DefaultPIDPath,
-		CanvasWidth:  DefaultCanvasWidth,
-		CanvasHeight: DefaultCanvasHeight,
-		Scale:        DefaultScale,
-		Headless:     DefaultHeadless,
+		SocketPath:     DefaultSocketPath,
+		PIDPath:        DefaultPIDPath,
+		CanvasWidth:    DefaultCanvasWidth,
+		CanvasHeight:   DefaultCanvasHeight,
+		Scale:          DefaultScale,
+		Headless:       DefaultHeadless,
+		HistoryEntries: DefaultHistoryEntries,
+		HistoryBytes:   DefaultHistoryBytes,
 	}
 }
 
@@ -80,3 +111,40 @@ func WithHeadless(headless bool) Option {
 		cfg.Headless = headless
 	}
 }
+
+// WithInitialImage initializes the canvas from a PNG file instead of the canvas size.
+func WithInitialImage(path string) Option {
+	return func(cfg *Config) {
+		cfg.InitialImage = path
+	}
+}
+
+// WithIndexed makes documents store palette indices instead of colors.
+func WithIndexed(indexed bool) Option {
+	return func(cfg *Config) {
+		cfg.Indexed = indexed
+	}
+}
+
+// WithHistoryLimits overrides the undo history entry and byte caps.
+func WithHistoryLimits(entries int, bytes int64) Option {
+	return func(cfg *Config) {
+		cfg.HistoryEntries = entries
+		cfg.HistoryBytes = bytes
+	}
+}
+
+// WithIdleTimeout stops the daemon after timeout without requests.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.IdleTimeout = timeout
+	}
+}
+
+// WithAutosaveDir saves the daemon's documents to dir when it stops on its
+// idle timeout or a signal.
+func WithAutosaveDir(dir string) Option {
+	return func(cfg *Config) {
+		cfg.AutosaveDir = dir
+	}
+}
@@ -1,6 +1,11 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestDefaultConfigValues(t *testing.T) {
 	cfg := DefaultConfig()
@@ -19,6 +24,9 @@ func TestDefaultConfigValues(t *testing.T) {
 	if cfg.Headless != DefaultHeadless {
 		t.Fatalf("expected default headless %v, got %v", DefaultHeadless, cfg.Headless)
 	}
+	if cfg.HistoryEntries != DefaultHistoryEntries || cfg.HistoryBytes != DefaultHistoryBytes {
+		t.Fatalf("expected default history limits %d/%d, got %d/%d", DefaultHistoryEntries, DefaultHistoryBytes, cfg.HistoryEntries, cfg.HistoryBytes)
+	}
 }
 
 func TestConfigOverrides(t *testing.T) {
@@ -28,6 +36,7 @@ func TestConfigOverrides(t *testing.T) {
 		WithCanvasSize(8, 9),
 		WithScale(3),
 		WithHeadless(true),
+		WithHistoryLimits(50, 1024),
 	)
 
 	if cfg.SocketPath != "/tmp/test.sock" {
@@ -45,4 +54,66 @@ func TestConfigOverrides(t *testing.T) {
 	if cfg.Headless != true {
 		t.Fatalf("expected headless override true, got %v", cfg.Headless)
 	}
+	if cfg.HistoryEntries != 50 || cfg.HistoryBytes != 1024 {
+		t.Fatalf("expected history limit override 50/1024, got %d/%d", cfg.HistoryEntries, cfg.HistoryBytes)
+	}
+}
+
+func TestInstancePaths(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	socketPath, pidPath, err := InstancePaths("hero")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(runtimeDir, "pxcli", "hero.sock"); socketPath != want {
+		t.Fatalf("expected socket %q, got %q", want, socketPath)
+	}
+	if want := filepath.Join(runtimeDir, "pxcli", "hero.pid"); pidPath != want {
+		t.Fatalf("expected PID path %q, got %q", want, pidPath)
+	}
+
+	socketPath, pidPath, err = InstancePaths(DefaultInstance)
+	if err != nil || socketPath != DefaultSocketPath || pidPath != DefaultPIDPath {
+		t.Fatalf("expected default paths, got %q %q %v", socketPath, pidPath, err)
+	}
+
+	for _, name := range []string{"", "../up", "a/b", ".hidden", "two words"} {
+		if _, _, err := InstancePaths(name); err == nil {
+			t.Fatalf("expected error for instance name %q", name)
+		}
+	}
+}
+
+func TestInstanceDirFallsBackToTempDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if dir := InstanceDir(); !strings.HasPrefix(dir, os.TempDir()) {
+		t.Fatalf("expected a dir under %q, got %q", os.TempDir(), dir)
+	}
+}
+
+func TestPIDPathForSocket(t *testing.T) {
+	tests := map[string]string{
+		DefaultSocketPath:    DefaultPIDPath,
+		"/run/a/sprite.sock": "/run/a/sprite.pid",
+		"/run/a/sprite-sock": "/run/a/sprite-sock.pid",
+	}
+	for socketPath, want := range tests {
+		if got := PIDPathForSocket(socketPath); got != want {
+			t.Fatalf("PIDPathForSocket(%q) = %q, want %q", socketPath, got, want)
+		}
+	}
+}
+
+func TestDefaultAutosaveDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	if got := DefaultAutosaveDir(); got != "/state/pxcli/autosave" {
+		t.Fatalf("expected /state/pxcli/autosave, got %q", got)
+	}
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/ada")
+	if got := DefaultAutosaveDir(); got != "/home/ada/.local/state/pxcli/autosave" {
+		t.Fatalf("expected the autosave dir under HOME, got %q", got)
+	}
 }
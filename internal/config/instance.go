@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultInstance names the daemon that uses DefaultSocketPath and
+// DefaultPIDPath.
+const DefaultInstance = "default"
+
+var instanceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// InstanceDir returns the directory that holds named instances' sockets and
+// PID files: $XDG_RUNTIME_DIR/pxcli, or a per-user directory under the system
+// temp dir when XDG_RUNTIME_DIR is unset.
+func InstanceDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "pxcli")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("pxcli-%d", os.Getuid()))
+}
+
+// ValidateInstanceName checks that name is usable as a file name: letters,
+// digits, '.', '_' and '-', starting with a letter or digit.
+func ValidateInstanceName(name string) error {
+	if !instanceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// InstancePaths returns the socket and PID file paths of a named instance.
+func InstancePaths(name string) (string, string, error) {
+	if err := ValidateInstanceName(name); err != nil {
+		return "", "", err
+	}
+	if name == DefaultInstance {
+		return DefaultSocketPath, DefaultPIDPath, nil
+	}
+	socketPath := filepath.Join(InstanceDir(), name+".sock")
+	return socketPath, PIDPathForSocket(socketPath), nil
+}
+
+// PIDPathForSocket returns the PID file path that goes with a socket path: the
+// socket path with its ".sock" extension replaced by ".pid".
+func PIDPathForSocket(socketPath string) string {
+	return strings.TrimSuffix(socketPath, ".sock") + ".pid"
+}
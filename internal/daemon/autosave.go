@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// autosaveDocuments writes every document of h to dir as a PNG of its composite
+// and a project file with its history, and returns the paths written. Files
+// are named <prefix>-<document>-<time>, so autosaves from several daemons and
+// shutdowns sit side by side.
+func autosaveDocuments(h *Handler, dir, prefix string, now time.Time) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	stamp := now.Format("20060102-150405")
+	var paths []string
+	for _, doc := range h.docs.List() {
+		manager, err := h.docs.Get(doc.Name)
+		if err != nil {
+			return paths, err
+		}
+		base := filepath.Join(dir, fmt.Sprintf("%s-%s-%s", prefix, doc.Name, stamp))
+		if err := manager.Canvas().ExportPNG(base + ".png"); err != nil {
+			return paths, err
+		}
+		paths = append(paths, base+".png")
+		if err := h.saveProject(manager, base+".pxp", true); err != nil {
+			return paths, err
+		}
+		paths = append(paths, base+".pxp")
+	}
+	return paths, nil
+}
+
+// autosaveFunc returns the runtime's autosave for the daemon of h on
+// socketPath, or nil when dir is unset. Files are prefixed with the socket's
+// name.
+func autosaveFunc(h *Handler, dir, socketPath string) func() error {
+	if dir == "" {
+		return nil
+	}
+	prefix := strings.TrimSuffix(filepath.Base(socketPath), ".sock")
+	return func() error {
+		_, err := autosaveDocuments(h, dir, prefix, time.Now())
+		return err
+	}
+}
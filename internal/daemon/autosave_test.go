@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"image/color"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pxcli/internal/canvas"
+	"pxcli/internal/history"
+	"pxcli/internal/project"
+	"pxcli/internal/protocol"
+	"pxcli/internal/testutil"
+)
+
+func TestAutosaveDocuments(t *testing.T) {
+	dir := filepath.Join(testutil.TempDir(t), "autosave")
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+	for _, request := range []protocol.Request{
+		{Command: "set_pixel", Args: []string{"1", "1", "red"}},
+		{Command: "palette", Args: []string{"add", "red", "ember"}},
+		{Command: "doc", Args: []string{"new", "tiles", "4x4"}},
+	} {
+		if response := handler.Handle(request); !strings.HasPrefix(response, "ok") {
+			t.Fatalf("%v: unexpected response %q", request, response)
+		}
+	}
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.Local)
+	paths, err := autosaveDocuments(handler, dir, "pxcli", now)
+	if err != nil {
+		t.Fatalf("unexpected autosave error: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "pxcli-main-20260304-050607.png"),
+		filepath.Join(dir, "pxcli-main-20260304-050607.pxp"),
+		filepath.Join(dir, "pxcli-tiles-20260304-050607.png"),
+		filepath.Join(dir, "pxcli-tiles-20260304-050607.pxp"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("expected %q, got %q", want, paths)
+	}
+
+	img, err := canvas.ReadPNG(paths[0])
+	if err != nil {
+		t.Fatalf("unexpected PNG error: %v", err)
+	}
+	if got := color.RGBAModel.Convert(img.At(1, 1)); got != (color.RGBA{R: 255, A: 255}) {
+		t.Fatalf("expected the autosaved PNG to hold the drawing, got %v", got)
+	}
+	p, err := project.LoadFile(paths[1])
+	if err != nil {
+		t.Fatalf("unexpected project error: %v", err)
+	}
+	if len(p.Undo) != 1 {
+		t.Fatalf("expected the autosaved project to keep its undo history, got %d entries", len(p.Undo))
+	}
+	if p.Palette == nil || len(p.Palette.Swatches) != 1 || p.Palette.Swatches[0].Name != "ember" {
+		t.Fatalf("expected the autosaved project to keep the palette, got %+v", p.Palette)
+	}
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"pxcli/internal/buildinfo"
+	pxcolor "pxcli/internal/color"
+	"pxcli/internal/command"
+	"pxcli/internal/protocol"
+)
+
+// protocolFeatures lists the optional protocol features every daemon of this
+// build supports.
+var protocolFeatures = []string{"batch", "transactions", "json", "quoting", "layers", "frames", "projects", "documents", "palettes"}
+
+// Capabilities describes what a running daemon supports.
+type Capabilities struct {
+	Protocol int           `json:"protocol"`
+	Version  string        `json:"version"`
+	Canvas   CanvasSize    `json:"canvas"`
+	Document string        `json:"document"`
+	Mode     string        `json:"mode"`
+	Commands []CommandInfo `json:"commands"`
+	Colors   ColorSupport  `json:"colors"`
+	Features []string      `json:"features"`
+}
+
+// CanvasSize is the canvas size reported by capabilities.
+type CanvasSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// CommandInfo names a request and its argument signature.
+type CommandInfo struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+}
+
+// ColorSupport lists the accepted color syntaxes and names.
+type ColorSupport struct {
+	Formats []string `json:"formats"`
+	Names   []string `json:"names"`
+}
+
+// handleHello answers the protocol handshake. A client may pass the protocol
+// version it needs, and is refused if this daemon is older.
+func (h *Handler) handleHello(args command.Args) string {
+	if args.Has("protocol") {
+		if required := args.Int("protocol"); required > protocol.Version {
+			return protocol.FormatError("unsupported_protocol", fmt.Sprintf(
+				"daemon speaks protocol %d but the client needs %d; restart the daemon with a newer pxcli", protocol.Version, required))
+		}
+	}
+	return protocol.FormatOK(fmt.Sprintf("protocol=%d version=%s", protocol.Version, protocol.QuoteArg(buildinfo.Version)))
+}
+
+// handleCapabilities reports the daemon's capabilities as a JSON object.
+func (h *Handler) handleCapabilities(command.Args) string {
+	encoded, err := json.Marshal(h.capabilities())
+	if err != nil {
+		return protocol.FormatError("error", err.Error())
+	}
+	return protocol.FormatOK(string(encoded))
+}
+
+func (h *Handler) capabilities() Capabilities {
+	document, manager := h.docs.Active()
+	target := manager.Canvas()
+	mode := "headless"
+	if h.windowed {
+		mode = "windowed"
+	}
+	specs := Specs()
+	commands := make([]CommandInfo, len(specs))
+	for i, spec := range specs {
+		commands[i] = CommandInfo{Name: spec.Name, Signature: spec.Signature()}
+	}
+	features := append([]string{mode}, protocolFeatures...)
+	if h.windowed {
+		features = append(features, "playback")
+	}
+	if h.docs.Indexed() {
+		features = append(features, "indexed")
+	}
+	return Capabilities{
+		Protocol: protocol.Version,
+		Version:  buildinfo.Version,
+		Canvas:   CanvasSize{Width: target.Width(), Height: target.Height()},
+		Document: document,
+		Mode:     mode,
+		Commands: commands,
+		Colors:   ColorSupport{Formats: pxcolor.Formats(), Names: pxcolor.Names()},
+		Features: features,
+	}
+}
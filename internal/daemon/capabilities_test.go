@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"pxcli/internal/buildinfo"
+	"pxcli/internal/canvas"
+	"pxcli/internal/history"
+	"pxcli/internal/protocol"
+)
+
+func TestHandlerHello(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	want := fmt.Sprintf("ok protocol=%d version=%s", protocol.Version, buildinfo.Version)
+	for _, args := range [][]string{nil, {"1"}} {
+		if response := handler.Handle(protocol.Request{Command: "hello", Args: args}); response != want {
+			t.Fatalf("hello %v: expected %q, got %q", args, want, response)
+		}
+	}
+	newer := fmt.Sprint(protocol.Version + 1)
+	if response := handler.Handle(protocol.Request{Command: "hello", Args: []string{newer}}); !strings.HasPrefix(response, "err unsupported_protocol ") {
+		t.Fatalf("expected unsupported_protocol, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "hello", Args: []string{"zero"}}); !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args, got %q", response)
+	}
+}
+
+func TestHandlerCapabilities(t *testing.T) {
+	target, err := canvas.New(16, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		opts     []HandlerOption
+		mode     string
+		playback bool
+	}{
+		{name: "headless", mode: "headless"},
+		{name: "windowed", opts: []HandlerOption{WithWindowed()}, mode: "windowed", playback: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(history.New(target), nil, tt.opts...)
+			payload, ok := strings.CutPrefix(handler.Handle(protocol.Request{Command: "capabilities"}), "ok ")
+			if !ok {
+				t.Fatalf("expected ok response, got %q", payload)
+			}
+			var caps Capabilities
+			if err := json.Unmarshal([]byte(payload), &caps); err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if caps.Protocol != protocol.Version || caps.Version != buildinfo.Version {
+				t.Fatalf("unexpected versions %d %q", caps.Protocol, caps.Version)
+			}
+			if caps.Canvas != (CanvasSize{Width: 16, Height: 8}) {
+				t.Fatalf("unexpected canvas size %+v", caps.Canvas)
+			}
+			if caps.Mode != tt.mode || !slices.Contains(caps.Features, tt.mode) {
+				t.Fatalf("expected %s mode, got %q with features %q", tt.mode, caps.Mode, caps.Features)
+			}
+			if slices.Contains(caps.Features, "playback") != tt.playback {
+				t.Fatalf("unexpected playback feature in %q", caps.Features)
+			}
+			if !slices.Contains(caps.Colors.Names, "red") || !slices.Contains(caps.Colors.Formats, "#rrggbbaa") {
+				t.Fatalf("unexpected colors %+v", caps.Colors)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesListKnownCommands(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	for _, command := range handler.capabilities().Commands {
+		if command.Name == "mode" || command.Name == "stop" {
+			continue
+		}
+		// Requests without args are rejected or answered, never unknown.
+		fields := strings.Fields(command.Name)
+		response := handler.Handle(protocol.Request{Command: fields[0], Args: fields[1:]})
+		if strings.HasPrefix(response, "err invalid_command ") {
+			t.Fatalf("capabilities lists %q but the handler does not know it", command.Signature)
+		}
+	}
+}
@@ -0,0 +1,611 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"pxcli/internal/canvas"
+	"pxcli/internal/command"
+	"pxcli/internal/protocol"
+)
+
+// Command is a daemon request: its spec and the handler method that runs it.
+type Command struct {
+	command.Spec
+	// NotInBatch marks commands that control history or the daemon itself
+	// and so cannot run inside a batch.
+	NotInBatch bool
+	// Session marks commands that act on the daemon, the connection or the
+	// document set rather than on one document, so they take no --doc flag.
+	Session bool
+	// ReadOnly marks document commands that change nothing, so they may run
+	// while another connection has a transaction open on the document.
+	ReadOnly bool
+	run      func(*Handler, command.Args) string
+}
+
+// Groups lists the command groups.
+func Groups() []command.Group {
+	return []command.Group{
+		{Name: "layer", Short: "Manage the layer stack"},
+		{Name: "frame", Short: "Manage animation frames"},
+		{Name: "history", Short: "Inspect undo history"},
+		{Name: "doc", Short: "Manage the daemon's documents"},
+		{Name: "palette", Short: "Manage the daemon's color palette"},
+	}
+}
+
+// Specs returns the specs of every daemon command.
+func Specs() []command.Spec {
+	commands := Commands()
+	specs := make([]command.Spec, len(commands))
+	for i, c := range commands {
+		specs[i] = c.Spec
+	}
+	return specs
+}
+
+// Commands returns every daemon command. It is the single definition of each
+// request's arguments, help text and handler.
+func Commands() []Command {
+	var (
+		x     = command.Int("x")
+		y     = command.Int("y")
+		color = command.Color("color")
+		layer = command.String("layer")
+		frame = command.Int("index")
+	)
+	fill := func(help string) command.Param {
+		return command.Bool("fill", command.Help(help))
+	}
+	commands := []Command{
+		{
+			Spec: command.Spec{
+				Name:   "hello",
+				Short:  "Check that the daemon speaks this pxcli's protocol version",
+				Params: []command.Param{command.Int("protocol", command.Optional, command.AtLeast(1))},
+			},
+			Session: true,
+			run:     (*Handler).handleHello,
+		},
+		{
+			Spec:    command.Spec{Name: "capabilities", Short: "Describe the daemon's protocol, canvas, commands, colors and features as JSON"},
+			Session: true,
+			run:     (*Handler).handleCapabilities,
+		},
+		{
+			Spec:    command.Spec{Name: "status", Short: "Report the daemon's PID, uptime, canvas, mode, history depth and request counts"},
+			Session: true,
+			run:     (*Handler).handleStatus,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "mode",
+				Short:  "Switch the connection between line and JSON requests",
+				Params: []command.Param{command.String("mode", command.OneOf(protocol.ModeLine, protocol.ModeJSON))},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handleMode,
+		},
+		{
+			Spec: command.Spec{Name: "set_pixel", Short: "Set a pixel color", Params: []command.Param{x, y, color}},
+			run:  (*Handler).handleSetPixel,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "get_pixel",
+				Short:  "Get a pixel color from the composite or a single layer",
+				Params: []command.Param{x, y},
+				Flags: []command.Param{command.String("layer", command.Placeholder("name|index"),
+					command.Help("Read a single layer instead of the composite"))},
+			},
+			ReadOnly: true,
+			run:      (*Handler).handleGetPixel,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "fill_rect",
+				Short:  "Fill a rectangle on the canvas",
+				Params: []command.Param{x, y, command.Int("w", command.AtLeast(1)), command.Int("h", command.AtLeast(1)), color},
+			},
+			run: (*Handler).handleFillRect,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "line",
+				Short:  "Draw a line on the canvas",
+				Params: []command.Param{command.Int("x1"), command.Int("y1"), command.Int("x2"), command.Int("y2"), color},
+			},
+			run: (*Handler).handleLine,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "rect",
+				Short:  "Draw a rectangle outline on the canvas",
+				Params: []command.Param{x, y, command.Int("w", command.AtLeast(1)), command.Int("h", command.AtLeast(1)), color},
+			},
+			run: (*Handler).handleRect,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "polyline",
+				Short:  "Draw connected line segments through the given points",
+				Params: []command.Param{command.Points("points", 2), color},
+			},
+			run: (*Handler).handlePolyline,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "polygon",
+				Short:  "Draw a closed polygon outline, or fill it with --fill",
+				Params: []command.Param{command.Points("points", 3), color},
+				Flags: []command.Param{
+					fill("Fill the polygon interior"),
+					command.String("rule", command.OneOf("evenodd", "nonzero"), command.Default("evenodd"),
+						command.Help("Fill rule for self-intersecting outlines: evenodd or nonzero")),
+				},
+			},
+			run: (*Handler).handlePolygon,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "fill",
+				Short:  "Flood fill a region with a color",
+				Params: []command.Param{x, y, color},
+				Flags: []command.Param{
+					command.Int("tolerance", command.Between(0, 255), command.Placeholder("N"),
+						command.Help("Maximum per-channel color difference (0-255)")),
+					command.Int("connectivity", command.OneOf("4", "8"), command.Default("4"),
+						command.Help("Neighbor connectivity: 4 or 8")),
+					command.Bool("global", command.Help("Replace every matching pixel, not just the contiguous region")),
+				},
+			},
+			run: (*Handler).handleFill,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "circle",
+				Short:  "Draw a circle on the canvas",
+				Params: []command.Param{command.Int("cx"), command.Int("cy"), command.Int("r", command.Between(0, canvas.MaxRadius)), color},
+				Flags:  []command.Param{fill("Draw a filled shape instead of an outline")},
+			},
+			run: (*Handler).handleCircle,
+		},
+		{
+			Spec: command.Spec{
+				Name:  "ellipse",
+				Short: "Draw an ellipse on the canvas",
+				Params: []command.Param{command.Int("cx"), command.Int("cy"),
+					command.Int("rx", command.Between(0, canvas.MaxRadius)), command.Int("ry", command.Between(0, canvas.MaxRadius)), color},
+				Flags: []command.Param{fill("Draw a filled shape instead of an outline")},
+			},
+			run: (*Handler).handleEllipse,
+		},
+		{
+			Spec: command.Spec{
+				Name:  "arc",
+				Short: "Draw a circular arc (or pie slice with --fill), counterclockwise from start to end",
+				Params: []command.Param{command.Int("cx"), command.Int("cy"), command.Int("r", command.Between(0, canvas.MaxRadius)),
+					command.Int("start", command.Placeholder("start-deg")), command.Int("end", command.Placeholder("end-deg")), color},
+				Flags: []command.Param{fill("Draw a filled pie slice instead of an arc outline")},
+			},
+			run: (*Handler).handleArc,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "clear",
+				Short:  "Clear the canvas",
+				Params: []command.Param{command.Color("color", command.Optional, command.Default("transparent"))},
+			},
+			run: (*Handler).handleClear,
+		},
+		{
+			Spec:     command.Spec{Name: "layer list", Short: "List layers from bottom to top"},
+			ReadOnly: true,
+			run:      (*Handler).handleLayerList,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "layer add",
+				Short:  "Add a layer above the active layer and select it",
+				Params: []command.Param{command.String("name", command.Optional)},
+			},
+			run: (*Handler).handleLayerAdd,
+		},
+		{
+			Spec: command.Spec{Name: "layer remove", Short: "Remove a layer", Params: []command.Param{layer}},
+			run:  layerAction((*canvas.Canvas).RemoveLayer),
+		},
+		{
+			Spec: command.Spec{
+				Name:   "layer move",
+				Short:  "Move a layer to a new stack position",
+				Params: []command.Param{layer, command.Int("index")},
+			},
+			run: (*Handler).handleLayerMove,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "layer rename",
+				Short:  "Rename a layer",
+				Params: []command.Param{layer, command.String("name")},
+			},
+			run: (*Handler).handleLayerRename,
+		},
+		{
+			Spec: command.Spec{Name: "layer select", Short: "Make a layer the drawing target", Params: []command.Param{layer}},
+			run:  (*Handler).handleLayerSelect,
+		},
+		{
+			Spec: command.Spec{Name: "layer show", Short: "Show a layer in the composite", Params: []command.Param{layer}},
+			run:  layerAction(func(c *canvas.Canvas, ref string) error { return c.SetLayerVisible(ref, true) }),
+		},
+		{
+			Spec: command.Spec{Name: "layer hide", Short: "Hide a layer from the composite", Params: []command.Param{layer}},
+			run:  layerAction(func(c *canvas.Canvas, ref string) error { return c.SetLayerVisible(ref, false) }),
+		},
+		{
+			Spec: command.Spec{Name: "layer lock", Short: "Lock a layer against drawing", Params: []command.Param{layer}},
+			run:  layerAction(func(c *canvas.Canvas, ref string) error { return c.SetLayerLocked(ref, true) }),
+		},
+		{
+			Spec: command.Spec{Name: "layer unlock", Short: "Unlock a layer for drawing", Params: []command.Param{layer}},
+			run:  layerAction(func(c *canvas.Canvas, ref string) error { return c.SetLayerLocked(ref, false) }),
+		},
+		{
+			Spec: command.Spec{
+				Name:   "layer opacity",
+				Short:  "Set a layer's opacity percentage",
+				Params: []command.Param{layer, command.Int("opacity", command.Between(0, 100), command.Placeholder("0-100"))},
+			},
+			run: (*Handler).handleLayerOpacity,
+		},
+		{
+			Spec: command.Spec{
+				Name:  "layer blend",
+				Short: "Set a layer's blend mode",
+				Params: []command.Param{layer, command.String("mode", command.OneOf(
+					string(canvas.BlendNormal), string(canvas.BlendMultiply), string(canvas.BlendScreen),
+					string(canvas.BlendOverlay), string(canvas.BlendAdd)))},
+			},
+			run: (*Handler).handleLayerBlend,
+		},
+		{
+			Spec:     command.Spec{Name: "frame list", Short: "List frames with their durations"},
+			ReadOnly: true,
+			run:      (*Handler).handleFrameList,
+		},
+		{
+			Spec: command.Spec{Name: "frame add", Short: "Add an empty frame after the current frame and select it"},
+			run:  (*Handler).handleFrameAdd,
+		},
+		{
+			Spec: command.Spec{Name: "frame dup", Short: "Duplicate a frame and select the copy", Params: []command.Param{frame}},
+			run:  (*Handler).handleFrameDup,
+		},
+		{
+			Spec: command.Spec{Name: "frame delete", Short: "Delete a frame", Params: []command.Param{frame}},
+			run:  (*Handler).handleFrameDelete,
+		},
+		{
+			Spec: command.Spec{Name: "frame select", Short: "Make a frame the drawing target", Params: []command.Param{frame}},
+			run:  (*Handler).handleFrameSelect,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "frame move",
+				Short:  "Move a frame to a new timeline position",
+				Params: []command.Param{frame, command.Int("to")},
+			},
+			run: (*Handler).handleFrameMove,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "frame duration",
+				Short:  "Set how long a frame is shown",
+				Params: []command.Param{frame, command.Int("ms", command.AtLeast(1))},
+			},
+			run: (*Handler).handleFrameDuration,
+		},
+		{
+			Spec: command.Spec{Name: "frame play", Short: "Loop the animation in the window"},
+			run:  playback(true),
+		},
+		{
+			Spec: command.Spec{Name: "frame pause", Short: "Stop animation playback and show the current frame"},
+			run:  playback(false),
+		},
+		{
+			Spec: command.Spec{
+				Name:   "export",
+				Short:  "Export the canvas to a PNG, animated GIF or sprite sheet",
+				Params: []command.Param{command.Path("filename", command.Placeholder("filename.png|filename.gif"))},
+				Flags: []command.Param{
+					command.String("layer", command.Placeholder("name|index"),
+						command.Help("Export a single layer instead of the composite")),
+					command.Size("sheet", command.Placeholder("RxC"),
+						command.Help("Write all frames as a rows x cols sprite sheet plus a JSON atlas")),
+				},
+				Check: checkExport,
+			},
+			ReadOnly: true,
+			run:      (*Handler).handleExport,
+		},
+		{
+			Spec: command.Spec{
+				Name:  "import",
+				Short: "Paste a PNG onto the active layer at an offset",
+				Params: []command.Param{command.Path("filename", command.Placeholder("filename.png")),
+					command.Int("x", command.Optional), command.Int("y", command.Optional)},
+				Check: func(args command.Args) error {
+					if args.Has("x") != args.Has("y") {
+						return command.Error{Code: "invalid_args", Message: "expected 1 or 3 args, got 2"}
+					}
+					return nil
+				},
+			},
+			run: (*Handler).handleImport,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "save",
+				Short:  "Save the session to a project file",
+				Params: []command.Param{command.Path("filename", command.Placeholder("file.pxp"))},
+				Flags:  []command.Param{command.Bool("history", command.Help("Also store the undo/redo stacks"))},
+			},
+			ReadOnly: true,
+			run:      (*Handler).handleSave,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "open",
+				Short:  "Replace the session with a saved project file",
+				Params: []command.Param{command.Path("filename", command.Placeholder("file.pxp"))},
+			},
+			NotInBatch: true,
+			run:        (*Handler).handleOpen,
+		},
+		{
+			Spec:       command.Spec{Name: "undo", Short: "Undo the last change"},
+			NotInBatch: true,
+			run:        (*Handler).handleUndo,
+		},
+		{
+			Spec:       command.Spec{Name: "redo", Short: "Redo the last undone change"},
+			NotInBatch: true,
+			run:        (*Handler).handleRedo,
+		},
+		{
+			Spec:     command.Spec{Name: "history stats", Short: "Show undo/redo entry counts and memory use against the limits"},
+			ReadOnly: true,
+			run:      (*Handler).handleHistoryStats,
+		},
+		{
+			Spec:       command.Spec{Name: "begin", Short: "Open a transaction; changes until commit become one undo step"},
+			NotInBatch: true,
+			run:        func(h *Handler, _ command.Args) string { return h.handleTransaction(h.history.Begin) },
+		},
+		{
+			Spec:       command.Spec{Name: "commit", Short: "Close the open transaction, keeping its changes as one undo step"},
+			NotInBatch: true,
+			run:        func(h *Handler, _ command.Args) string { return h.handleTransaction(h.history.Commit) },
+		},
+		{
+			Spec:       command.Spec{Name: "rollback", Short: "Close the open transaction, reverting its changes"},
+			NotInBatch: true,
+			run:        func(h *Handler, _ command.Args) string { return h.handleTransaction(h.history.Rollback) },
+		},
+		{
+			Spec: command.Spec{
+				Name:  "batch",
+				Short: "Run newline-separated operations from stdin as one atomic undo step",
+				Long: "Run newline-separated operations from stdin as one atomic undo step.\n\n" +
+					"Each line is a daemon request such as \"set_pixel 1 2 red\". Blank lines and lines\n" +
+					"starting with # are ignored. If any operation fails, every change is rolled back.",
+				Params: []command.Param{command.Int("lines", command.Between(0, protocol.MaxBatchLines))},
+			},
+			NotInBatch: true,
+			run:        (*Handler).handleBatch,
+		},
+		{
+			Spec:       command.Spec{Name: "stop", Short: "Stop the pxcli daemon"},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handleStop,
+		},
+		{
+			Spec:    command.Spec{Name: "doc list", Short: "List documents with their sizes"},
+			Session: true,
+			run:     (*Handler).handleDocList,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "doc new",
+				Short:  "Add an empty document and make it active",
+				Params: []command.Param{command.String("name"), command.Size("size", command.Placeholder("WxH"))},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handleDocNew,
+		},
+		{
+			Spec:       command.Spec{Name: "doc select", Short: "Make a document the target of commands without --doc", Params: []command.Param{command.String("name")}},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handleDocSelect,
+		},
+		{
+			Spec:       command.Spec{Name: "doc close", Short: "Discard a document and its undo history", Params: []command.Param{command.String("name")}},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handleDocClose,
+		},
+		{
+			Spec:    command.Spec{Name: "palette list", Short: "List palette swatches with their indices and names"},
+			Session: true,
+			run:     (*Handler).handlePaletteList,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "palette load",
+				Short:  "Replace the palette with a .gpl, .hex, .pal or .ase swatch file",
+				Params: []command.Param{command.Path("filename")},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handlePaletteLoad,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "palette add",
+				Short:  "Append a swatch, optionally named for @name references",
+				Params: []command.Param{color, command.String("name", command.Optional)},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handlePaletteAdd,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "palette remove",
+				Short:  "Remove a swatch by name or index",
+				Params: []command.Param{command.String("swatch", command.Placeholder("name|index"))},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handlePaletteRemove,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "palette set",
+				Short:  "Change a swatch's color, recoloring an indexed document",
+				Params: []command.Param{command.String("swatch", command.Placeholder("name|index")), color},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handlePaletteSet,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "palette lock",
+				Short:  "Restrict drawing colors to the palette: strict rejects others, snap uses the nearest swatch",
+				Params: []command.Param{command.String("mode", command.OneOf(lockStrict, lockSnap))},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handlePaletteLock,
+		},
+		{
+			Spec:       command.Spec{Name: "palette unlock", Short: "Allow drawing with any color again"},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handlePaletteUnlock,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "palette save",
+				Short:  "Write the palette to a .gpl, .hex, .pal or .ase swatch file",
+				Params: []command.Param{command.Path("filename")},
+			},
+			Session: true,
+			run:     (*Handler).handlePaletteSave,
+		},
+		{
+			Spec: command.Spec{
+				Name:   "ramp",
+				Short:  "Generate a hue-shifted shading ramp around a base color, optionally appending it to the palette",
+				Params: []command.Param{command.Color("base")},
+				Flags: []command.Param{
+					command.Int("steps", command.Between(2, 32), command.Default("5"),
+						command.Help("Number of colors, from shadow to highlight")),
+					command.Int("hue-shift", command.Between(-180, 180), command.Default("15"), command.Placeholder("degrees"),
+						command.Help("Degrees the ends turn, shadows toward blue and highlights toward yellow")),
+					command.Int("sat-curve", command.Between(-100, 100), command.Default("0"), command.Placeholder("points"),
+						command.Help("Saturation points the ends lose, easing in from the base; negative saturates")),
+					command.String("add", command.Placeholder("prefix"),
+						command.Help("Append the colors to the palette as <prefix>-1 (darkest) to <prefix>-N")),
+				},
+			},
+			NotInBatch: true,
+			Session:    true,
+			run:        (*Handler).handleRamp,
+		},
+	}
+	docFlag := command.String("doc", command.Placeholder("name"), command.Help("Act on this document instead of the active one"))
+	for i := range commands {
+		if !commands[i].Session {
+			commands[i].Flags = append(commands[i].Flags, docFlag)
+		}
+	}
+	return commands
+}
+
+// lookupSpec returns the spec of a command known to exist.
+func lookupSpec(name string) command.Spec {
+	for _, spec := range Specs() {
+		if spec.Name == name {
+			return spec
+		}
+	}
+	panic("daemon: unknown command spec " + name)
+}
+
+// lookupCommand finds the command for a request and returns the arguments
+// that follow its name.
+func lookupCommand(commands map[string]*Command, request protocol.Request) (*Command, []string, error) {
+	if c, ok := commands[request.Command]; ok {
+		return c, request.Args, nil
+	}
+	for _, group := range Groups() {
+		if group.Name != request.Command {
+			continue
+		}
+		if len(request.Args) == 0 {
+			return nil, nil, handlerError{Code: "invalid_args", Message: fmt.Sprintf("%s subcommand is required", group.Name)}
+		}
+		if c, ok := commands[group.Name+" "+request.Args[0]]; ok {
+			return c, request.Args[1:], nil
+		}
+		return nil, nil, handlerError{Code: "invalid_args", Message: fmt.Sprintf("unknown %s subcommand %q", group.Name, request.Args[0])}
+	}
+	return nil, nil, handlerError{Code: "invalid_command", Message: fmt.Sprintf("unknown command %q", request.Command)}
+}
+
+// checkExport rejects --layer combined with a sprite sheet or GIF.
+func checkExport(args command.Args) error {
+	if !args.Has("layer") {
+		return nil
+	}
+	if args.Has("sheet") {
+		return command.Error{Code: "invalid_args", Message: "--layer cannot be combined with --sheet"}
+	}
+	if strings.EqualFold(filepath.Ext(args.String("filename")), ".gif") {
+		return command.Error{Code: "invalid_args", Message: "--layer is not supported for GIF export"}
+	}
+	return nil
+}
+
+// layerAction runs a canvas method taking the "layer" argument as an undoable
+// change.
+func layerAction(action func(*canvas.Canvas, string) error) func(*Handler, command.Args) string {
+	return func(h *Handler, args command.Args) string {
+		return h.applyCanvas(func(c *canvas.Canvas) error {
+			return action(c, args.String("layer"))
+		})
+	}
+}
+
+func playback(playing bool) func(*Handler, command.Args) string {
+	return func(h *Handler, _ command.Args) string {
+		h.history.Canvas().SetPlayback(playing)
+		return protocol.FormatOK("")
+	}
+}
+
+// handleMode rejects mode changes that reach the handler: the server switches
+// a connection's mode itself, so these only come from batches.
+func (h *Handler) handleMode(command.Args) string {
+	return protocol.FormatError("invalid_command", "mode can only be switched on a connection")
+}
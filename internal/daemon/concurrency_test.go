@@ -1,14 +1,19 @@
 package daemon
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"pxcli/internal/config"
+	"pxcli/internal/protocol"
 	"pxcli/internal/testutil"
 )
 
@@ -95,3 +100,207 @@ func TestHeadlessRuntimeConcurrentRequests(t *testing.T) {
 	assertPathMissing(t, pidPath)
 	assertPathMissing(t, socketPath)
 }
+
+func TestHeadlessRuntimeManyPersistentClients(t *testing.T) {
+	dir := testutil.TempDir(t)
+	socketPath := filepath.Join(dir, "pxcli.sock")
+	pidPath := filepath.Join(dir, "pxcli.pid")
+	cfg := config.New(
+		config.WithSocketPath(socketPath),
+		config.WithPIDPath(pidPath),
+		config.WithCanvasSize(16, 16),
+	)
+
+	done := startHeadlessRuntime(t, cfg)
+	t.Cleanup(func() {
+		if _, err := os.Stat(socketPath); err == nil {
+			_, _ = sendRequest(socketPath, "stop\n")
+		}
+	})
+
+	// Each of 16 clients keeps one connection open and draws its own row.
+	const clients = 16
+	errs := make(chan error, clients)
+	var wg sync.WaitGroup
+	for y := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("unix", socketPath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for x := range 16 {
+				response, err := exchangeLine(conn, reader, fmt.Sprintf("set_pixel %d %d #00ff00", x, y))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if response != "ok\n" {
+					errs <- fmt.Errorf("unexpected response for (%d,%d): %q", x, y, response)
+					return
+				}
+			}
+		}()
+	}
+
+	waitDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waitDone)
+	}()
+	select {
+	case <-waitDone:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for persistent clients")
+	}
+	close(errs)
+	for err := range errs {
+		t.Fatalf("persistent client failed: %v", err)
+	}
+
+	response := mustSendRequest(t, socketPath, "get_pixel 15 15\n")
+	if response != "ok #00ff00ff\n" {
+		t.Fatalf("expected green pixel at (15,15), got %q", response)
+	}
+
+	if response := mustSendRequest(t, socketPath, "stop\n"); response != "ok\n" {
+		t.Fatalf("expected ok stop response, got %q", response)
+	}
+	assertRuntimeDone(t, done)
+}
+
+func TestServerSlowClientDoesNotBlockOthers(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	server, err := NewServer(socketPath, stubHandler{response: "ok"}, WithRequestTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+	t.Cleanup(func() {
+		stopServer(t, server, done)
+	})
+
+	// One client connects and says nothing; another starts a request and never
+	// finishes the line.
+	silent, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer silent.Close()
+	stalled, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer stalled.Close()
+	if _, err := io.WriteString(stalled, "set_pix"); err != nil {
+		t.Fatalf("unexpected error writing partial request: %v", err)
+	}
+
+	start := time.Now()
+	if response := mustSendRequest(t, socketPath, "clear\n"); response != "ok\n" {
+		t.Fatalf("expected ok response, got %q", response)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected other clients to be served while one stalls, took %v", elapsed)
+	}
+
+	_ = stalled.SetReadDeadline(time.Now().Add(3 * time.Second))
+	line, err := bufio.NewReader(stalled).ReadString('\n')
+	if err != nil {
+		t.Fatalf("unexpected error reading timeout response: %v", err)
+	}
+	if !strings.HasPrefix(line, "err timeout ") {
+		t.Fatalf("expected timeout error for the partial request, got %q", line)
+	}
+	assertConnClosed(t, stalled)
+}
+
+// orderHandler records the first argument of each request it handles.
+type orderHandler struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (h *orderHandler) Handle(request protocol.Request) string {
+	time.Sleep(2 * time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.order = append(h.order, request.Args[0])
+	return protocol.FormatOK("")
+}
+
+func TestServerQueueIsFairAcrossConnections(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	handler := &orderHandler{}
+	server, err := NewServer(socketPath, handler)
+	if err != nil {
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+	t.Cleanup(func() {
+		stopServer(t, server, done)
+	})
+
+	// Two clients stream requests as fast as they can; neither should get
+	// more than a request or two ahead of the other.
+	const requests = 20
+	start := make(chan struct{})
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			t.Fatalf("unexpected error connecting to socket: %v", err)
+		}
+		defer conn.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader := bufio.NewReader(conn)
+			<-start
+			for range requests {
+				if _, err := exchangeLine(conn, reader, "mark "+name); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("client failed: %v", err)
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if len(handler.order) != 2*requests {
+		t.Fatalf("expected %d handled requests, got %d", 2*requests, len(handler.order))
+	}
+	// Only count runs while both clients were still sending.
+	order := strings.Join(handler.order, "")
+	last := min(strings.LastIndex(order, "a"), strings.LastIndex(order, "b"))
+	run := 1
+	for i := 1; i <= last; i++ {
+		if handler.order[i] == handler.order[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run > 2 {
+			t.Fatalf("expected requests to alternate between clients, got %v", handler.order)
+		}
+	}
+}
+
+func exchangeLine(conn net.Conn, reader *bufio.Reader, request string) (string, error) {
+	if _, err := io.WriteString(conn, request+"\n"); err != nil {
+		return "", err
+	}
+	return reader.ReadString('\n')
+}
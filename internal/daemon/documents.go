@@ -0,0 +1,276 @@
+package daemon
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"pxcli/internal/canvas"
+	pxcolor "pxcli/internal/color"
+	"pxcli/internal/command"
+	"pxcli/internal/history"
+	"pxcli/internal/protocol"
+)
+
+// DefaultDocument names the document a daemon starts with.
+const DefaultDocument = "main"
+
+var documentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// DocumentInfo describes a document for listing.
+type DocumentInfo struct {
+	Name   string
+	Width  int
+	Height int
+	Active bool
+}
+
+// Documents holds a daemon's named canvases, each with its own undo history,
+// and tracks which one is active. It is the render source of the window, which
+// shows the active document.
+type Documents struct {
+	mu     sync.Mutex
+	docs   map[string]*history.Manager
+	order  []string
+	active string
+	// owners maps a document with an open transaction to the session that
+	// began it.
+	owners map[*history.Manager]uint64
+	// switched is set when the active document changes, so the renderer
+	// redraws even though no canvas is dirty.
+	switched bool
+}
+
+// NewDocuments creates a document set holding manager as DefaultDocument.
+func NewDocuments(manager *history.Manager) *Documents {
+	return &Documents{
+		docs:   map[string]*history.Manager{DefaultDocument: manager},
+		order:  []string{DefaultDocument},
+		active: DefaultDocument,
+		owners: map[*history.Manager]uint64{},
+	}
+}
+
+// Active returns the active document's name and history.
+func (d *Documents) Active() (string, *history.Manager) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.active, d.docs[d.active]
+}
+
+// Get returns the history of a named document.
+func (d *Documents) Get(name string) (*history.Manager, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.get(name)
+}
+
+func (d *Documents) get(name string) (*history.Manager, error) {
+	manager, ok := d.docs[name]
+	if !ok {
+		return nil, handlerError{Code: "unknown_document", Message: fmt.Sprintf("no document named %q", name)}
+	}
+	return manager, nil
+}
+
+// New adds an empty width x height document, with the same history limits as
+// the active one, and makes it active. In an indexed daemon the document is
+// indexed with palette.
+func (d *Documents) New(name string, width, height int, palette pxcolor.Palette) error {
+	if !documentNamePattern.MatchString(name) {
+		return handlerError{Code: "invalid_args", Message: fmt.Sprintf("document name %q must use letters, digits, '.', '_' and '-'", name)}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.docs[name]; ok {
+		return handlerError{Code: "document_exists", Message: fmt.Sprintf("document %q already exists", name)}
+	}
+	var (
+		grid *canvas.Canvas
+		err  error
+	)
+	if d.docs[d.active].Canvas().Indexed() {
+		grid, err = canvas.NewIndexed(width, height, palette)
+	} else {
+		grid, err = canvas.New(width, height)
+	}
+	if err != nil {
+		return err
+	}
+	manager := history.New(grid)
+	manager.SetLimits(d.docs[d.active].Limits())
+	d.docs[name] = manager
+	d.order = append(d.order, name)
+	d.activate(name)
+	return nil
+}
+
+// Select makes a document active.
+func (d *Documents) Select(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.get(name); err != nil {
+		return err
+	}
+	d.activate(name)
+	return nil
+}
+
+// Close discards a document and its history. Closing the active document
+// activates the one before it; the last document cannot be closed.
+func (d *Documents) Close(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.get(name); err != nil {
+		return err
+	}
+	if len(d.order) == 1 {
+		return handlerError{Code: "last_document", Message: "cannot close the only document"}
+	}
+	index := slices.Index(d.order, name)
+	d.order = slices.Delete(d.order, index, index+1)
+	delete(d.owners, d.docs[name])
+	delete(d.docs, name)
+	if d.active == name {
+		d.activate(d.order[max(index-1, 0)])
+	}
+	return nil
+}
+
+// setOwner records session as the owner of manager's transaction while one is
+// open, and forgets the owner once it is closed.
+func (d *Documents) setOwner(manager *history.Manager, session uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if manager.InTransaction() {
+		d.owners[manager] = session
+	} else {
+		delete(d.owners, manager)
+	}
+}
+
+// checkOwner rejects a change to a document whose open transaction belongs to
+// another session.
+func (d *Documents) checkOwner(manager *history.Manager, session uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	owner, ok := d.owners[manager]
+	if ok && owner != session && manager.InTransaction() {
+		return handlerError{Code: "in_transaction", Message: "another connection has a transaction open on this document"}
+	}
+	return nil
+}
+
+// EndSession rolls back the transactions a closed session left open.
+func (d *Documents) EndSession(session uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for manager, owner := range d.owners {
+		if owner == session {
+			_ = manager.Rollback()
+			delete(d.owners, manager)
+		}
+	}
+}
+
+// Indexed reports whether the daemon's documents store palette indices.
+func (d *Documents) Indexed() bool {
+	_, manager := d.Active()
+	return manager.Canvas().Indexed()
+}
+
+func (d *Documents) activate(name string) {
+	if d.active != name {
+		d.active = name
+		d.switched = true
+	}
+}
+
+// List describes the documents in creation order.
+func (d *Documents) List() []DocumentInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	infos := make([]DocumentInfo, len(d.order))
+	for i, name := range d.order {
+		target := d.docs[name].Canvas()
+		infos[i] = DocumentInfo{Name: name, Width: target.Width(), Height: target.Height(), Active: name == d.active}
+	}
+	return infos
+}
+
+// Dirty reports whether the active document changed or needs redrawing.
+func (d *Documents) Dirty() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.switched || d.docs[d.active].Canvas().Dirty()
+}
+
+// RenderSnapshot returns the active document's render snapshot.
+func (d *Documents) RenderSnapshot() canvas.RenderSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.switched = false
+	return d.docs[d.active].Canvas().RenderSnapshot()
+}
+
+// Width returns the active document's width.
+func (d *Documents) Width() int {
+	_, manager := d.Active()
+	return manager.Canvas().Width()
+}
+
+// Height returns the active document's height.
+func (d *Documents) Height() int {
+	_, manager := d.Active()
+	return manager.Canvas().Height()
+}
+
+// Title names the active document for the window title.
+func (d *Documents) Title() string {
+	name, _ := d.Active()
+	return "pxcli - " + name
+}
+
+func (h *Handler) handleDocList(command.Args) string {
+	return protocol.FormatOK(formatDocuments(h.docs.List()))
+}
+
+func (h *Handler) handleDocNew(args command.Args) string {
+	width, height := args.Size("size")
+	if err := h.docs.New(args.String("name"), width, height, h.swatches()); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK(args.String("name"))
+}
+
+func (h *Handler) handleDocSelect(args command.Args) string {
+	if err := h.docs.Select(args.String("name")); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handleDocClose(args command.Args) string {
+	manager, err := h.docs.Get(args.String("name"))
+	if err != nil {
+		return formatError(err)
+	}
+	if err := h.docs.checkOwner(manager, h.session); err != nil {
+		return formatError(err)
+	}
+	if err := h.docs.Close(args.String("name")); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK("")
+}
+
+// formatDocuments renders documents as "; "-separated records.
+func formatDocuments(docs []DocumentInfo) string {
+	records := make([]string, len(docs))
+	for i, doc := range docs {
+		records[i] = fmt.Sprintf("%s width=%d height=%d active=%t", doc.Name, doc.Width, doc.Height, doc.Active)
+	}
+	return strings.Join(records, "; ")
+}
@@ -4,233 +4,653 @@ import (
 	"errors"
 	"fmt"
 	"image/color"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"pxcli/internal/buildinfo"
 	"pxcli/internal/canvas"
 	pxcolor "pxcli/internal/color"
+	"pxcli/internal/command"
 	"pxcli/internal/history"
+	"pxcli/internal/palette"
+	"pxcli/internal/project"
 	"pxcli/internal/protocol"
 )
 
 // Handler maps protocol requests to canvas operations.
 type Handler struct {
+	docs *Documents
+	// history is the document a request acts on. Handle sets it on a copy of
+	// the handler for each request; inside a batch it is the batch's document.
 	history *history.Manager
-	onStop  func()
+	// session identifies the connection a request arrived on, so that a
+	// transaction belongs to the connection that began it. Requests given to
+	// Handle share session 0.
+	session    uint64
+	onStop     func()
+	windowed   bool
+	scale      int
+	socketPath string
+	commands   map[string]*Command
+	// palette is shared by the per-request copies of the handler and by
+	// every document that stores colors.
+	palette *paletteState
+	// status is shared by the per-request copies of the handler.
+	status *statusTracker
 }
 
-// NewHandler creates a command handler for the provided history manager.
-func NewHandler(history *history.Manager, onStop func()) *Handler {
-	return &Handler{history: history, onStop: onStop}
+// HandlerOption configures the handler.
+type HandlerOption func(*Handler)
+
+// WithWindowed reports the daemon as windowed rather than headless in its
+// capabilities.
+func WithWindowed() HandlerOption {
+	return func(h *Handler) {
+		h.windowed = true
+	}
 }
 
-// Handle executes a command and returns a single-line protocol response.
-func (h *Handler) Handle(request protocol.Request) string {
-	switch request.Command {
-	case "set_pixel":
-		return h.handleSetPixel(request.Args)
-	case "get_pixel":
-		return h.handleGetPixel(request.Args)
-	case "fill_rect":
-		return h.handleFillRect(request.Args)
-	case "line":
-		return h.handleLine(request.Args)
-	case "clear":
-		return h.handleClear(request.Args)
-	case "export":
-		return h.handleExport(request.Args)
-	case "undo":
-		return h.handleUndo(request.Args)
-	case "redo":
-		return h.handleRedo(request.Args)
-	case "stop":
-		return h.handleStop(request.Args)
-	default:
-		return protocol.FormatError("invalid_command", fmt.Sprintf("unknown command %q", request.Command))
+// WithScale reports the window scale in the daemon's status.
+func WithScale(scale int) HandlerOption {
+	return func(h *Handler) {
+		h.scale = scale
 	}
 }
 
-func (h *Handler) handleSetPixel(args []string) string {
-	if len(args) != 3 {
-		return invalidArgCount(3, len(args))
+// WithSocketPath reports the socket the daemon listens on in its status.
+func WithSocketPath(socketPath string) HandlerOption {
+	return func(h *Handler) {
+		h.socketPath = socketPath
 	}
-	x, err := parseIntArg(args[0], "x")
+}
+
+// NewHandler creates a command handler whose first document is the provided
+// history manager.
+func NewHandler(history *history.Manager, onStop func(), opts ...HandlerOption) *Handler {
+	handler := &Handler{
+		docs:     NewDocuments(history),
+		onStop:   onStop,
+		commands: map[string]*Command{},
+		palette:  &paletteState{},
+		status:   newStatusTracker(),
+	}
+	commands := Commands()
+	for i := range commands {
+		handler.commands[commands[i].Name] = &commands[i]
+	}
+	for _, opt := range opts {
+		opt(handler)
+	}
+	return handler
+}
+
+// Handle executes a command and returns a single-line protocol response.
+func (h *Handler) Handle(request protocol.Request) string {
+	return h.HandleSession(0, request)
+}
+
+// HandleSession executes a command that arrived on a session, a connection,
+// and returns a single-line protocol response.
+func (h *Handler) HandleSession(session uint64, request protocol.Request) string {
+	target := *h
+	target.session = session
+	response := target.handle(request)
+	h.status.record(response)
+	return response
+}
+
+// EndSession rolls back any transaction a closed session left open.
+func (h *Handler) EndSession(session uint64) {
+	h.docs.EndSession(session)
+}
+
+func (h *Handler) handle(request protocol.Request) string {
+	c, args, err := lookupCommand(h.commands, request)
 	if err != nil {
 		return formatError(err)
 	}
-	y, err := parseIntArg(args[1], "y")
+	bound, err := c.Bind(args)
 	if err != nil {
 		return formatError(err)
 	}
-	value, err := pxcolor.Parse(args[2])
-	if err != nil {
-		return formatError(err)
+	bound.Body = request.Body
+	if c.Session {
+		return c.run(h, bound)
 	}
-	if err := h.history.Apply(func(c *canvas.Canvas) error {
-		return c.SetPixel(x, y, value)
-	}); err != nil {
+	target := *h
+	if target.history, err = h.document(bound); err != nil {
 		return formatError(err)
 	}
-	return protocol.FormatOK("")
+	if !c.ReadOnly {
+		if err := h.docs.checkOwner(target.history, h.session); err != nil {
+			return formatError(err)
+		}
+	}
+	return c.run(&target, bound)
+}
+
+// Documents returns the handler's documents.
+func (h *Handler) Documents() *Documents {
+	return h.docs
 }
 
-func (h *Handler) handleGetPixel(args []string) string {
-	if len(args) != 2 {
-		return invalidArgCount(2, len(args))
+// document returns the history of the document a request names with --doc,
+// or else the batch's document or the active one.
+func (h *Handler) document(args command.Args) (*history.Manager, error) {
+	if !args.Has("doc") {
+		if h.history != nil {
+			return h.history, nil
+		}
+		_, manager := h.docs.Active()
+		return manager, nil
 	}
-	x, err := parseIntArg(args[0], "x")
+	manager, err := h.docs.Get(args.String("doc"))
 	if err != nil {
-		return formatError(err)
+		return nil, err
 	}
-	y, err := parseIntArg(args[1], "y")
+	if h.history != nil && manager != h.history {
+		return nil, handlerError{Code: "invalid_args", Message: "batch operations must target the batch's document"}
+	}
+	return manager, nil
+}
+
+func (h *Handler) handleSetPixel(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	value, err := h.history.Canvas().GetPixel(x, y)
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.SetPixel(args.Int("x"), args.Int("y"), value)
+	})
+}
+
+func (h *Handler) handleGetPixel(args command.Args) string {
+	var (
+		value color.RGBA
+		err   error
+	)
+	x, y := args.Int("x"), args.Int("y")
+	if args.Has("layer") {
+		value, err = h.history.Canvas().GetLayerPixel(args.String("layer"), x, y)
+	} else {
+		value, err = h.history.Canvas().GetPixel(x, y)
+	}
 	if err != nil {
 		return formatError(err)
 	}
 	return protocol.FormatOK(pxcolor.Format(value))
 }
 
-func (h *Handler) handleFillRect(args []string) string {
-	if len(args) != 5 {
-		return invalidArgCount(5, len(args))
+func (h *Handler) handleFillRect(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
+	if err != nil {
+		return formatError(err)
 	}
-	x, err := parseIntArg(args[0], "x")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.FillRect(args.Int("x"), args.Int("y"), args.Int("w"), args.Int("h"), value)
+	})
+}
+
+func (h *Handler) handleLine(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	y, err := parseIntArg(args[1], "y")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Line(args.Int("x1"), args.Int("y1"), args.Int("x2"), args.Int("y2"), value)
+	})
+}
+
+func (h *Handler) handleRect(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	w, err := parseIntArg(args[2], "w")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Rect(args.Int("x"), args.Int("y"), args.Int("w"), args.Int("h"), value)
+	})
+}
+
+func (h *Handler) handlePolyline(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	hgt, err := parseIntArg(args[3], "h")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Polyline(args.Points("points"), value)
+	})
+}
+
+func (h *Handler) handlePolygon(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	value, err := pxcolor.Parse(args[4])
+	rule, err := canvas.ParseFillRule(args.String("rule"))
 	if err != nil {
 		return formatError(err)
 	}
-	if err := h.history.Apply(func(c *canvas.Canvas) error {
-		return c.FillRect(x, y, w, hgt, value)
-	}); err != nil {
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Polygon(args.Points("points"), value, args.Bool("fill"), rule)
+	})
+}
+
+func (h *Handler) handleFill(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
+	if err != nil {
 		return formatError(err)
 	}
-	return protocol.FormatOK("")
+	fillOpts := canvas.DefaultFillOptions()
+	if args.Has("tolerance") {
+		fillOpts.Tolerance = args.Int("tolerance")
+	}
+	fillOpts.Connectivity = args.Int("connectivity")
+	fillOpts.Global = args.Bool("global")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.FloodFill(args.Int("x"), args.Int("y"), value, fillOpts)
+	})
 }
 
-func (h *Handler) handleLine(args []string) string {
-	if len(args) != 5 {
-		return invalidArgCount(5, len(args))
+func (h *Handler) handleCircle(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
+	if err != nil {
+		return formatError(err)
 	}
-	x1, err := parseIntArg(args[0], "x1")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Circle(args.Int("cx"), args.Int("cy"), args.Int("r"), value, args.Bool("fill"))
+	})
+}
+
+func (h *Handler) handleEllipse(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	y1, err := parseIntArg(args[1], "y1")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Ellipse(args.Int("cx"), args.Int("cy"), args.Int("rx"), args.Int("ry"), value, args.Bool("fill"))
+	})
+}
+
+func (h *Handler) handleArc(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	x2, err := parseIntArg(args[2], "x2")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Arc(args.Int("cx"), args.Int("cy"), args.Int("r"), args.Int("start"), args.Int("end"), value, args.Bool("fill"))
+	})
+}
+
+func (h *Handler) handleClear(args command.Args) string {
+	value, swatch, err := h.parseColor(args.String("color"))
 	if err != nil {
 		return formatError(err)
 	}
-	y2, err := parseIntArg(args[3], "y2")
+	return h.applyPaint(swatch, func(c *canvas.Canvas) error {
+		return c.Clear(value)
+	})
+}
+
+func (h *Handler) handleExport(args command.Args) string {
+	path := args.String("filename")
+	target := h.history.Canvas()
+
+	if args.Has("sheet") {
+		rows, cols := args.Size("sheet")
+		atlasPath, err := target.ExportSheet(path, rows, cols)
+		if err != nil {
+			return formatError(err)
+		}
+		return protocol.FormatOK(atlasPath)
+	}
+
+	var err error
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".gif"):
+		err = target.ExportGIF(path)
+	case args.Has("layer"):
+		err = target.ExportLayerPNG(args.String("layer"), path)
+	default:
+		err = target.ExportPNG(path)
+	}
 	if err != nil {
 		return formatError(err)
 	}
-	value, err := pxcolor.Parse(args[4])
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handleImport(args command.Args) string {
+	img, err := canvas.ReadPNG(args.String("filename"))
 	if err != nil {
 		return formatError(err)
 	}
-	if err := h.history.Apply(func(c *canvas.Canvas) error {
-		return c.Line(x1, y1, x2, y2, value)
-	}); err != nil {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.Paste(img, args.Int("x"), args.Int("y"))
+	})
+}
+
+func (h *Handler) handleSave(args command.Args) string {
+	if err := h.saveProject(h.history, args.String("filename"), args.Bool("history")); err != nil {
 		return formatError(err)
 	}
 	return protocol.FormatOK("")
 }
 
-func (h *Handler) handleClear(args []string) string {
-	if len(args) > 1 {
-		return invalidArgCount(1, len(args))
+// saveProject writes a document and the palette to a project file, with the
+// document's undo and redo stacks if withHistory is set.
+func (h *Handler) saveProject(manager *history.Manager, path string, withHistory bool) error {
+	current, undo, redo := manager.Export()
+	p := project.Project{
+		Metadata: map[string]string{
+			"generator": "pxcli " + buildinfo.Version,
+			"saved_at":  time.Now().UTC().Format(time.RFC3339),
+		},
+		Document: current.Document(),
+		Palette:  &project.Palette{Swatches: slices.Clone(h.documentSwatches(manager)), Lock: h.palette.lock},
+	}
+	if withHistory {
+		p.Undo = snapshotDocuments(undo)
+		p.Redo = snapshotDocuments(redo)
+	}
+	return project.SaveFile(path, p)
+}
+
+func (h *Handler) handleOpen(args command.Args) string {
+	p, err := project.LoadFile(args.String("filename"))
+	if err != nil {
+		return formatError(err)
 	}
-	value := canvasTransparent()
-	if len(args) == 1 {
-		parsed, err := pxcolor.Parse(args[0])
-		if err != nil {
+	// A saved palette replaces the document's. An indexed document maps the
+	// project's colors back to indices against it, appending colors it lacks.
+	swatches := h.documentSwatches(h.history)
+	if p.Palette != nil {
+		if err := checkLock(p.Palette.Lock); err != nil {
 			return formatError(err)
 		}
-		value = parsed
+		swatches = p.Palette.Swatches.Sanitize()
+	}
+	if err := h.loadProject(p, swatches); err != nil {
+		return formatError(err)
+	}
+	if p.Palette != nil {
+		h.palette.lock = p.Palette.Lock
+		if !h.history.Canvas().Indexed() {
+			h.palette.swatches = swatches
+		}
+	}
+	return protocol.FormatOK(fmt.Sprintf("%dx%d", p.Document.Width, p.Document.Height))
+}
+
+// loadProject replaces the request's document and history with a project's,
+// converted against swatches if the document is indexed.
+func (h *Handler) loadProject(p project.Project, swatches pxcolor.Palette) error {
+	indexed := h.history.Canvas().Indexed()
+	current, err := documentSnapshot(p.Document, indexed, swatches)
+	if err != nil {
+		return err
 	}
+	undo, err := documentSnapshots(p.Undo, indexed, swatches)
+	if err != nil {
+		return err
+	}
+	redo, err := documentSnapshots(p.Redo, indexed, swatches)
+	if err != nil {
+		return err
+	}
+	return h.history.Load(current, undo, redo)
+}
+
+func snapshotDocuments(snapshots []canvas.Snapshot) []canvas.Document {
+	docs := make([]canvas.Document, len(snapshots))
+	for i, snapshot := range snapshots {
+		docs[i] = snapshot.Document()
+	}
+	return docs
+}
+
+func documentSnapshots(docs []canvas.Document, indexed bool, swatches pxcolor.Palette) ([]canvas.Snapshot, error) {
+	snapshots := make([]canvas.Snapshot, len(docs))
+	for i, doc := range docs {
+		snapshot, err := documentSnapshot(doc, indexed, swatches)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i] = snapshot
+	}
+	return snapshots, nil
+}
+
+// documentSnapshot converts a project document to a snapshot, in indexed
+// form against swatches if indexed is set.
+func documentSnapshot(doc canvas.Document, indexed bool, swatches pxcolor.Palette) (canvas.Snapshot, error) {
+	snapshot, err := doc.Snapshot()
+	if err != nil || !indexed {
+		return snapshot, err
+	}
+	return snapshot.Indexed(swatches)
+}
+
+func (h *Handler) handleFrameList(command.Args) string {
+	return protocol.FormatOK(formatFrames(h.history.Canvas().Frames()))
+}
+
+func (h *Handler) handleFrameSelect(args command.Args) string {
+	if err := h.history.Canvas().SelectFrame(args.Int("index")); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handleFrameAdd(command.Args) string {
+	var added int
 	if err := h.history.Apply(func(c *canvas.Canvas) error {
-		c.Clear(value)
+		added = c.AddFrame()
 		return nil
 	}); err != nil {
 		return formatError(err)
 	}
-	return protocol.FormatOK("")
+	return protocol.FormatOK(strconv.Itoa(added))
+}
+
+func (h *Handler) handleFrameDup(args command.Args) string {
+	var added int
+	if err := h.history.Apply(func(c *canvas.Canvas) error {
+		var err error
+		added, err = c.DuplicateFrame(args.Int("index"))
+		return err
+	}); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK(strconv.Itoa(added))
+}
+
+func (h *Handler) handleFrameDelete(args command.Args) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.DeleteFrame(args.Int("index"))
+	})
+}
+
+func (h *Handler) handleFrameMove(args command.Args) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.MoveFrame(args.Int("index"), args.Int("to"))
+	})
 }
 
-func (h *Handler) handleExport(args []string) string {
-	if len(args) != 1 {
-		return invalidArgCount(1, len(args))
+func (h *Handler) handleFrameDuration(args command.Args) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.SetFrameDuration(args.Int("index"), args.Int("ms"))
+	})
+}
+
+// formatFrames renders the timeline as "; "-separated records.
+func formatFrames(frames []canvas.FrameInfo) string {
+	records := make([]string, len(frames))
+	for i, f := range frames {
+		records[i] = fmt.Sprintf("%d duration=%d active=%t", f.Index, f.Duration, f.Active)
 	}
-	if err := h.history.Canvas().ExportPNG(args[0]); err != nil {
+	return strings.Join(records, "; ")
+}
+
+func (h *Handler) handleLayerList(command.Args) string {
+	return protocol.FormatOK(formatLayers(h.history.Canvas().Layers()))
+}
+
+func (h *Handler) handleLayerSelect(args command.Args) string {
+	if err := h.history.Canvas().SelectLayer(args.String("layer")); err != nil {
 		return formatError(err)
 	}
 	return protocol.FormatOK("")
 }
 
-func (h *Handler) handleUndo(args []string) string {
-	if len(args) != 0 {
-		return invalidArgCount(0, len(args))
+func (h *Handler) handleLayerAdd(args command.Args) string {
+	var added canvas.LayerInfo
+	if err := h.history.Apply(func(c *canvas.Canvas) error {
+		var err error
+		added, err = c.AddLayer(args.String("name"))
+		return err
+	}); err != nil {
+		return formatError(err)
 	}
-	if err := h.history.Undo(); err != nil {
+	return protocol.FormatOK(protocol.QuoteArg(added.Name))
+}
+
+func (h *Handler) handleLayerMove(args command.Args) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.MoveLayer(args.String("layer"), args.Int("index"))
+	})
+}
+
+func (h *Handler) handleLayerRename(args command.Args) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.RenameLayer(args.String("layer"), args.String("name"))
+	})
+}
+
+func (h *Handler) handleLayerOpacity(args command.Args) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.SetLayerOpacity(args.String("layer"), args.Int("opacity"))
+	})
+}
+
+func (h *Handler) handleLayerBlend(args command.Args) string {
+	mode, err := canvas.ParseBlendMode(args.String("mode"))
+	if err != nil {
+		return formatError(err)
+	}
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.SetLayerBlend(args.String("layer"), mode)
+	})
+}
+
+func (h *Handler) applyCanvas(mutate func(*canvas.Canvas) error) string {
+	if err := h.history.Apply(mutate); err != nil {
 		return formatError(err)
 	}
 	return protocol.FormatOK("")
 }
 
-func (h *Handler) handleRedo(args []string) string {
-	if len(args) != 0 {
-		return invalidArgCount(0, len(args))
+// applyPaint is applyCanvas for drawing with a color from parseColor, keeping
+// indexed writes on the swatch the color named.
+func (h *Handler) applyPaint(swatch int, paint func(*canvas.Canvas) error) string {
+	return h.applyCanvas(func(c *canvas.Canvas) error {
+		return c.PaintWithSwatch(swatch, func() error { return paint(c) })
+	})
+}
+
+// formatLayers renders layers bottom to top as "; "-separated records.
+func formatLayers(layers []canvas.LayerInfo) string {
+	records := make([]string, len(layers))
+	for i, l := range layers {
+		records[i] = fmt.Sprintf("%d %s visible=%t opacity=%d locked=%t blend=%s active=%t",
+			l.Index, protocol.QuoteArg(l.Name), l.Visible, l.Opacity, l.Locked, l.Blend, l.Active)
+	}
+	return strings.Join(records, "; ")
+}
+
+func (h *Handler) handleUndo(command.Args) string {
+	if err := h.history.Undo(); err != nil {
+		return formatError(err)
 	}
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handleRedo(command.Args) string {
 	if err := h.history.Redo(); err != nil {
 		return formatError(err)
 	}
 	return protocol.FormatOK("")
 }
 
-func (h *Handler) handleStop(args []string) string {
-	if len(args) != 0 {
-		return invalidArgCount(0, len(args))
-	}
-	if h.onStop != nil {
-		h.onStop()
+func (h *Handler) handleTransaction(action func() error) string {
+	if err := action(); err != nil {
+		return formatError(err)
 	}
+	h.docs.setOwner(h.history, h.session)
 	return protocol.FormatOK("")
 }
 
-func parseIntArg(value, name string) (int, error) {
-	parsed, err := strconv.Atoi(value)
+var errBatchFailed = errors.New("batch operation failed")
+
+// handleBatch runs the request body's operations as one atomic undo step. The
+// response is a header followed by one result line per operation; after a
+// failure every change is rolled back and the remaining lines are skipped.
+func (h *Handler) handleBatch(args command.Args) string {
+	n := args.Int("lines")
+	if n != len(args.Body) {
+		return protocol.FormatError("invalid_args", fmt.Sprintf("batch announced %d lines, got %d", n, len(args.Body)))
+	}
+	results := make([]string, n)
+	failed := -1
+	_ = h.history.Atomic(func() error {
+		for i, line := range args.Body {
+			results[i] = h.handleBatchLine(line)
+			if strings.HasPrefix(results[i], "err ") {
+				failed = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+	if failed >= 0 {
+		for i := failed + 1; i < n; i++ {
+			results[i] = protocol.FormatError("skipped", "not executed")
+		}
+		header := protocol.FormatError("batch_failed", fmt.Sprintf("line %d failed; batch rolled back", failed+1))
+		return protocol.FormatBatch(header, results)
+	}
+	return protocol.FormatBatch(protocol.FormatOK(strconv.Itoa(n)), results)
+}
+
+func (h *Handler) handleBatchLine(line string) string {
+	request, err := protocol.ParseLine(line)
 	if err != nil {
-		return 0, handlerError{Code: "invalid_args", Message: fmt.Sprintf("%s must be an integer", name)}
+		return formatError(err)
 	}
-	return parsed, nil
+	c, _, err := lookupCommand(h.commands, request)
+	if err == nil && c.NotInBatch {
+		return protocol.FormatError("invalid_command", fmt.Sprintf("%s is not allowed in a batch", request.Command))
+	}
+	return h.handle(request)
 }
 
-func invalidArgCount(expected, got int) string {
-	return protocol.FormatError("invalid_args", fmt.Sprintf("expected %d args, got %d", expected, got))
+func (h *Handler) handleHistoryStats(command.Args) string {
+	return protocol.FormatOK(formatHistoryStats(h.history.Stats()))
 }
 
-func canvasTransparent() color.RGBA {
-	return color.RGBA{R: 0, G: 0, B: 0, A: 0}
+// formatHistoryStats renders history usage as space-separated key=value pairs.
+func formatHistoryStats(stats history.Stats) string {
+	return fmt.Sprintf("undo=%d redo=%d bytes=%d max_entries=%d max_bytes=%d evicted=%d",
+		stats.UndoEntries, stats.RedoEntries, stats.Bytes, stats.MaxEntries, stats.MaxBytes, stats.Evicted)
+}
+
+func (h *Handler) handleStop(command.Args) string {
+	if h.onStop != nil {
+		h.onStop()
+	}
+	return protocol.FormatOK("")
 }
 
 type handlerError struct {
@@ -253,6 +673,10 @@ func formatError(err error) string {
 	if errors.As(err, &herr) {
 		return protocol.FormatError(herr.Code, herr.Message)
 	}
+	var cmdErr command.Error
+	if errors.As(err, &cmdErr) {
+		return protocol.FormatError(cmdErr.Code, cmdErr.Message)
+	}
 	var cerr canvas.Error
 	if errors.As(err, &cerr) {
 		return protocol.FormatError(cerr.Code, cerr.Message)
@@ -265,5 +689,17 @@ func formatError(err error) string {
 	if errors.As(err, &histErr) {
 		return protocol.FormatError(histErr.Code, histErr.Message)
 	}
+	var projErr project.Error
+	if errors.As(err, &projErr) {
+		return protocol.FormatError(projErr.Code, projErr.Message)
+	}
+	var palErr palette.Error
+	if errors.As(err, &palErr) {
+		return protocol.FormatError(palErr.Code, palErr.Message)
+	}
+	var protoErr protocol.Error
+	if errors.As(err, &protoErr) {
+		return protocol.FormatError(protoErr.Code, protoErr.Message)
+	}
 	return protocol.FormatError("error", err.Error())
 }
@@ -1,7 +1,12 @@
 package daemon
 
 import (
+	"image"
 	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -74,6 +79,576 @@ func TestHandlerMutatingCommandReturnsOK(t *testing.T) {
 	}
 }
 
+func TestHandlerFillRecordsSingleUndoStep(t *testing.T) {
+	target, err := canvas.New(3, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	response := handler.Handle(protocol.Request{Command: "fill", Args: []string{"1", "1", "blue", "--connectivity=8"}})
+	if response != "ok" {
+		t.Fatalf("expected ok, got %q", response)
+	}
+	value, err := target.GetPixel(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error reading pixel: %v", err)
+	}
+	if value != (color.RGBA{R: 0, G: 0, B: 255, A: 255}) {
+		t.Fatalf("expected blue pixel, got %+v", value)
+	}
+
+	if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+		t.Fatalf("expected ok undo, got %q", response)
+	}
+	value, err = target.GetPixel(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error reading pixel: %v", err)
+	}
+	if value != (color.RGBA{}) {
+		t.Fatalf("expected transparent pixel after undo, got %+v", value)
+	}
+	if response := handler.Handle(protocol.Request{Command: "undo"}); !strings.HasPrefix(response, "err no_history ") {
+		t.Fatalf("expected no_history after single undo, got %q", response)
+	}
+}
+
+func TestHandlerFillRejectsUnknownOption(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	response := handler.Handle(protocol.Request{Command: "fill", Args: []string{"0", "0", "red", "--mode=fast"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %q", response)
+	}
+}
+
+func TestHandlerShapeCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		x, y int
+	}{
+		{name: "circle", args: []string{"4", "4", "3", "red"}, x: 7, y: 4},
+		{name: "ellipse", args: []string{"4", "4", "4", "2", "red", "--fill"}, x: 2, y: 4},
+		{name: "arc", args: []string{"4", "4", "3", "0", "90", "red"}, x: 4, y: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := canvas.New(9, 9)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			handler := NewHandler(history.New(target), nil)
+
+			response := handler.Handle(protocol.Request{Command: tt.name, Args: tt.args})
+			if response != "ok" {
+				t.Fatalf("expected ok, got %q", response)
+			}
+			value, err := target.GetPixel(tt.x, tt.y)
+			if err != nil {
+				t.Fatalf("unexpected error reading pixel: %v", err)
+			}
+			if value != (color.RGBA{R: 255, G: 0, B: 0, A: 255}) {
+				t.Fatalf("expected red pixel at (%d,%d), got %+v", tt.x, tt.y, value)
+			}
+		})
+	}
+}
+
+func TestHandlerPolygonIsSingleUndoStep(t *testing.T) {
+	target, err := canvas.New(5, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	response := handler.Handle(protocol.Request{Command: "polygon", Args: []string{"0,0", "4,0", "4,4", "0,4", "red", "--fill", "--rule=nonzero"}})
+	if response != "ok" {
+		t.Fatalf("expected ok, got %q", response)
+	}
+	value, err := target.GetPixel(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error reading pixel: %v", err)
+	}
+	if value != (color.RGBA{R: 255, G: 0, B: 0, A: 255}) {
+		t.Fatalf("expected filled interior, got %+v", value)
+	}
+
+	if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+		t.Fatalf("expected ok undo, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "undo"}); !strings.HasPrefix(response, "err no_history ") {
+		t.Fatalf("expected polygon to be a single history step, got %q", response)
+	}
+}
+
+func TestHandlerLayerCommands(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"add", "ink"}, want: "ok ink"},
+		{args: []string{"opacity", "ink", "50"}, want: "ok"},
+		{args: []string{"blend", "1", "multiply"}, want: "ok"},
+		{args: []string{"lock", "background"}, want: "ok"},
+		{
+			args: []string{"list"},
+			want: "ok 0 background visible=true opacity=100 locked=true blend=normal active=false; " +
+				"1 ink visible=true opacity=50 locked=false blend=multiply active=true",
+		},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: "layer", Args: step.args}); response != step.want {
+			t.Fatalf("layer %v: expected %q, got %q", step.args, step.want, response)
+		}
+	}
+
+	if response := handler.Handle(protocol.Request{Command: "layer", Args: []string{"select", "background"}}); response != "ok" {
+		t.Fatalf("expected ok select, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "set_pixel", Args: []string{"0", "0", "red"}}); !strings.HasPrefix(response, "err layer_locked ") {
+		t.Fatalf("expected layer_locked error, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "layer", Args: []string{"remove", "missing"}}); !strings.HasPrefix(response, "err invalid_layer ") {
+		t.Fatalf("expected invalid_layer error, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "layer", Args: []string{"flip"}}); !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %q", response)
+	}
+}
+
+func TestHandlerLayerNamesWithSpaces(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	for _, line := range []string{
+		`layer add "line art"`,
+		`set_pixel 0 0 red`,
+	} {
+		request, err := protocol.ParseLine(line)
+		if err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		if response := handler.Handle(request); !strings.HasPrefix(response, "ok") {
+			t.Fatalf("%s: unexpected response %q", line, response)
+		}
+	}
+	want := "ok 0 background visible=true opacity=100 locked=false blend=normal active=false; " +
+		`1 "line art" visible=true opacity=100 locked=false blend=normal active=true`
+	if response := handler.Handle(protocol.Request{Command: "layer", Args: []string{"list"}}); response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "get_pixel", Args: []string{"0", "0", "--layer=line art"}}); response != "ok #ff0000ff" {
+		t.Fatalf("expected layer pixel, got %q", response)
+	}
+}
+
+func TestHandlerLayerOption(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	for _, request := range []protocol.Request{
+		{Command: "set_pixel", Args: []string{"0", "0", "#0000ff"}},
+		{Command: "layer", Args: []string{"add", "top"}},
+		{Command: "layer", Args: []string{"opacity", "top", "0"}},
+		{Command: "set_pixel", Args: []string{"0", "0", "#ff0000"}},
+	} {
+		if response := handler.Handle(request); response != "ok" && response != "ok top" {
+			t.Fatalf("%s %v: unexpected response %q", request.Command, request.Args, response)
+		}
+	}
+
+	if response := handler.Handle(protocol.Request{Command: "get_pixel", Args: []string{"0", "0"}}); response != "ok #0000ffff" {
+		t.Fatalf("expected composite to skip transparent layer, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "get_pixel", Args: []string{"0", "0", "--layer=top"}}); response != "ok #ff0000ff" {
+		t.Fatalf("expected layer pixel, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "get_pixel", Args: []string{"0", "0", "--layer=nope"}}); !strings.HasPrefix(response, "err invalid_layer ") {
+		t.Fatalf("expected invalid_layer error, got %q", response)
+	}
+}
+
+func TestHandlerLayerChangesAreUndoable(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	if response := handler.Handle(protocol.Request{Command: "layer", Args: []string{"add"}}); response != "ok layer1" {
+		t.Fatalf("expected ok layer1, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "layer", Args: []string{"remove", "background"}}); response != "ok" {
+		t.Fatalf("expected ok remove, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+		t.Fatalf("expected ok undo, got %q", response)
+	}
+	if got := len(target.Layers()); got != 2 {
+		t.Fatalf("expected undo to restore removed layer, got %d layers", got)
+	}
+	if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+		t.Fatalf("expected ok undo, got %q", response)
+	}
+	if got := len(target.Layers()); got != 1 {
+		t.Fatalf("expected undo to drop added layer, got %d layers", got)
+	}
+}
+
+func TestHandlerHistoryStats(t *testing.T) {
+	target, err := canvas.New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := history.New(target)
+	manager.SetLimits(history.Limits{MaxEntries: 2})
+	handler := NewHandler(manager, nil)
+
+	for _, x := range []string{"0", "1", "2"} {
+		if response := handler.Handle(protocol.Request{Command: "set_pixel", Args: []string{x, "0", "#ff0000"}}); response != "ok" {
+			t.Fatalf("expected ok set_pixel, got %q", response)
+		}
+	}
+	if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+		t.Fatalf("expected ok undo, got %q", response)
+	}
+
+	response := handler.Handle(protocol.Request{Command: "history", Args: []string{"stats"}})
+	want := regexp.MustCompile(`^ok undo=1 redo=1 bytes=\d+ max_entries=2 max_bytes=0 evicted=1$`)
+	if !want.MatchString(response) {
+		t.Fatalf("unexpected stats response %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "history", Args: []string{"purge"}}); !strings.HasPrefix(response, "err invalid_args") {
+		t.Fatalf("expected invalid_args for unknown subcommand, got %q", response)
+	}
+}
+
+func TestHandlerBatchIsOneUndoStep(t *testing.T) {
+	target, err := canvas.New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := history.New(target)
+	handler := NewHandler(manager, nil)
+
+	response := handler.Handle(protocol.Request{
+		Command: "batch",
+		Args:    []string{"3"},
+		Body:    []string{"set_pixel 0 0 #ff0000", "fill_rect 1 1 2 2 #00ff00", "get_pixel 0 0"},
+	})
+	if want := "ok 3\nok\nok\nok #ff0000ff"; response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+	if stats := manager.Stats(); stats.UndoEntries != 1 {
+		t.Fatalf("expected one undo entry, got %+v", stats)
+	}
+	if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+		t.Fatalf("expected ok undo, got %q", response)
+	}
+	for _, p := range [][2]int{{0, 0}, {1, 1}, {2, 2}} {
+		if got, _ := target.GetPixel(p[0], p[1]); got != (color.RGBA{}) {
+			t.Fatalf("expected undo to clear %v, got %v", p, got)
+		}
+	}
+}
+
+func TestHandlerBatchRollsBackOnFailure(t *testing.T) {
+	target, err := canvas.New(4, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := history.New(target)
+	handler := NewHandler(manager, nil)
+
+	response := handler.Handle(protocol.Request{
+		Command: "batch",
+		Args:    []string{"4"},
+		Body:    []string{"set_pixel 0 0 #ff0000", "layer add ink", "set_pixel 9 9 #ff0000", "clear"},
+	})
+	want := strings.Join([]string{
+		"err batch_failed line 3 failed; batch rolled back",
+		"ok",
+		"ok ink",
+		"err out_of_bounds pixel (9,9) outside canvas",
+		"err skipped not executed",
+	}, "\n")
+	if response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+	if got, _ := target.GetPixel(0, 0); got != (color.RGBA{}) {
+		t.Fatalf("expected rollback to clear pixel, got %v", got)
+	}
+	if got := len(target.Layers()); got != 1 {
+		t.Fatalf("expected rollback to remove the added layer, got %d layers", got)
+	}
+	if stats := manager.Stats(); stats.UndoEntries != 0 {
+		t.Fatalf("expected no undo entries, got %+v", stats)
+	}
+
+	response = handler.Handle(protocol.Request{Command: "batch", Args: []string{"1"}, Body: []string{"undo"}})
+	if want := "err batch_failed line 1 failed; batch rolled back\nerr invalid_command undo is not allowed in a batch"; response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+	response = handler.Handle(protocol.Request{Command: "batch", Args: []string{"2"}, Body: []string{"clear"}})
+	if !strings.HasPrefix(response, "err invalid_args") {
+		t.Fatalf("expected invalid_args for a short body, got %q", response)
+	}
+}
+
+func TestHandlerTransactionCommands(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := history.New(target)
+	handler := NewHandler(manager, nil)
+
+	steps := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{command: "commit", want: "err no_transaction no transaction is open"},
+		{command: "begin", want: "ok"},
+		{command: "set_pixel", args: []string{"0", "0", "#ff0000"}, want: "ok"},
+		{command: "set_pixel", args: []string{"1", "1", "#ff0000"}, want: "ok"},
+		{command: "undo", want: "err in_transaction undo is not allowed while a transaction is open"},
+		{command: "commit", want: "ok"},
+		{command: "begin", want: "ok"},
+		{command: "clear", args: []string{"#0000ff"}, want: "ok"},
+		{command: "rollback", want: "ok"},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #00000000"},
+		{command: "undo", want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #00000000"},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: step.command, Args: step.args}); response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+}
+
+func TestHandlerFrameCommands(t *testing.T) {
+	target, err := canvas.New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"add"}, want: "ok 1"},
+		{args: []string{"dup", "0"}, want: "ok 1"},
+		{args: []string{"duration", "2", "250"}, want: "ok"},
+		{args: []string{"move", "2", "0"}, want: "ok"},
+		{args: []string{"delete", "2"}, want: "ok"},
+		{args: []string{"list"}, want: "ok 0 duration=250 active=false; 1 duration=100 active=true"},
+		{args: []string{"select", "5"}, want: "err invalid_frame frame index 5 out of range 0-1"},
+		{args: []string{"play"}, want: "ok"},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: "frame", Args: step.args}); response != step.want {
+			t.Fatalf("frame %v: expected %q, got %q", step.args, step.want, response)
+		}
+	}
+	if !target.Playing() {
+		t.Fatalf("expected playback to be enabled")
+	}
+
+	for range 5 {
+		if response := handler.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+			t.Fatalf("expected ok undo, got %q", response)
+		}
+	}
+	if got := len(target.Frames()); got != 1 {
+		t.Fatalf("expected undo to restore a single frame, got %d", got)
+	}
+}
+
+func TestHandlerExportSheetReturnsAtlasPath(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	target.AddFrame()
+	handler := NewHandler(history.New(target), nil)
+	path := filepath.Join(t.TempDir(), "sheet.png")
+
+	response := handler.Handle(protocol.Request{Command: "export", Args: []string{path, "--sheet=1x2"}})
+	if want := "ok " + filepath.Join(filepath.Dir(path), "sheet.json"); response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+	response = handler.Handle(protocol.Request{Command: "export", Args: []string{path, "--sheet=2"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args for malformed grid, got %q", response)
+	}
+	response = handler.Handle(protocol.Request{Command: "export", Args: []string{path + ".gif", "--layer=background"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args for layered GIF export, got %q", response)
+	}
+}
+
+func TestHandlerImportPastesAtOffset(t *testing.T) {
+	target, err := canvas.New(3, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	src := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{B: 255, A: 255})
+	path := filepath.Join(t.TempDir(), "dot.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create png: %v", err)
+	}
+	if err := png.Encode(file, src); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	_ = file.Close()
+
+	if response := handler.Handle(protocol.Request{Command: "import", Args: []string{path, "2", "1"}}); response != "ok" {
+		t.Fatalf("expected ok import, got %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "get_pixel", Args: []string{"2", "1"}}); response != "ok #0000ffff" {
+		t.Fatalf("expected imported pixel, got %q", response)
+	}
+
+	response := handler.Handle(protocol.Request{Command: "import", Args: []string{filepath.Join(t.TempDir(), "missing.png")}})
+	if !strings.HasPrefix(response, "err io ") {
+		t.Fatalf("expected io error, got %q", response)
+	}
+	response = handler.Handle(protocol.Request{Command: "import", Args: []string{path, "1"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %q", response)
+	}
+}
+
+func TestHandlerSaveAndOpenProject(t *testing.T) {
+	source, err := canvas.New(3, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saver := NewHandler(history.New(source), nil)
+	for _, request := range []protocol.Request{
+		{Command: "set_pixel", Args: []string{"2", "1", "#ff0000"}},
+		{Command: "layer", Args: []string{"add", "ink"}},
+		{Command: "set_pixel", Args: []string{"0", "0", "#00ff00"}},
+		{Command: "palette", Args: []string{"add", "#00ff00", "leaf"}},
+		{Command: "palette", Args: []string{"lock", "snap"}},
+	} {
+		if response := saver.Handle(request); !strings.HasPrefix(response, "ok") {
+			t.Fatalf("%s %v: unexpected response %q", request.Command, request.Args, response)
+		}
+	}
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.pxp")
+	full := filepath.Join(dir, "full.pxp")
+	if response := saver.Handle(protocol.Request{Command: "save", Args: []string{plain}}); response != "ok" {
+		t.Fatalf("expected ok save, got %q", response)
+	}
+	if response := saver.Handle(protocol.Request{Command: "save", Args: []string{full, "--history"}}); response != "ok" {
+		t.Fatalf("expected ok save, got %q", response)
+	}
+
+	target, err := canvas.New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opener := NewHandler(history.New(target), nil)
+
+	if response := opener.Handle(protocol.Request{Command: "open", Args: []string{plain}}); response != "ok 3x2" {
+		t.Fatalf("expected ok 3x2, got %q", response)
+	}
+	if response := opener.Handle(protocol.Request{Command: "get_pixel", Args: []string{"0", "0", "--layer=ink"}}); response != "ok #00ff00ff" {
+		t.Fatalf("expected saved layer pixel, got %q", response)
+	}
+	if response := opener.Handle(protocol.Request{Command: "undo"}); !strings.HasPrefix(response, "err no_history ") {
+		t.Fatalf("expected project without history, got %q", response)
+	}
+	if response := opener.Handle(protocol.Request{Command: "palette", Args: []string{"list"}}); response != "ok 0 leaf color=#00ff00ff" {
+		t.Fatalf("expected the saved palette, got %q", response)
+	}
+	if response := opener.Handle(protocol.Request{Command: "set_pixel", Args: []string{"1", "1", "red"}}); response != "ok" {
+		t.Fatalf("expected ok set_pixel, got %q", response)
+	}
+	if response := opener.Handle(protocol.Request{Command: "get_pixel", Args: []string{"1", "1"}}); response != "ok #00ff00ff" {
+		t.Fatalf("expected the saved snap lock to apply, got %q", response)
+	}
+
+	if response := opener.Handle(protocol.Request{Command: "open", Args: []string{full}}); response != "ok 3x2" {
+		t.Fatalf("expected ok 3x2, got %q", response)
+	}
+	for range 3 {
+		if response := opener.Handle(protocol.Request{Command: "undo"}); response != "ok" {
+			t.Fatalf("expected saved undo history, got %q", response)
+		}
+	}
+	if response := opener.Handle(protocol.Request{Command: "get_pixel", Args: []string{"2", "1"}}); response != "ok #00000000" {
+		t.Fatalf("expected fully undone canvas, got %q", response)
+	}
+
+	response := opener.Handle(protocol.Request{Command: "open", Args: []string{filepath.Join(dir, "missing.pxp")}})
+	if !strings.HasPrefix(response, "err io ") {
+		t.Fatalf("expected io error, got %q", response)
+	}
+}
+
+func TestHandlerPolylineInvalidPoint(t *testing.T) {
+	target, err := canvas.New(5, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	response := handler.Handle(protocol.Request{Command: "polyline", Args: []string{"0,0", "2", "red"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %q", response)
+	}
+	response = handler.Handle(protocol.Request{Command: "polyline", Args: []string{"0,0", "9,9", "red"}})
+	if !strings.HasPrefix(response, "err out_of_bounds ") {
+		t.Fatalf("expected out_of_bounds error, got %q", response)
+	}
+}
+
+func TestHandlerCircleInvalidRadius(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	response := handler.Handle(protocol.Request{Command: "circle", Args: []string{"0", "0", "r", "red"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %q", response)
+	}
+	response = handler.Handle(protocol.Request{Command: "circle", Args: []string{"0", "0", "-2", "red"}})
+	if !strings.HasPrefix(response, "err invalid_args ") {
+		t.Fatalf("expected invalid_args error, got %q", response)
+	}
+}
+
 func TestHandlerClearDefaultTransparent(t *testing.T) {
 	target, err := canvas.New(2, 2)
 	if err != nil {
@@ -115,3 +690,283 @@ func TestHandlerStopTriggersCallback(t *testing.T) {
 		t.Fatalf("expected stop callback to be invoked")
 	}
 }
+
+func TestHandlerDocumentCommands(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	steps := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{command: "doc", args: []string{"new", "tiles", "4x3"}, want: "ok tiles"},
+		{command: "doc", args: []string{"new", "tiles", "4x3"}, want: `err document_exists document "tiles" already exists`},
+		{command: "doc", args: []string{"new", "bad name", "4x3"}, want: `err invalid_args document name "bad name" must use letters, digits, '.', '_' and '-'`},
+		{command: "set_pixel", args: []string{"3", "2", "#ff0000"}, want: "ok"},
+		{command: "get_pixel", args: []string{"3", "2", "--doc=main"}, want: "err out_of_bounds pixel (3,2) outside canvas"},
+		{command: "set_pixel", args: []string{"0", "0", "#0000ff", "--doc=main"}, want: "ok"},
+		{command: "undo", want: "ok"},
+		{command: "get_pixel", args: []string{"3", "2"}, want: "ok #00000000"},
+		{command: "get_pixel", args: []string{"0", "0", "--doc=main"}, want: "ok #0000ffff"},
+		{command: "get_pixel", args: []string{"0", "0", "--doc=missing"}, want: `err unknown_document no document named "missing"`},
+		{command: "doc", args: []string{"list"}, want: "ok main width=2 height=2 active=false; tiles width=4 height=3 active=true"},
+		{command: "doc", args: []string{"select", "main"}, want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #0000ffff"},
+		{command: "doc", args: []string{"close", "main"}, want: "ok"},
+		{command: "doc", args: []string{"close", "tiles"}, want: "err last_document cannot close the only document"},
+		{command: "doc", args: []string{"list"}, want: "ok tiles width=4 height=3 active=true"},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: step.command, Args: step.args}); response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+}
+
+func TestHandlerBatchTargetsDocument(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+	if response := handler.Handle(protocol.Request{Command: "doc", Args: []string{"new", "tiles", "2x2"}}); response != "ok tiles" {
+		t.Fatalf("expected ok tiles, got %q", response)
+	}
+
+	response := handler.Handle(protocol.Request{
+		Command: "batch",
+		Args:    []string{"2", "--doc=main"},
+		Body:    []string{"set_pixel 0 0 #ff0000", "get_pixel 0 0 --doc=main"},
+	})
+	if want := "ok 2\nok\nok #ff0000ff"; response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+	if got, _ := target.GetPixel(0, 0); got != (color.RGBA{R: 255, A: 255}) {
+		t.Fatalf("expected the batch to draw on main, got %v", got)
+	}
+
+	response = handler.Handle(protocol.Request{Command: "batch", Args: []string{"1", "--doc=main"}, Body: []string{"clear --doc=tiles"}})
+	if want := "err batch_failed line 1 failed; batch rolled back\nerr invalid_args batch operations must target the batch's document"; response != want {
+		t.Fatalf("expected %q, got %q", want, response)
+	}
+}
+
+func TestHandlerPaletteCommands(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+	dir := t.TempDir()
+	gpl := filepath.Join(dir, "base.gpl")
+	if err := os.WriteFile(gpl, []byte("GIMP Palette\nName: base\n255 0 0\tember\n0 0 128\tdeep blue\n"), 0o644); err != nil {
+		t.Fatalf("failed to write palette: %v", err)
+	}
+	saved := filepath.Join(dir, "saved.ase")
+
+	steps := []struct {
+		command string
+		args    []string
+		body    []string
+		want    string
+	}{
+		{command: "palette", args: []string{"list"}, want: "ok"},
+		{command: "set_pixel", args: []string{"0", "0", "@0"}, want: `err invalid_swatch no palette entry "0"`},
+		{command: "palette", args: []string{"load", gpl}, want: "ok 2"},
+		{command: "palette", args: []string{"add", "#00ff00"}, want: "ok 2"},
+		{command: "palette", args: []string{"add", "@ember", "ember"}, want: `err invalid_args swatch "ember" already exists`},
+		{command: "palette", args: []string{"list"}, want: `ok 0 ember color=#ff0000ff; 1 "deep blue" color=#000080ff; 2 "" color=#00ff00ff`},
+		{command: "set_pixel", args: []string{"0", "0", "@ember"}, want: "ok"},
+		{command: "set_pixel", args: []string{"1", "0", "@deep blue"}, want: "ok"},
+		{command: "fill_rect", args: []string{"0", "1", "2", "1", "@2"}, want: "ok"},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #000080ff"},
+		{command: "set_pixel", args: []string{"1", "1", "@ember:darken(20%):mix(@deep blue, 50%)"}, want: "ok"},
+		{command: "get_pixel", args: []string{"1", "1"}, want: "ok #4d0040ff"},
+		{command: "batch", args: []string{"1"}, body: []string{"palette remove 0"}, want: "err batch_failed line 1 failed; batch rolled back\nerr invalid_command palette is not allowed in a batch"},
+		{command: "palette", args: []string{"remove", "ember"}, want: "ok"},
+		{command: "palette", args: []string{"remove", "ember"}, want: `err invalid_swatch no palette entry "ember"`},
+		{command: "palette", args: []string{"save", saved}, want: "ok"},
+		{command: "palette", args: []string{"load", saved}, want: "ok 2"},
+		{command: "palette", args: []string{"list"}, want: `ok 0 "deep blue" color=#000080ff; 1 "" color=#00ff00ff`},
+		{command: "palette", args: []string{"load", filepath.Join(dir, "base.txt")}, want: `err invalid_args unsupported palette file "base.txt": use .gpl, .hex, .pal or .ase`},
+	}
+	for _, step := range steps {
+		response := handler.Handle(protocol.Request{Command: step.command, Args: step.args, Body: step.body})
+		if response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+	if got, _ := target.GetPixel(0, 1); got != (color.RGBA{G: 255, A: 255}) {
+		t.Fatalf("expected @2 to fill with green, got %v", got)
+	}
+}
+
+func TestHandlerRamp(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	steps := []struct {
+		command string
+		args    []string
+		body    []string
+		want    string
+	}{
+		{command: "ramp", args: []string{"red", "--steps=3", "--hue-shift=0"}, want: "ok #330000ff; #ff0000ff; #ffccccff"},
+		{command: "ramp", args: []string{"red", "--steps=1"}, want: "err invalid_args steps must be between 2 and 32"},
+		{command: "ramp", args: []string{"red", "--steps=3", "--hue-shift=0", "--add=fire"},
+			want: "ok #330000ff index=0 name=fire-1; #ff0000ff index=1 name=fire-2; #ffccccff index=2 name=fire-3"},
+		{command: "ramp", args: []string{"@fire-2", "--steps=3", "--hue-shift=0"}, want: "ok #330000ff; #ff0000ff; #ffccccff"},
+		{command: "ramp", args: []string{"blue", "--add=fire"}, want: `err invalid_args swatch "fire-1" already exists`},
+		{command: "palette", args: []string{"list"}, want: `ok 0 fire-1 color=#330000ff; 1 fire-2 color=#ff0000ff; 2 fire-3 color=#ffccccff`},
+		{command: "batch", args: []string{"1"}, body: []string{"ramp red"}, want: "err batch_failed line 1 failed; batch rolled back\nerr invalid_command ramp is not allowed in a batch"},
+	}
+	for _, step := range steps {
+		response := handler.Handle(protocol.Request{Command: step.command, Args: step.args, Body: step.body})
+		if response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+}
+
+func TestHandlerPaletteLock(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+
+	steps := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{command: "palette", args: []string{"lock", "strict"}, want: "ok"},
+		{command: "set_pixel", args: []string{"0", "0", "red"}, want: "err off_palette color #ff0000ff is not in the palette"},
+		{command: "palette", args: []string{"add", "#800000", "maroon"}, want: "ok 0"},
+		{command: "palette", args: []string{"add", "#c0c0c0"}, want: "ok 1"},
+		{command: "set_pixel", args: []string{"0", "0", "#800000"}, want: "ok"},
+		{command: "set_pixel", args: []string{"0", "0", "red"}, want: "err off_palette color #ff0000ff is not in the palette"},
+		{command: "clear", want: "ok"},
+		{command: "palette", args: []string{"lock", "bold"}, want: "err invalid_args mode must be strict or snap"},
+		{command: "palette", args: []string{"lock", "snap"}, want: "ok"},
+		{command: "set_pixel", args: []string{"1", "0", "red"}, want: "ok"},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #800000ff"},
+		{command: "set_pixel", args: []string{"1", "1", "white"}, want: "ok"},
+		{command: "get_pixel", args: []string{"1", "1"}, want: "ok #c0c0c0ff"},
+		{command: "palette", args: []string{"unlock"}, want: "ok"},
+		{command: "set_pixel", args: []string{"0", "1", "red"}, want: "ok"},
+		{command: "get_pixel", args: []string{"0", "1"}, want: "ok #ff0000ff"},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: step.command, Args: step.args}); response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+}
+
+func TestHandlerIndexedDocuments(t *testing.T) {
+	target, err := canvas.NewIndexed(2, 2, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "indexed.pxp")
+	reordered := filepath.Join(dir, "reordered.hex")
+	if err := os.WriteFile(reordered, []byte("0000ff\n00ff00\n"), 0o644); err != nil {
+		t.Fatalf("failed to write palette: %v", err)
+	}
+	blues := filepath.Join(dir, "blues.hex")
+	if err := os.WriteFile(blues, []byte("0000ff\n000080\n"), 0o644); err != nil {
+		t.Fatalf("failed to write palette: %v", err)
+	}
+
+	steps := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{command: "set_pixel", args: []string{"0", "0", "red"}, want: "err off_palette color #ff0000ff is not in the palette"},
+		{command: "palette", args: []string{"add", "red", "hair"}, want: "ok 0"},
+		{command: "set_pixel", args: []string{"0", "0", "@hair"}, want: "ok"},
+		{command: "palette", args: []string{"add", "black"}, want: "ok 1"},
+		{command: "get_pixel", args: []string{"1", "1"}, want: "ok #00000000"},
+		{command: "layer", args: []string{"add", "top"}, want: "ok top"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #ff0000ff"},
+		{command: "palette", args: []string{"add", "blue"}, want: "ok 2"},
+		{command: "set_pixel", args: []string{"1", "0", "blue"}, want: "ok"},
+		{command: "palette", args: []string{"remove", "hair"}, want: "err swatch_in_use swatch 0 is used by pixels"},
+		{command: "palette", args: []string{"remove", "1"}, want: "ok"},
+		{command: "palette", args: []string{"list"}, want: `ok 0 hair color=#ff0000ff; 1 "" color=#0000ffff`},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #0000ffff"},
+		{command: "undo", want: "ok"},
+		{command: "palette", args: []string{"list"}, want: `ok 0 hair color=#ff0000ff; 1 "" color=#000000ff; 2 "" color=#0000ffff`},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #0000ffff"},
+		{command: "redo", want: "ok"},
+		{command: "palette", args: []string{"set", "hair", "#00ff00"}, want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #00ff00ff"},
+		{command: "undo", want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #ff0000ff"},
+		{command: "redo", want: "ok"},
+		{command: "save", args: []string{"--history", saved}, want: "ok"},
+		{command: "doc", args: []string{"new", "sheet", "2x2"}, want: "ok sheet"},
+		{command: "palette", args: []string{"list"}, want: `ok 0 hair color=#00ff00ff; 1 "" color=#0000ffff`},
+		{command: "palette", args: []string{"set", "0", "white"}, want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0", "--doc=main"}, want: "ok #00ff00ff"},
+		{command: "open", args: []string{saved}, want: "ok 2x2"},
+		{command: "palette", args: []string{"list"}, want: `ok 0 hair color=#00ff00ff; 1 "" color=#0000ffff`},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #00ff00ff"},
+		{command: "undo", want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #ff0000ff"},
+		{command: "redo", want: "ok"},
+		{command: "palette", args: []string{"load", reordered}, want: "ok 2"},
+		{command: "palette", args: []string{"list"}, want: `ok 0 "" color=#0000ffff; 1 "" color=#00ff00ff`},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #00ff00ff"},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #0000ffff"},
+		{command: "palette", args: []string{"load", blues}, want: "err swatch_in_use swatch 1 is used by pixels"},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: step.command, Args: step.args}); response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+	if !handler.docs.Indexed() {
+		t.Fatalf("expected the daemon to report indexed documents")
+	}
+}
+
+func TestHandlerIndexedDrawingKeepsReferencedSwatch(t *testing.T) {
+	target, err := canvas.NewIndexed(3, 1, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil)
+	steps := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{command: "palette", args: []string{"add", "red", "skin"}, want: "ok 0"},
+		{command: "palette", args: []string{"add", "red", "cape"}, want: "ok 1"},
+		{command: "set_pixel", args: []string{"0", "0", "@cape"}, want: "ok"},
+		{command: "fill_rect", args: []string{"1", "0", "1", "1", "@0"}, want: "ok"},
+		{command: "set_pixel", args: []string{"2", "0", "red"}, want: "ok"},
+		{command: "palette", args: []string{"set", "cape", "blue"}, want: "ok"},
+		{command: "get_pixel", args: []string{"0", "0"}, want: "ok #0000ffff"},
+		{command: "get_pixel", args: []string{"1", "0"}, want: "ok #ff0000ff"},
+		{command: "get_pixel", args: []string{"2", "0"}, want: "ok #ff0000ff"},
+		{command: "palette", args: []string{"remove", "cape"}, want: "err swatch_in_use swatch 1 is used by pixels"},
+	}
+	for _, step := range steps {
+		if response := handler.Handle(protocol.Request{Command: step.command, Args: step.args}); response != step.want {
+			t.Fatalf("%s %v: expected %q, got %q", step.command, step.args, step.want, response)
+		}
+	}
+}
@@ -3,9 +3,7 @@ package daemon
 import (
 	"os"
 
-	"pxcli/internal/canvas"
 	"pxcli/internal/config"
-	"pxcli/internal/history"
 )
 
 // HeadlessOptions provides overrides for headless runtime wiring.
@@ -22,13 +20,13 @@ func RunHeadless(cfg config.Config, opts HeadlessOptions) error {
 		return err
 	}
 
-	grid, err := canvas.New(cfg.CanvasWidth, cfg.CanvasHeight)
+	grid, err := newCanvas(cfg)
 	if err != nil {
 		return err
 	}
-	manager := history.New(grid)
+	manager := newHistory(grid, cfg)
 	stopper := NewStopper()
-	handler := NewHandler(manager, stopper.Stop)
+	handler := NewHandler(manager, stopper.Stop, WithSocketPath(socketPath))
 
 	server, err := NewServer(socketPath, handler)
 	if err != nil {
@@ -41,10 +39,12 @@ func RunHeadless(cfg config.Config, opts HeadlessOptions) error {
 	}
 
 	runtime, err := NewRuntime(server, RuntimeOptions{
-		PIDPath:    pidPath,
-		SocketPath: socketPath,
-		StopCh:     stopper.Done(),
-		SignalCh:   opts.SignalCh,
+		PIDPath:     pidPath,
+		SocketPath:  socketPath,
+		StopCh:      stopper.Done(),
+		SignalCh:    opts.SignalCh,
+		IdleTimeout: cfg.IdleTimeout,
+		Autosave:    autosaveFunc(handler, cfg.AutosaveDir, socketPath),
 	})
 	if err != nil {
 		_ = server.Close()
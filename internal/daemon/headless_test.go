@@ -2,6 +2,9 @@ package daemon
 
 import (
 	"bufio"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
 	"net"
 	"os"
@@ -50,6 +53,53 @@ func TestHeadlessRuntimeHandlesCommands(t *testing.T) {
 	assertPathMissing(t, socketPath)
 }
 
+func TestHeadlessRuntimeFromImage(t *testing.T) {
+	dir := testutil.TempDir(t)
+	socketPath := filepath.Join(dir, "pxcli.sock")
+	pidPath := filepath.Join(dir, "pxcli.pid")
+	imagePath := filepath.Join(dir, "hero.png")
+
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	src.SetNRGBA(2, 1, color.NRGBA{G: 255, A: 255})
+	file, err := os.Create(imagePath)
+	if err != nil {
+		t.Fatalf("failed to create png: %v", err)
+	}
+	if err := png.Encode(file, src); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	_ = file.Close()
+
+	cfg := config.New(
+		config.WithSocketPath(socketPath),
+		config.WithPIDPath(pidPath),
+		config.WithCanvasSize(8, 8),
+		config.WithInitialImage(imagePath),
+	)
+
+	done := startHeadlessRuntime(t, cfg)
+	t.Cleanup(func() {
+		if _, err := os.Stat(socketPath); err == nil {
+			_, _ = sendRequest(socketPath, "stop\n")
+		}
+	})
+
+	response := mustSendRequest(t, socketPath, "get_pixel 2 1\n")
+	if response != "ok #00ff00ff\n" {
+		t.Fatalf("expected pixel from initial image, got %q", response)
+	}
+	response = mustSendRequest(t, socketPath, "get_pixel 3 0\n")
+	if !strings.HasPrefix(response, "err out_of_bounds ") {
+		t.Fatalf("expected canvas sized to the image, got %q", response)
+	}
+
+	response = mustSendRequest(t, socketPath, "stop\n")
+	if response != "ok\n" {
+		t.Fatalf("expected ok stop response, got %q", response)
+	}
+	assertRuntimeDone(t, done)
+}
+
 func TestHeadlessRuntimeWithoutDisplay(t *testing.T) {
 	dir := testutil.TempDir(t)
 	socketPath := filepath.Join(dir, "pxcli.sock")
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"pxcli/internal/config"
+)
+
+// Instance is a running daemon found by ListInstances.
+type Instance struct {
+	Name       string
+	PID        int
+	SocketPath string
+}
+
+// ListInstances returns the live daemons, sorted by name: the default
+// instance and every named instance in dir. PID files of exited daemons are
+// skipped, and daemons on custom sockets outside dir are not found.
+func ListInstances(dir string, isAlive LivenessFunc) ([]Instance, error) {
+	if isAlive == nil {
+		isAlive = processAlive
+	}
+	names := []string{config.DefaultInstance}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".pid")
+		if ok && !entry.IsDir() && name != config.DefaultInstance && config.ValidateInstanceName(name) == nil {
+			names = append(names, name)
+		}
+	}
+
+	var instances []Instance
+	for _, name := range names {
+		socketPath := filepath.Join(dir, name+".sock")
+		if name == config.DefaultInstance {
+			socketPath = config.DefaultSocketPath
+		}
+		pid, err := readPID(config.PIDPathForSocket(socketPath))
+		if err != nil || !isAlive(pid) {
+			continue
+		}
+		instances = append(instances, Instance{Name: name, PID: pid, SocketPath: socketPath})
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+	return instances, nil
+}
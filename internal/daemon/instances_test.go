@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pxcli/internal/testutil"
+)
+
+func TestListInstancesSkipsExitedDaemons(t *testing.T) {
+	dir := testutil.TempDir(t)
+	files := map[string]string{
+		"sprite.pid": "100\n",
+		"tiles.pid":  "200\n",
+		"hero.pid":   "300\n",
+		"broken.pid": "nope\n",
+		"notes.txt":  "400\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	instances, err := ListInstances(dir, func(pid int) bool {
+		return pid == 100 || pid == 300 || pid == 400
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var named []Instance
+	for _, instance := range instances {
+		if instance.Name != "default" {
+			named = append(named, instance)
+		}
+	}
+	want := []Instance{
+		{Name: "hero", PID: 300, SocketPath: filepath.Join(dir, "hero.sock")},
+		{Name: "sprite", PID: 100, SocketPath: filepath.Join(dir, "sprite.sock")},
+	}
+	if len(named) != len(want) || named[0] != want[0] || named[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, named)
+	}
+}
+
+func TestListInstancesMissingDir(t *testing.T) {
+	if _, err := ListInstances(filepath.Join(testutil.TempDir(t), "missing"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"pxcli/internal/protocol"
+)
+
+// listResults names the leading positional fields of commands whose payload is
+// a "; "-separated list of records, keyed by the command and its first
+// argument for command groups.
+var listResults = map[string][]string{
+	"layer list":   {"index", "name"},
+	"frame list":   {"index"},
+	"doc list":     {"name"},
+	"palette list": {"index", "name"},
+	"ramp":         {"color"},
+}
+
+// stringResults lists commands whose single-value result is a name, color or
+// path, keyed like listResults. It stays a string even if it looks like a
+// number or boolean, as a layer or document named "007" or "true" may.
+var stringResults = map[string]bool{
+	"get_pixel": true,
+	"export":    true,
+	"layer add": true,
+	"doc new":   true,
+	"mode":      true,
+}
+
+// stringFields lists the record fields that hold names, colors or paths, which
+// stay strings whatever they look like.
+var stringFields = map[string]bool{
+	"name":       true,
+	"color":      true,
+	"blend":      true,
+	"document":   true,
+	"mode":       true,
+	"socket":     true,
+	"version":    true,
+	"last_error": true,
+}
+
+// toJSON converts a line-protocol response to request into a JSON response.
+func toJSON(id json.RawMessage, request protocol.Request, response string) protocol.JSONResponse {
+	lines := strings.Split(response, "\n")
+	out := toJSONLine(request, lines[0])
+	out.ID = id
+	if request.Command != "batch" || len(lines) == 1 {
+		return out
+	}
+	results := make([]protocol.JSONResponse, len(lines)-1)
+	for i, line := range lines[1:] {
+		var op protocol.Request
+		if i < len(request.Body) {
+			op, _ = protocol.ParseLine(request.Body[i])
+		}
+		results[i] = toJSONLine(op, line)
+	}
+	out.Result = mustMarshal(map[string]any{"results": results})
+	return out
+}
+
+func toJSONLine(request protocol.Request, line string) protocol.JSONResponse {
+	if rest, ok := strings.CutPrefix(line, "err "); ok {
+		code, message, _ := strings.Cut(rest, " ")
+		return protocol.JSONErrorResponse(nil, code, message)
+	}
+	payload := strings.TrimSpace(strings.TrimPrefix(line, "ok"))
+	out := protocol.JSONResponse{Status: protocol.StatusOK}
+	if result := decodeResult(request, payload); result != nil {
+		out.Result = mustMarshal(result)
+	}
+	return out
+}
+
+// decodeResult types a response payload: lists of records become arrays of
+// objects, key=value pairs become objects, and single values become numbers,
+// booleans or strings. Names, colors and paths, as listed in stringResults and
+// stringFields, are always strings.
+func decodeResult(request protocol.Request, payload string) any {
+	key := request.Command
+	if len(request.Args) > 0 {
+		key += " " + request.Args[0]
+	}
+	names, ok := listResults[key]
+	if !ok {
+		names, ok = listResults[request.Command]
+	}
+	stringResult := stringResults[key] || stringResults[request.Command]
+	if ok {
+		records := []map[string]any{}
+		if payload != "" {
+			for _, record := range splitRecords(payload) {
+				records = append(records, decodeRecord(record, names))
+			}
+		}
+		return records
+	}
+	switch {
+	case payload == "":
+		return nil
+	case request.Command == "open":
+		if w, h, ok := strings.Cut(payload, "x"); ok {
+			return map[string]any{"width": typedValue(w), "height": typedValue(h)}
+		}
+	case request.Command == "batch":
+		return nil
+	case request.Command == "capabilities" && json.Valid([]byte(payload)):
+		return json.RawMessage(payload)
+	}
+	if strings.HasPrefix(payload, `"`) {
+		if tokens, err := protocol.SplitArgs(payload); err == nil && len(tokens) == 1 {
+			return tokens[0]
+		}
+	}
+	if stringResult {
+		return payload
+	}
+	if !strings.Contains(payload, " ") && !strings.Contains(payload, "=") {
+		return typedValue(payload)
+	}
+	return decodeRecord(payload, nil)
+}
+
+func decodeRecord(record string, names []string) map[string]any {
+	fields := map[string]any{}
+	position := 0
+	tokens, err := protocol.SplitArgs(record)
+	if err != nil {
+		tokens = strings.Fields(record)
+	}
+	for _, token := range tokens {
+		if key, value, ok := strings.Cut(token, "="); ok {
+			fields[key] = fieldValue(key, value)
+			continue
+		}
+		name := "value"
+		if position < len(names) {
+			name = names[position]
+		}
+		if position > 0 && position >= len(names) {
+			name += strconv.Itoa(position)
+		}
+		fields[name] = fieldValue(name, token)
+		position++
+	}
+	return fields
+}
+
+// splitRecords splits a list payload on "; " separators outside quoted names.
+func splitRecords(payload string) []string {
+	var records []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(payload); i++ {
+		switch c := payload[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(payload[i:], "; "):
+			records = append(records, payload[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	return append(records, payload[start:])
+}
+
+// fieldValue types a record field, keeping the fields in stringFields strings.
+func fieldValue(name, value string) any {
+	if stringFields[name] {
+		return value
+	}
+	return typedValue(value)
+}
+
+func typedValue(value string) any {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if b, err := strconv.ParseBool(value); err == nil && (value == "true" || value == "false") {
+		return b
+	}
+	return value
+}
+
+func mustMarshal(value any) json.RawMessage {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"testing"
+
+	"pxcli/internal/protocol"
+)
+
+func TestToJSONTypesResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		body     []string
+		response string
+		want     string
+	}{
+		{name: "empty", request: "clear", response: "ok", want: `{"status":"ok"}`},
+		{name: "color", request: "get_pixel 0 0", response: "ok #ff0000ff", want: `{"status":"ok","result":"#ff0000ff"}`},
+		{name: "integer", request: "frame add", response: "ok 2", want: `{"status":"ok","result":2}`},
+		{
+			name:     "key value pairs",
+			request:  "history stats",
+			response: "ok undo=1 redo=0 bytes=72 max_entries=10 max_bytes=0 evicted=0",
+			want:     `{"status":"ok","result":{"bytes":72,"evicted":0,"max_bytes":0,"max_entries":10,"redo":0,"undo":1}}`,
+		},
+		{
+			name:     "layer records",
+			request:  "layer list",
+			response: "ok 0 background visible=true opacity=100 locked=false blend=normal active=false; 1 ink visible=false opacity=40 locked=true blend=add active=true",
+			want: `{"status":"ok","result":[` +
+				`{"active":false,"blend":"normal","index":0,"locked":false,"name":"background","opacity":100,"visible":true},` +
+				`{"active":true,"blend":"add","index":1,"locked":true,"name":"ink","opacity":40,"visible":false}]}`,
+		},
+		{
+			name:     "quoted layer names",
+			request:  "layer list",
+			response: `ok 0 "line art" visible=true opacity=100 locked=false blend=normal active=true; 1 "a; \"b\"" visible=true opacity=100 locked=false blend=normal active=false`,
+			want: `{"status":"ok","result":[` +
+				`{"active":true,"blend":"normal","index":0,"locked":false,"name":"line art","opacity":100,"visible":true},` +
+				`{"active":false,"blend":"normal","index":1,"locked":false,"name":"a; \"b\"","opacity":100,"visible":true}]}`,
+		},
+		{
+			name:     "ramp records",
+			request:  "ramp red --steps=2 --add=fire",
+			response: `ok #330000ff index=0 name=fire-1; #ffccccff index=1 name=fire-2`,
+			want:     `{"status":"ok","result":[{"color":"#330000ff","index":0,"name":"fire-1"},{"color":"#ffccccff","index":1,"name":"fire-2"}]}`,
+		},
+		{name: "boolean-like layer name", request: "layer add true", response: "ok true", want: `{"status":"ok","result":"true"}`},
+		{name: "numeric document name", request: "doc new 2024 8x8", response: "ok 2024", want: `{"status":"ok","result":"2024"}`},
+		{name: "numeric sheet path", request: "export /tmp/7.png --sheet=1x2", response: "ok 7", want: `{"status":"ok","result":"7"}`},
+		{
+			name:     "numeric record names",
+			request:  "doc list",
+			response: "ok main width=8 height=8 active=false; 2024 width=4 height=4 active=true",
+			want:     `{"status":"ok","result":[{"active":false,"height":8,"name":"main","width":8},{"active":true,"height":4,"name":"2024","width":4}]}`,
+		},
+		{
+			name:     "name-like records",
+			request:  "palette list",
+			response: "ok 0 007 color=#000000ff; 1 true color=#ffffffff",
+			want:     `{"status":"ok","result":[{"color":"#000000ff","index":0,"name":"007"},{"color":"#ffffffff","index":1,"name":"true"}]}`,
+		},
+		{
+			name:     "boolean-like layer in list",
+			request:  "layer list",
+			response: "ok 0 true visible=true opacity=100 locked=false blend=normal active=true",
+			want:     `{"status":"ok","result":[{"active":true,"blend":"normal","index":0,"locked":false,"name":"true","opacity":100,"visible":true}]}`,
+		},
+		{name: "quoted name", request: `layer add "line art"`, response: `ok "line art"`, want: `{"status":"ok","result":"line art"}`},
+		{name: "raw object", request: "capabilities", response: `ok {"protocol":1,"mode":"headless"}`, want: `{"status":"ok","result":{"protocol":1,"mode":"headless"}}`},
+		{name: "hello", request: "hello 1", response: "ok protocol=1 version=dev", want: `{"status":"ok","result":{"protocol":1,"version":"dev"}}`},
+		{
+			name:     "status",
+			request:  "status",
+			response: `ok pid=7 uptime=3 width=8 height=4 mode=headless "socket=/tmp/a b.sock" document=main undo=0 redo=0 requests=2 last_error=`,
+			want: `{"status":"ok","result":{"document":"main","height":4,"last_error":"","mode":"headless","pid":7,` +
+				`"redo":0,"requests":2,"socket":"/tmp/a b.sock","undo":0,"uptime":3,"width":8}}`,
+		},
+		{name: "canvas size", request: "open /tmp/a.pxp", response: "ok 8x4", want: `{"status":"ok","result":{"height":4,"width":8}}`},
+		{
+			name:     "error",
+			request:  "set_pixel 9 9 red",
+			response: "err out_of_bounds pixel (9,9) outside canvas",
+			want:     `{"status":"error","error":{"code":"out_of_bounds","message":"pixel (9,9) outside canvas"}}`,
+		},
+		{
+			name:     "failed batch",
+			request:  "batch 2",
+			body:     []string{"get_pixel 0 0", "set_pixel 9 9 red"},
+			response: "err batch_failed line 2 failed; batch rolled back\nok #00000000\nerr out_of_bounds pixel (9,9) outside canvas",
+			want: `{"status":"error","result":{"results":[{"status":"ok","result":"#00000000"},` +
+				`{"status":"error","error":{"code":"out_of_bounds","message":"pixel (9,9) outside canvas"}}]},` +
+				`"error":{"code":"batch_failed","message":"line 2 failed; batch rolled back"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := protocol.ParseLine(tt.request)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			request.Body = tt.body
+			if got := protocol.FormatJSON(toJSON(nil, request, tt.response)); got != tt.want {
+				t.Fatalf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
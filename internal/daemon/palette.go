@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pxcli/internal/canvas"
+	pxcolor "pxcli/internal/color"
+	"pxcli/internal/command"
+	"pxcli/internal/history"
+	"pxcli/internal/palette"
+	"pxcli/internal/protocol"
+)
+
+// Palette lock modes.
+const (
+	lockStrict = "strict"
+	lockSnap   = "snap"
+)
+
+// paletteState is the daemon's palette and how it constrains drawing colors.
+// The lock applies to every document; the swatches are shared by documents
+// that store colors, while indexed documents each carry their own.
+type paletteState struct {
+	swatches pxcolor.Palette
+	// lock is lockStrict, lockSnap, or empty when drawing colors are free.
+	lock string
+}
+
+// swatches returns the palette a request resolves colors against: that of
+// the document it acts on, or of the active document for daemon-wide
+// commands.
+func (h *Handler) swatches() pxcolor.Palette {
+	manager := h.history
+	if manager == nil {
+		_, manager = h.docs.Active()
+	}
+	return h.documentSwatches(manager)
+}
+
+// documentSwatches returns the palette of manager's document: its own when
+// indexed, and otherwise the daemon's.
+func (h *Handler) documentSwatches(manager *history.Manager) pxcolor.Palette {
+	if target := manager.Canvas(); target.Indexed() {
+		return target.Palette()
+	}
+	return h.palette.swatches
+}
+
+// setSwatches replaces the palette. On an indexed active document this is an
+// undoable edit of that document alone, and its pixels move to new indices as
+// mapping says; see canvas.SetPalette.
+func (h *Handler) setSwatches(swatches pxcolor.Palette, mapping []int) error {
+	_, manager := h.docs.Active()
+	if !manager.Canvas().Indexed() {
+		h.palette.swatches = swatches
+		return nil
+	}
+	if err := h.docs.checkOwner(manager, h.session); err != nil {
+		return err
+	}
+	return manager.Apply(func(c *canvas.Canvas) error {
+		return c.SetPalette(swatches, mapping)
+	})
+}
+
+// checkLock validates a palette lock mode read from a project file.
+func checkLock(lock string) error {
+	switch lock {
+	case "", lockStrict, lockSnap:
+		return nil
+	}
+	return handlerError{Code: "invalid_project", Message: fmt.Sprintf("unknown palette lock mode %q", lock)}
+}
+
+// parseColor parses the color argument of a drawing command, resolving "@"
+// references against the palette. A locked palette rejects or snaps colors
+// that are not swatches; fully transparent colors always pass, so erasing
+// still works. It also returns the swatch a plain "@" reference or a snap
+// picked, or -1, for applyPaint.
+func (h *Handler) parseColor(value string) (color.RGBA, int, error) {
+	swatches := h.swatches()
+	c, err := pxcolor.ParseWith(value, swatches)
+	if err != nil {
+		return c, -1, err
+	}
+	swatch := -1
+	if ref, ok := strings.CutPrefix(strings.TrimSpace(value), "@"); ok {
+		if index, err := swatches.Resolve(ref); err == nil {
+			swatch = index
+		}
+	}
+	if h.palette.lock == "" || c.A == 0 || swatches.Index(c) >= 0 {
+		return c, swatch, nil
+	}
+	if h.palette.lock == lockSnap {
+		if index := swatches.Nearest(c); index >= 0 {
+			return swatches[index].Color, index, nil
+		}
+	}
+	return c, -1, handlerError{Code: "off_palette", Message: fmt.Sprintf("color %s is not in the palette", pxcolor.Format(c))}
+}
+
+func (h *Handler) handlePaletteList(command.Args) string {
+	return protocol.FormatOK(formatPalette(h.swatches()))
+}
+
+// handlePaletteLoad replaces the palette with a swatch file's. Pixels of an
+// indexed document move to the first new swatch with their color.
+func (h *Handler) handlePaletteLoad(args command.Args) string {
+	loaded, err := palette.LoadFile(args.String("filename"))
+	if err != nil {
+		return formatError(err)
+	}
+	current := h.swatches()
+	mapping := make([]int, len(current))
+	for i, swatch := range current {
+		mapping[i] = loaded.Index(swatch.Color)
+	}
+	if err := h.setSwatches(loaded, mapping); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK(strconv.Itoa(len(loaded)))
+}
+
+func (h *Handler) handlePaletteAdd(args command.Args) string {
+	swatches := h.swatches()
+	value, err := pxcolor.ParseWith(args.String("color"), swatches)
+	if err != nil {
+		return formatError(err)
+	}
+	swatches = slices.Clone(swatches)
+	index, err := swatches.Add(args.String("name"), value)
+	if err != nil {
+		return formatError(err)
+	}
+	if err := h.setSwatches(swatches, nil); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK(strconv.Itoa(index))
+}
+
+// handlePaletteRemove deletes a swatch. Pixels of an indexed document using
+// later swatches follow them down one index, and a swatch still in use there
+// cannot be removed.
+func (h *Handler) handlePaletteRemove(args command.Args) string {
+	swatches := slices.Clone(h.swatches())
+	removed, err := swatches.Resolve(args.String("swatch"))
+	if err != nil {
+		return formatError(err)
+	}
+	if err := swatches.Remove(args.String("swatch")); err != nil {
+		return formatError(err)
+	}
+	mapping := make([]int, len(swatches)+1)
+	for i := range mapping {
+		switch {
+		case i < removed:
+			mapping[i] = i
+		case i == removed:
+			mapping[i] = -1
+		default:
+			mapping[i] = i - 1
+		}
+	}
+	if err := h.setSwatches(swatches, mapping); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handlePaletteSet(args command.Args) string {
+	swatches := slices.Clone(h.swatches())
+	index, err := swatches.Resolve(args.String("swatch"))
+	if err != nil {
+		return formatError(err)
+	}
+	value, err := pxcolor.ParseWith(args.String("color"), swatches)
+	if err != nil {
+		return formatError(err)
+	}
+	swatches[index].Color = value
+	if err := h.setSwatches(swatches, nil); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handlePaletteSave(args command.Args) string {
+	if err := palette.SaveFile(args.String("filename"), h.swatches()); err != nil {
+		return formatError(err)
+	}
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handlePaletteLock(args command.Args) string {
+	h.palette.lock = strings.ToLower(args.String("mode"))
+	return protocol.FormatOK("")
+}
+
+func (h *Handler) handlePaletteUnlock(command.Args) string {
+	h.palette.lock = ""
+	return protocol.FormatOK("")
+}
+
+// handleRamp answers the ramp's colors as "; "-separated records, darkest
+// first. With --add the colors are also appended to the palette, all or none,
+// and each record carries the new swatch's index and name.
+func (h *Handler) handleRamp(args command.Args) string {
+	base, err := pxcolor.ParseWith(args.String("base"), h.swatches())
+	if err != nil {
+		return formatError(err)
+	}
+	ramp := pxcolor.Ramp(base, pxcolor.RampOptions{
+		Steps:    args.Int("steps"),
+		HueShift: float64(args.Int("hue-shift")),
+		SatCurve: float64(args.Int("sat-curve")),
+	})
+	records := make([]string, len(ramp))
+	for i, value := range ramp {
+		records[i] = pxcolor.Format(value)
+	}
+	if args.Has("add") {
+		swatches := slices.Clone(h.swatches())
+		for i, value := range ramp {
+			name := fmt.Sprintf("%s-%d", args.String("add"), i+1)
+			index, err := swatches.Add(name, value)
+			if err != nil {
+				return formatError(err)
+			}
+			records[i] += fmt.Sprintf(" index=%d name=%s", index, protocol.QuoteArg(name))
+		}
+		if err := h.setSwatches(swatches, nil); err != nil {
+			return formatError(err)
+		}
+	}
+	return protocol.FormatOK(strings.Join(records, "; "))
+}
+
+// formatPalette renders swatches as "; "-separated "<index> <name> color=<hex>"
+// records; unnamed swatches have an empty quoted name.
+func formatPalette(p pxcolor.Palette) string {
+	records := make([]string, len(p))
+	for i, swatch := range p {
+		records[i] = fmt.Sprintf("%d %s color=%s", i, protocol.QuoteArg(swatch.Name), pxcolor.Format(swatch.Color))
+	}
+	return strings.Join(records, "; ")
+}
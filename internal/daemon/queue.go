@@ -0,0 +1,75 @@
+package daemon
+
+import "pxcli/internal/protocol"
+
+// commandQueue runs requests from every connection one at a time, in arrival
+// order. Each connection waits for its response before sending another
+// request, so a connection streaming requests rejoins the back of the queue
+// after each one and cannot starve the others.
+type commandQueue struct {
+	handler RequestHandler
+	jobs    chan queuedRequest
+	done    chan struct{}
+}
+
+type queuedRequest struct {
+	session uint64
+	request protocol.Request
+	// end asks to release the session's state instead of handling a request.
+	end   bool
+	reply chan string
+}
+
+func newCommandQueue(handler RequestHandler) *commandQueue {
+	q := &commandQueue{
+		handler: handler,
+		jobs:    make(chan queuedRequest),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *commandQueue) run() {
+	defer close(q.done)
+	for job := range q.jobs {
+		job.reply <- q.handle(job)
+	}
+}
+
+// handle runs a job, telling sessions apart if the handler can.
+func (q *commandQueue) handle(job queuedRequest) string {
+	handler, ok := q.handler.(SessionHandler)
+	switch {
+	case job.end && ok:
+		handler.EndSession(job.session)
+		return ""
+	case job.end:
+		return ""
+	case ok:
+		return handler.HandleSession(job.session, job.request)
+	default:
+		return q.handler.Handle(job.request)
+	}
+}
+
+// submit queues a request from a session and waits for its response.
+func (q *commandQueue) submit(session uint64, request protocol.Request) string {
+	reply := make(chan string, 1)
+	q.jobs <- queuedRequest{session: session, request: request, reply: reply}
+	return <-reply
+}
+
+// end queues the release of a closed session's state, after the requests
+// queued before it.
+func (q *commandQueue) end(session uint64) {
+	reply := make(chan string, 1)
+	q.jobs <- queuedRequest{session: session, end: true, reply: reply}
+	<-reply
+}
+
+// stop ends the queue once no more requests can be submitted.
+func (q *commandQueue) stop() {
+	close(q.jobs)
+	<-q.done
+}
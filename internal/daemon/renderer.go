@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"time"
 
 	"pxcli/internal/canvas"
 )
@@ -20,6 +21,8 @@ type RenderSource interface {
 	RenderSnapshot() canvas.RenderSnapshot
 	Width() int
 	Height() int
+	// Title is the window title, naming what is shown.
+	Title() string
 }
 
 // RendererOptions holds future renderer configuration.
@@ -42,3 +45,22 @@ func ValidateRenderer(headless bool) error {
 	}
 	return RendererUnavailableError()
 }
+
+// playbackFrame returns the index of the looping animation frame shown after elapsed.
+func playbackFrame(frames []canvas.RenderFrame, elapsed time.Duration) int {
+	var total time.Duration
+	for _, frame := range frames {
+		total += frame.Duration
+	}
+	if total <= 0 {
+		return 0
+	}
+	elapsed %= total
+	for i, frame := range frames {
+		if elapsed < frame.Duration {
+			return i
+		}
+		elapsed -= frame.Duration
+	}
+	return len(frames) - 1
+}
@@ -7,8 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+
+	"pxcli/internal/canvas"
 )
 
 var errRendererClosed = errors.New("renderer closed")
@@ -52,7 +55,7 @@ func (r *EbitenRenderer) Run(ctx context.Context) error {
 
 	width, height := r.source.Width(), r.source.Height()
 	windowW, windowH := scaledWindowSize(width, height, r.scale)
-	ebiten.SetWindowTitle("pxcli")
+	ebiten.SetWindowTitle(r.source.Title())
 	ebiten.SetWindowSize(windowW, windowH)
 	ebiten.SetWindowResizable(false)
 
@@ -91,6 +94,11 @@ type renderGame struct {
 	img     *ebiten.Image
 	closeCh <-chan struct{}
 	ctx     context.Context
+
+	// Animation playback state; frames is empty when playback is off.
+	frames    []canvas.RenderFrame
+	playStart time.Time
+	shown     int
 }
 
 func (g *renderGame) Update() error {
@@ -109,13 +117,30 @@ func (g *renderGame) Update() error {
 	}
 
 	if g.img == nil || g.source.Dirty() {
+		// Selecting another document changes the title and usually the size.
+		ebiten.SetWindowTitle(g.source.Title())
 		snapshot := g.source.RenderSnapshot()
-		if g.img == nil {
+		if g.img == nil || snapshot.Width != g.width || snapshot.Height != g.height {
+			// Opening a project or switching documents can change the size.
 			g.img = ebiten.NewImage(snapshot.Width, snapshot.Height)
 			g.width = snapshot.Width
 			g.height = snapshot.Height
+			ebiten.SetWindowSize(scaledWindowSize(g.width, g.height, g.scale))
+		}
+		if len(g.frames) == 0 {
+			g.playStart = time.Now()
+		}
+		g.frames = snapshot.Frames
+		g.shown = -1
+		if len(g.frames) == 0 {
+			g.img.ReplacePixels(snapshot.Pixels)
+		}
+	}
+	if len(g.frames) > 0 {
+		if idx := playbackFrame(g.frames, time.Since(g.playStart)); idx != g.shown {
+			g.img.ReplacePixels(g.frames[idx].Pixels)
+			g.shown = idx
 		}
-		g.img.ReplacePixels(snapshot.Pixels)
 	}
 	return nil
 }
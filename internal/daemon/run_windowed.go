@@ -5,9 +5,7 @@ import (
 	"errors"
 	"os"
 
-	"pxcli/internal/canvas"
 	"pxcli/internal/config"
-	"pxcli/internal/history"
 )
 
 type rendererFactory func(source RenderSource, scale int) (Renderer, error)
@@ -24,22 +22,24 @@ func runWindowed(cfg config.Config, opts WindowedOptions, factory rendererFactor
 		return err
 	}
 
-	grid, err := canvas.New(cfg.CanvasWidth, cfg.CanvasHeight)
+	grid, err := newCanvas(cfg)
 	if err != nil {
 		return err
 	}
-	manager := history.New(grid)
+	manager := newHistory(grid, cfg)
 	stopper := NewStopper()
 
-	renderer, err := factory(grid, cfg.Scale)
-	if err != nil {
-		return err
-	}
-
+	var renderer Renderer
 	handler := NewHandler(manager, func() {
 		stopper.Stop()
 		renderer.RequestClose()
-	})
+	}, WithWindowed(), WithScale(cfg.Scale), WithSocketPath(socketPath))
+
+	// The window shows whichever document is active.
+	renderer, err = factory(handler.Documents(), cfg.Scale)
+	if err != nil {
+		return err
+	}
 
 	server, err := NewServer(socketPath, handler)
 	if err != nil {
@@ -52,10 +52,12 @@ func runWindowed(cfg config.Config, opts WindowedOptions, factory rendererFactor
 	}
 
 	runtime, err := NewRuntime(server, RuntimeOptions{
-		PIDPath:    pidPath,
-		SocketPath: socketPath,
-		StopCh:     stopper.Done(),
-		SignalCh:   opts.SignalCh,
+		PIDPath:     pidPath,
+		SocketPath:  socketPath,
+		StopCh:      stopper.Done(),
+		SignalCh:    opts.SignalCh,
+		IdleTimeout: cfg.IdleTimeout,
+		Autosave:    autosaveFunc(handler, cfg.AutosaveDir, socketPath),
 	})
 	if err != nil {
 		_ = server.Close()
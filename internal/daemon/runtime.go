@@ -6,6 +6,11 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
+
+	"pxcli/internal/canvas"
+	"pxcli/internal/config"
+	"pxcli/internal/history"
 )
 
 // Stopper provides a reusable stop signal for coordinating shutdown.
@@ -43,16 +48,24 @@ type RuntimeOptions struct {
 	SocketPath string
 	StopCh     <-chan struct{}
 	SignalCh   <-chan os.Signal
+	// IdleTimeout stops the runtime after this long without requests; zero
+	// never does.
+	IdleTimeout time.Duration
+	// Autosave, when set, runs after the server stops on the idle timeout or
+	// a signal, but not on a stop request.
+	Autosave func() error
 }
 
 // Runtime coordinates server lifecycle, stop requests, and cleanup.
 type Runtime struct {
-	server     *Server
-	pidPath    string
-	socketPath string
-	stopCh     <-chan struct{}
-	signalCh   <-chan os.Signal
-	stopOnce   sync.Once
+	server      *Server
+	pidPath     string
+	socketPath  string
+	stopCh      <-chan struct{}
+	signalCh    <-chan os.Signal
+	idleTimeout time.Duration
+	autosave    func() error
+	stopOnce    sync.Once
 }
 
 // NewRuntime creates a runtime for the provided server.
@@ -61,15 +74,18 @@ func NewRuntime(server *Server, opts RuntimeOptions) (*Runtime, error) {
 		return nil, errors.New("server must not be nil")
 	}
 	return &Runtime{
-		server:     server,
-		pidPath:    opts.PIDPath,
-		socketPath: opts.SocketPath,
-		stopCh:     opts.StopCh,
-		signalCh:   opts.SignalCh,
+		server:      server,
+		pidPath:     opts.PIDPath,
+		socketPath:  opts.SocketPath,
+		stopCh:      opts.StopCh,
+		signalCh:    opts.SignalCh,
+		idleTimeout: opts.IdleTimeout,
+		autosave:    opts.Autosave,
 	}, nil
 }
 
-// Run blocks until the server stops, a stop request arrives, or a signal is received.
+// Run blocks until the server stops, a stop request arrives, a signal is
+// received, or no request arrives for the idle timeout.
 func (r *Runtime) Run() error {
 	if r.server == nil {
 		return errors.New("server must not be nil")
@@ -98,25 +114,55 @@ func (r *Runtime) Run() error {
 		serverDone <- r.server.Serve()
 	}()
 
-	var serveErr error
-	select {
-	case serveErr = <-serverDone:
-	case <-stopCh:
-		r.Stop()
-		serveErr = <-serverDone
-	case <-signalCh:
-		r.Stop()
-		serveErr = <-serverDone
+	var (
+		idleTimer *time.Timer
+		idleCh    <-chan time.Time
+	)
+	if r.idleTimeout > 0 {
+		idleTimer = time.NewTimer(r.idleTimeout)
+		defer idleTimer.Stop()
+		idleCh = idleTimer.C
 	}
 
-	cleanupErr := CleanupFiles(r.pidPath, r.socketPath)
-	if serveErr != nil {
-		return serveErr
+	var serveErr, autosaveErr error
+wait:
+	for {
+		select {
+		case serveErr = <-serverDone:
+			break wait
+		case <-stopCh:
+			r.Stop()
+			serveErr = <-serverDone
+			break wait
+		case <-signalCh:
+			r.Stop()
+			serveErr = <-serverDone
+			autosaveErr = r.runAutosave()
+			break wait
+		case <-idleCh:
+			// Requests that arrived since the timer was set push the deadline back.
+			if remaining := r.idleTimeout - time.Since(r.server.LastRequest()); remaining > 0 {
+				idleTimer.Reset(remaining)
+				continue
+			}
+			r.Stop()
+			serveErr = <-serverDone
+			autosaveErr = r.runAutosave()
+			break wait
+		}
 	}
-	if cleanupErr != nil {
-		return cleanupErr
+
+	cleanupErr := CleanupFiles(r.pidPath, r.socketPath)
+	return errors.Join(serveErr, autosaveErr, cleanupErr)
+}
+
+// runAutosave saves the daemon's work once the server has stopped, so no
+// request can change it meanwhile.
+func (r *Runtime) runAutosave() error {
+	if r.autosave == nil {
+		return nil
 	}
-	return nil
+	return r.autosave()
 }
 
 // Stop closes the server listener once.
@@ -128,3 +174,30 @@ func (r *Runtime) Stop() {
 		_ = r.server.Close()
 	})
 }
+
+// newCanvas creates the daemon canvas, sized to and initialized from the
+// configured initial image when there is one. An indexed canvas starts with
+// an empty palette, or with the image's colors.
+func newCanvas(cfg config.Config) (*canvas.Canvas, error) {
+	if cfg.InitialImage == "" {
+		if cfg.Indexed {
+			return canvas.NewIndexed(cfg.CanvasWidth, cfg.CanvasHeight, nil)
+		}
+		return canvas.New(cfg.CanvasWidth, cfg.CanvasHeight)
+	}
+	img, err := canvas.ReadPNG(cfg.InitialImage)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Indexed {
+		return canvas.NewIndexedFromImage(img)
+	}
+	return canvas.NewFromImage(img)
+}
+
+// newHistory creates the undo history for grid with the configured limits.
+func newHistory(grid *canvas.Canvas, cfg config.Config) *history.Manager {
+	manager := history.New(grid)
+	manager.SetLimits(history.Limits{MaxEntries: cfg.HistoryEntries, MaxBytes: cfg.HistoryBytes})
+	return manager
+}
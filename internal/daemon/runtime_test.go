@@ -106,6 +106,99 @@ func TestRuntimeSignalShutdownWithoutClients(t *testing.T) {
 	assertPathMissing(t, socketPath)
 }
 
+func TestRuntimeIdleTimeoutAutosaves(t *testing.T) {
+	dir := testutil.TempDir(t)
+	socketPath := filepath.Join(dir, "pxcli.sock")
+	pidPath := filepath.Join(dir, "pxcli.pid")
+
+	server, err := NewServer(socketPath, stubHandler{response: "ok"})
+	if err != nil {
+		t.Fatalf("unexpected server error: %v", err)
+	}
+	if err := WritePID(pidPath, os.Getpid()); err != nil {
+		t.Fatalf("unexpected pid write error: %v", err)
+	}
+
+	autosaved := make(chan struct{}, 1)
+	runtime, err := NewRuntime(server, RuntimeOptions{
+		PIDPath:     pidPath,
+		SocketPath:  socketPath,
+		SignalCh:    make(chan os.Signal),
+		IdleTimeout: 200 * time.Millisecond,
+		Autosave: func() error {
+			autosaved <- struct{}{}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected runtime error: %v", err)
+	}
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- runtime.Run()
+	}()
+
+	// A request halfway through pushes the idle deadline back.
+	waitForPath(t, socketPath)
+	time.Sleep(100 * time.Millisecond)
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected dial error: %v", err)
+	}
+	if _, err := io.WriteString(conn, "clear\n"); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	_ = conn.Close()
+	requested := time.Now()
+
+	assertRuntimeDone(t, done)
+	if idle := time.Since(requested); idle < 200*time.Millisecond {
+		t.Fatalf("expected the runtime to wait out the timeout after the last request, stopped after %v (%v since start)", idle, time.Since(started))
+	}
+	select {
+	case <-autosaved:
+	default:
+		t.Fatalf("expected an autosave before exiting")
+	}
+	assertPathMissing(t, pidPath)
+	assertPathMissing(t, socketPath)
+}
+
+func TestRuntimeStopRequestSkipsAutosave(t *testing.T) {
+	dir := testutil.TempDir(t)
+	socketPath := filepath.Join(dir, "pxcli.sock")
+
+	server, err := NewServer(socketPath, stubHandler{response: "ok"})
+	if err != nil {
+		t.Fatalf("unexpected server error: %v", err)
+	}
+	stopper := NewStopper()
+	runtime, err := NewRuntime(server, RuntimeOptions{
+		SocketPath:  socketPath,
+		StopCh:      stopper.Done(),
+		SignalCh:    make(chan os.Signal),
+		IdleTimeout: time.Hour,
+		Autosave: func() error {
+			return errors.New("autosave should not run on a stop request")
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected runtime error: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- runtime.Run()
+	}()
+
+	waitForPath(t, socketPath)
+	stopper.Stop()
+	assertRuntimeDone(t, done)
+}
+
 func assertRuntimeDone(t *testing.T, done <-chan error) {
 	t.Helper()
 	select {
@@ -2,27 +2,90 @@ package daemon
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"pxcli/internal/protocol"
 )
 
+const (
+	// DefaultIdleTimeout is how long a connection may wait between requests
+	// before the server closes it.
+	DefaultIdleTimeout = 2 * time.Minute
+	// DefaultRequestTimeout bounds how long a client may take to finish sending
+	// a request once it has started, and to accept the response.
+	DefaultRequestTimeout = 10 * time.Second
+)
+
 // RequestHandler handles a parsed protocol request.
 type RequestHandler interface {
 	Handle(request protocol.Request) string
 }
 
-// Server listens on a Unix socket and handles one request per connection.
+// SessionHandler is a RequestHandler that tells connections apart, so that
+// state such as an open transaction belongs to the connection that created it.
+type SessionHandler interface {
+	RequestHandler
+	HandleSession(session uint64, request protocol.Request) string
+	// EndSession releases the state a closed connection left behind.
+	EndSession(session uint64)
+}
+
+// Server listens on a Unix socket and serves each connection concurrently,
+// answering its requests in order until the client closes it or it stays idle
+// for the idle timeout. A batch request carries its operation lines after the
+// header line. A connection starts in line mode and may switch to JSON mode,
+// where each request and response is one JSON object per line, with a
+// "mode json" request. Requests from all connections run one at a time
+// through a first-come, first-served command queue.
 type Server struct {
-	listener net.Listener
-	handler  RequestHandler
+	listener       net.Listener
+	handler        RequestHandler
+	idleTimeout    time.Duration
+	requestTimeout time.Duration
+	queue          *commandQueue
+	// lastRequest is when the latest request arrived, in Unix nanoseconds.
+	lastRequest atomic.Int64
+	// sessions numbers connections from 1; session 0 is left to requests
+	// handled outside a connection.
+	sessions atomic.Uint64
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	active sync.WaitGroup
+}
+
+// ServerOption configures the server.
+type ServerOption func(*Server)
+
+// WithIdleTimeout overrides how long a connection may sit idle between requests.
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if timeout > 0 {
+			s.idleTimeout = timeout
+		}
+	}
+}
+
+// WithRequestTimeout overrides how long a client may take to send a started
+// request and to accept its response.
+func WithRequestTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if timeout > 0 {
+			s.requestTimeout = timeout
+		}
+	}
 }
 
 // NewServer creates a server listening on the provided Unix socket path.
-func NewServer(socketPath string, handler RequestHandler) (*Server, error) {
+func NewServer(socketPath string, handler RequestHandler, opts ...ServerOption) (*Server, error) {
 	if handler == nil {
 		return nil, errors.New("handler must not be nil")
 	}
@@ -30,11 +93,28 @@ func NewServer(socketPath string, handler RequestHandler) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Server{listener: listener, handler: handler}, nil
+	server := &Server{
+		listener:       listener,
+		handler:        handler,
+		idleTimeout:    DefaultIdleTimeout,
+		requestTimeout: DefaultRequestTimeout,
+		conns:          make(map[net.Conn]struct{}),
+	}
+	server.lastRequest.Store(time.Now().UnixNano())
+	for _, opt := range opts {
+		if opt != nil {
+			opt(server)
+		}
+	}
+	return server, nil
 }
 
-// Serve accepts connections sequentially until the listener is closed.
+// Serve accepts connections until the listener is closed, then waits for open
+// connections to finish their in-flight requests.
 func (s *Server) Serve() error {
+	s.queue = newCommandQueue(s.handler)
+	defer s.queue.stop()
+	defer s.active.Wait()
 	for {
 		conn, err := s.listener.Accept()
 		if err != nil {
@@ -43,36 +123,186 @@ func (s *Server) Serve() error {
 			}
 			return err
 		}
-		s.handleConn(conn)
+		if !s.track(conn) {
+			_ = conn.Close()
+			return nil
+		}
+		go s.handleConn(conn)
 	}
 }
 
-// Close shuts down the server listener.
+// LastRequest returns when the latest request arrived, or when the server was
+// created if none has.
+func (s *Server) LastRequest() time.Time {
+	return time.Unix(0, s.lastRequest.Load())
+}
+
+// Close shuts down the server listener. Open connections are closed once any
+// request they are handling has been answered.
 func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		// Wake connections blocked waiting for their next request.
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
 	return s.listener.Close()
 }
 
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.active.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	_ = conn.Close()
+	s.active.Done()
+}
+
+// setReadDeadline arms a read deadline, reporting false once the server is
+// closing so the connection stops reading.
+func (s *Server) setReadDeadline(conn net.Conn, timeout time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	return conn.SetReadDeadline(time.Now().Add(timeout)) == nil
+}
+
+// connection is the per-connection state: its session, reader and protocol
+// mode.
+type connection struct {
+	net.Conn
+	session uint64
+	reader  *bufio.Reader
+	mode    string
+}
+
 func (s *Server) handleConn(conn net.Conn) {
-	defer conn.Close()
+	defer s.untrack(conn)
+
+	c := &connection{Conn: conn, session: s.sessions.Add(1), reader: bufio.NewReader(conn), mode: protocol.ModeLine}
+	defer s.queue.end(c.session)
+	for s.setReadDeadline(conn, s.idleTimeout) {
+		// Wait for the next request, then give the client the request timeout
+		// to finish it, so a partial line cannot hold the connection open.
+		if _, err := c.reader.Peek(1); err != nil {
+			return
+		}
+		if !s.setReadDeadline(conn, s.requestTimeout) {
+			return
+		}
+		line, err := c.reader.ReadString('\n')
+		switch {
+		case err == nil:
+		case errors.Is(err, io.EOF):
+			// A final request without a trailing newline is still answered.
+			if line != "" {
+				s.serveRequest(c, strings.TrimRight(line, "\r\n"))
+			}
+			return
+		case isTimeout(err):
+			s.writeError(c, nil, protocol.Error{Code: "timeout", Message: "request not completed in time"})
+			return
+		default:
+			s.writeError(c, nil, protocol.Error{Code: "invalid_command", Message: "unable to read request"})
+			return
+		}
+		if !s.serveRequest(c, strings.TrimRight(line, "\r\n")) {
+			return
+		}
+	}
+}
+
+// serveRequest answers one request line, reporting whether the connection can
+// carry further requests. A line-mode request that cannot be parsed ends the
+// connection, since the framing of what follows is unknown; a malformed JSON
+// request is a single line and does not.
+func (s *Server) serveRequest(c *connection, line string) bool {
+	var (
+		request protocol.Request
+		id      json.RawMessage
+		err     error
+	)
+	s.lastRequest.Store(time.Now().UnixNano())
+	if c.mode == protocol.ModeJSON {
+		request, id, err = protocol.ParseJSON(line)
+		if err != nil {
+			return s.writeError(c, id, err)
+		}
+	} else {
+		request, err = protocol.ParseLine(line)
+		if err == nil && request.Command == "batch" {
+			request, err = protocol.ReadBatchBody(c.reader, request)
+		}
+		if err != nil {
+			if isTimeout(err) {
+				err = protocol.Error{Code: "timeout", Message: "request not completed in time"}
+			}
+			s.writeError(c, nil, err)
+			return false
+		}
+	}
 
-	reader := bufio.NewReader(conn)
-	line, err := reader.ReadString('\n')
-	if err != nil && !errors.Is(err, io.EOF) {
-		writeResponse(conn, protocol.FormatError("invalid_command", "unable to read request"))
-		return
+	// The response uses the mode the request arrived in, even if it switches modes.
+	requestMode := c.mode
+	var response string
+	if request.Command == "mode" {
+		response = c.switchMode(request.Args)
+	} else {
+		response = s.queue.submit(c.session, request)
 	}
-	line = strings.TrimRight(line, "\r\n")
-	request, err := protocol.ParseLine(line)
+	if requestMode == protocol.ModeJSON {
+		response = protocol.FormatJSON(toJSON(id, request, response))
+	}
+	return s.writeResponse(c, response)
+}
+
+func (c *connection) switchMode(args []string) string {
+	bound, err := lookupSpec("mode").Bind(args)
 	if err != nil {
-		writeResponse(conn, formatProtocolError(err))
-		return
+		return formatError(err)
+	}
+	c.mode = strings.ToLower(bound.String("mode"))
+	return protocol.FormatOK(c.mode)
+}
+
+// writeError sends a request error in the connection's mode.
+func (s *Server) writeError(c *connection, id json.RawMessage, err error) bool {
+	response := formatProtocolError(err)
+	if c.mode == protocol.ModeJSON {
+		var perr protocol.Error
+		if !errors.As(err, &perr) {
+			perr = protocol.Error{Code: "invalid_command", Message: err.Error()}
+		}
+		response = protocol.FormatJSON(protocol.JSONErrorResponse(id, perr.Code, perr.Message))
+	}
+	return s.writeResponse(c, response)
+}
+
+// writeResponse sends a response, giving up on clients that stop reading.
+func (s *Server) writeResponse(conn net.Conn, response string) bool {
+	if err := conn.SetWriteDeadline(time.Now().Add(s.requestTimeout)); err != nil {
+		return false
 	}
-	response := s.handler.Handle(request)
-	writeResponse(conn, response)
+	_, err := io.WriteString(conn, response+"\n")
+	return err == nil
 }
 
-func writeResponse(conn net.Conn, response string) {
-	_, _ = io.WriteString(conn, response+"\n")
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 func formatProtocolError(err error) string {
@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	"pxcli/internal/canvas"
+	"pxcli/internal/history"
 	"pxcli/internal/protocol"
 	"pxcli/internal/testutil"
 )
@@ -22,7 +24,7 @@ func (s stubHandler) Handle(request protocol.Request) string {
 	return s.response
 }
 
-func TestServerRespondsSingleLine(t *testing.T) {
+func TestServerAnswersRequestsOnOneConnection(t *testing.T) {
 	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
 	server, err := NewServer(socketPath, stubHandler{response: "ok"})
 	if err != nil {
@@ -39,19 +41,72 @@ func TestServerRespondsSingleLine(t *testing.T) {
 	}
 	defer conn.Close()
 
+	reader := bufio.NewReader(conn)
+	for _, request := range []string{"clear\n", "set_pixel 0 0 red\n", "get_pixel 0 0\n"} {
+		if _, err := io.WriteString(conn, request); err != nil {
+			t.Fatalf("unexpected error writing request: %v", err)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error reading response: %v", err)
+		}
+		if line != "ok\n" {
+			t.Fatalf("expected response %q, got %q", "ok\n", line)
+		}
+	}
+}
+
+func TestServerClosesIdleConnection(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	server, err := NewServer(socketPath, stubHandler{response: "ok"}, WithIdleTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+	t.Cleanup(func() {
+		stopServer(t, server, done)
+	})
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer conn.Close()
+
 	if _, err := io.WriteString(conn, "clear\n"); err != nil {
 		t.Fatalf("unexpected error writing request: %v", err)
 	}
-
 	reader := bufio.NewReader(conn)
-	line, err := reader.ReadString('\n')
+	if line, err := reader.ReadString('\n'); err != nil || line != "ok\n" {
+		t.Fatalf("expected ok response, got %q (%v)", line, err)
+	}
+
+	assertConnClosed(t, conn)
+}
+
+func TestServerCloseEndsIdleConnections(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	server, err := NewServer(socketPath, stubHandler{response: "ok"})
 	if err != nil {
-		t.Fatalf("unexpected error reading response: %v", err)
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "clear\n"); err != nil {
+		t.Fatalf("unexpected error writing request: %v", err)
 	}
-	if line != "ok\n" {
-		t.Fatalf("expected response %q, got %q", "ok\n", line)
+	reader := bufio.NewReader(conn)
+	if line, err := reader.ReadString('\n'); err != nil || line != "ok\n" {
+		t.Fatalf("expected ok response, got %q (%v)", line, err)
 	}
 
+	stopServer(t, server, done)
 	assertConnClosed(t, conn)
 }
 
@@ -88,6 +143,90 @@ func TestServerInvalidRequestClosesConnection(t *testing.T) {
 	assertConnClosed(t, conn)
 }
 
+type bodyEchoHandler struct{}
+
+func (bodyEchoHandler) Handle(request protocol.Request) string {
+	return protocol.FormatBatch(protocol.FormatOK(strings.Join(request.Args, " ")), request.Body)
+}
+
+func TestServerReadsBatchBody(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	server, err := NewServer(socketPath, bodyEchoHandler{})
+	if err != nil {
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+	t.Cleanup(func() {
+		stopServer(t, server, done)
+	})
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "batch 2\nset_pixel 0 0 red\nclear\n"); err != nil {
+		t.Fatalf("unexpected error writing request: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	var response strings.Builder
+	for range 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error reading response: %v", err)
+		}
+		response.WriteString(line)
+	}
+	if want := "ok 2\nset_pixel 0 0 red\nclear\n"; response.String() != want {
+		t.Fatalf("expected response %q, got %q", want, response.String())
+	}
+}
+
+func TestServerJSONMode(t *testing.T) {
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	server, err := NewServer(socketPath, stubHandler{response: "ok #ff0000ff"})
+	if err != nil {
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+	t.Cleanup(func() {
+		stopServer(t, server, done)
+	})
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	steps := []struct {
+		request string
+		want    string
+	}{
+		{request: "mode xml", want: "err invalid_args mode must be line or json\n"},
+		{request: "mode json", want: "ok json\n"},
+		{request: `{"id":1,"command":"get_pixel","args":[0,0]}`, want: `{"id":1,"status":"ok","result":"#ff0000ff"}` + "\n"},
+		// A malformed JSON request does not end the connection.
+		{request: `{"id":2,`, want: `{"status":"error","error":{"code":"invalid_request","message":"malformed JSON request: unexpected EOF"}}` + "\n"},
+		{request: `{"id":3,"command":"mode","args":["line"]}`, want: `{"id":3,"status":"ok","result":"line"}` + "\n"},
+		{request: "get_pixel 0 0", want: "ok #ff0000ff\n"},
+	}
+	for _, step := range steps {
+		if _, err := io.WriteString(conn, step.request+"\n"); err != nil {
+			t.Fatalf("unexpected error writing request: %v", err)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error reading response: %v", err)
+		}
+		if line != step.want {
+			t.Fatalf("request %q: expected %q, got %q", step.request, step.want, line)
+		}
+	}
+}
+
 func startServer(t *testing.T, server *Server) <-chan error {
 	t.Helper()
 	done := make(chan error, 1)
@@ -116,6 +255,76 @@ func assertConnClosed(t *testing.T, conn net.Conn) {
 	var buf [1]byte
 	n, err := conn.Read(buf[:])
 	if n != 0 || !errors.Is(err, io.EOF) {
-		t.Fatalf("expected EOF after response, got n=%d err=%v", n, err)
+		t.Fatalf("expected the server to close the connection, got n=%d err=%v", n, err)
+	}
+}
+
+func TestServerScopesTransactionsToConnections(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected canvas error: %v", err)
+	}
+	socketPath := filepath.Join(testutil.TempDir(t), "pxcli.sock")
+	server, err := NewServer(socketPath, NewHandler(history.New(target), nil))
+	if err != nil {
+		t.Fatalf("unexpected error creating server: %v", err)
+	}
+	done := startServer(t, server)
+	t.Cleanup(func() {
+		stopServer(t, server, done)
+	})
+
+	owner, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer owner.Close()
+	other, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to socket: %v", err)
+	}
+	defer other.Close()
+	ownerReader, otherReader := bufio.NewReader(owner), bufio.NewReader(other)
+
+	steps := []struct {
+		conn    net.Conn
+		reader  *bufio.Reader
+		request string
+		want    string
+	}{
+		{owner, ownerReader, "begin", "ok\n"},
+		{owner, ownerReader, "set_pixel 0 0 red", "ok\n"},
+		{other, otherReader, "set_pixel 1 1 blue", "err in_transaction another connection has a transaction open on this document\n"},
+		{other, otherReader, "commit", "err in_transaction another connection has a transaction open on this document\n"},
+		{other, otherReader, "get_pixel 0 0", "ok #ff0000ff\n"},
+	}
+	for _, step := range steps {
+		response, err := exchangeLine(step.conn, step.reader, step.request)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.request, err)
+		}
+		if response != step.want {
+			t.Fatalf("%s: expected %q, got %q", step.request, step.want, response)
+		}
+	}
+
+	// Closing the owner rolls its transaction back and frees the document.
+	_ = owner.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		response, err := exchangeLine(other, otherReader, "set_pixel 1 1 blue")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if response == "ok\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transaction was not released after its connection closed: %q", response)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if response, _ := exchangeLine(other, otherReader, "get_pixel 0 0"); response != "ok #00000000\n" {
+		t.Fatalf("expected the transaction to be rolled back, got %q", response)
 	}
 }
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"pxcli/internal/command"
+	"pxcli/internal/protocol"
+)
+
+// statusTracker counts the requests a daemon has answered and remembers the
+// last one that failed.
+type statusTracker struct {
+	mu        sync.Mutex
+	started   time.Time
+	requests  int
+	lastError string
+}
+
+func newStatusTracker() *statusTracker {
+	return &statusTracker{started: time.Now()}
+}
+
+// record counts a request by its response.
+func (t *statusTracker) record(response string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests++
+	header, _, _ := strings.Cut(response, "\n")
+	if rest, ok := strings.CutPrefix(header, "err "); ok {
+		t.lastError = rest
+	}
+}
+
+func (t *statusTracker) snapshot() (time.Duration, int, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Since(t.started), t.requests, t.lastError
+}
+
+// handleStatus reports the daemon's process, canvas and request counters as
+// space-separated key=value pairs. It changes nothing, so scripts can use it as
+// a readiness probe.
+func (h *Handler) handleStatus(command.Args) string {
+	document, manager := h.docs.Active()
+	target := manager.Canvas()
+	stats := manager.Stats()
+	uptime, requests, lastError := h.status.snapshot()
+
+	mode := "headless"
+	if h.windowed {
+		mode = "windowed"
+	}
+	fields := []string{
+		"running=true",
+		fmt.Sprintf("pid=%d", os.Getpid()),
+		fmt.Sprintf("uptime=%d", int(uptime.Seconds())),
+		fmt.Sprintf("width=%d", target.Width()),
+		fmt.Sprintf("height=%d", target.Height()),
+		"mode=" + mode,
+	}
+	if h.windowed {
+		fields = append(fields, fmt.Sprintf("scale=%d", h.scale))
+	}
+	fields = append(fields,
+		protocol.QuoteArg("socket="+h.socketPath),
+		protocol.QuoteArg("document="+document),
+		fmt.Sprintf("undo=%d", stats.UndoEntries),
+		fmt.Sprintf("redo=%d", stats.RedoEntries),
+		fmt.Sprintf("requests=%d", requests),
+		protocol.QuoteArg("last_error="+lastError),
+	)
+	return protocol.FormatOK(strings.Join(fields, " "))
+}
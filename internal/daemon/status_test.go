@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"pxcli/internal/canvas"
+	"pxcli/internal/history"
+	"pxcli/internal/protocol"
+)
+
+func TestHandlerStatus(t *testing.T) {
+	target, err := canvas.New(16, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil, WithSocketPath("/tmp/my pxcli.sock"))
+
+	requests := []protocol.Request{
+		{Command: "set_pixel", Args: []string{"0", "0", "red"}},
+		{Command: "set_pixel", Args: []string{"1", "0", "red"}},
+		{Command: "undo"},
+		{Command: "get_pixel", Args: []string{"99", "0"}},
+	}
+	for _, request := range requests {
+		handler.Handle(request)
+	}
+
+	response := handler.Handle(protocol.Request{Command: "status"})
+	want := regexp.MustCompile(fmt.Sprintf(`^ok running=true pid=%d uptime=\d+ width=16 height=8 mode=headless `+
+		`"socket=/tmp/my pxcli.sock" document=main undo=1 redo=1 requests=4 `+
+		`"last_error=out_of_bounds pixel \(99,0\) outside canvas"$`, os.Getpid()))
+	if !want.MatchString(response) {
+		t.Fatalf("unexpected status %q", response)
+	}
+	if response := handler.Handle(protocol.Request{Command: "status"}); !regexp.MustCompile(` requests=5 `).MatchString(response) {
+		t.Fatalf("expected the status request to be counted, got %q", response)
+	}
+}
+
+func TestHandlerStatusWindowed(t *testing.T) {
+	target, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := NewHandler(history.New(target), nil, WithWindowed(), WithScale(12))
+
+	response := handler.Handle(protocol.Request{Command: "status"})
+	if !regexp.MustCompile(` mode=windowed scale=12 socket= .* requests=0 last_error=$`).MatchString(response) {
+		t.Fatalf("unexpected status %q", response)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"pxcli/internal/canvas"
 	"pxcli/internal/client"
 	"pxcli/internal/config"
 	"pxcli/internal/testutil"
@@ -20,6 +21,31 @@ func TestScaledWindowSize(t *testing.T) {
 	}
 }
 
+func TestPlaybackFrameLoopsByDuration(t *testing.T) {
+	t.Parallel()
+	frames := []canvas.RenderFrame{
+		{Duration: 100 * time.Millisecond},
+		{Duration: 300 * time.Millisecond},
+		{Duration: 100 * time.Millisecond},
+	}
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{elapsed: 0, want: 0},
+		{elapsed: 99 * time.Millisecond, want: 0},
+		{elapsed: 100 * time.Millisecond, want: 1},
+		{elapsed: 399 * time.Millisecond, want: 1},
+		{elapsed: 450 * time.Millisecond, want: 2},
+		{elapsed: 520 * time.Millisecond, want: 0},
+	}
+	for _, tt := range tests {
+		if got := playbackFrame(frames, tt.elapsed); got != tt.want {
+			t.Fatalf("elapsed %v: expected frame %d, got %d", tt.elapsed, tt.want, got)
+		}
+	}
+}
+
 func TestWindowedStopRequestsRendererClose(t *testing.T) {
 	dir := testutil.TempDir(t)
 	socketPath := filepath.Join(dir, "pxcli.sock")
@@ -1,11 +1,19 @@
 package history
 
 import (
+	"slices"
 	"sync"
 
 	"pxcli/internal/canvas"
 )
 
+const (
+	// DefaultMaxEntries is the default cap on undo plus redo entries.
+	DefaultMaxEntries = 10000
+	// DefaultMaxBytes is the default cap on memory held by undo and redo entries.
+	DefaultMaxBytes int64 = 256 << 20
+)
+
 // Error represents an undo/redo history error with a code and message.
 type Error struct {
 	Code    string
@@ -19,17 +27,75 @@ func (e Error) Error() string {
 	return e.Code + ": " + e.Message
 }
 
-// Manager tracks undo/redo history for a canvas.
+// Limits caps the history size. Zero or negative values disable a cap.
+type Limits struct {
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// DefaultLimits returns the default history caps.
+func DefaultLimits() Limits {
+	return Limits{MaxEntries: DefaultMaxEntries, MaxBytes: DefaultMaxBytes}
+}
+
+// Stats reports history usage against its limits.
+type Stats struct {
+	UndoEntries int
+	RedoEntries int
+	Bytes       int64
+	MaxEntries  int
+	MaxBytes    int64
+	// Evicted counts entries dropped to stay within the limits.
+	Evicted int
+}
+
+// Manager tracks undo/redo history for a canvas as recorded changes, evicting
+// the oldest entries once the limits are exceeded.
 type Manager struct {
-	mu     sync.Mutex
-	canvas *canvas.Canvas
-	undo   []canvas.Snapshot
-	redo   []canvas.Snapshot
+	mu      sync.Mutex
+	canvas  *canvas.Canvas
+	limits  Limits
+	undo    []entry
+	redo    []entry
+	bytes   int64
+	evicted int
+	// open is set while a transaction collects its changes in pending.
+	open    bool
+	pending []canvas.Change
 }
 
-// New creates a new history manager for the provided canvas.
+// entry is one undo step: the changes of a single command or of a whole transaction.
+type entry []canvas.Change
+
+func (e entry) revert(target *canvas.Canvas) error {
+	for i := len(e) - 1; i >= 0; i-- {
+		if err := target.RevertChange(e[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e entry) reapply(target *canvas.Canvas) error {
+	for _, change := range e {
+		if err := target.ReapplyChange(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e entry) size() int64 {
+	var size int64
+	for _, change := range e {
+		size += int64(change.Size())
+	}
+	return size
+}
+
+// New creates a new history manager for the provided canvas with default limits.
 func New(target *canvas.Canvas) *Manager {
-	return &Manager{canvas: target}
+	return &Manager{canvas: target, limits: DefaultLimits()}
 }
 
 // Canvas returns the managed canvas.
@@ -37,49 +103,307 @@ func (m *Manager) Canvas() *canvas.Canvas {
 	return m.canvas
 }
 
-// Apply runs a mutating operation and records undo history on success.
+// SetLimits changes the history caps, evicting entries that no longer fit.
+func (m *Manager) SetLimits(limits Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+	m.enforce()
+}
+
+// Limits returns the history caps.
+func (m *Manager) Limits() Limits {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.limits
+}
+
+// Stats returns the current history usage.
+func (m *Manager) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Stats{
+		UndoEntries: len(m.undo),
+		RedoEntries: len(m.redo),
+		Bytes:       m.bytes,
+		MaxEntries:  m.limits.MaxEntries,
+		MaxBytes:    m.limits.MaxBytes,
+		Evicted:     m.evicted,
+	}
+}
+
+// Apply runs a mutating operation and records undo history on success. A failed
+// operation is rolled back; one that changes nothing is not recorded. Inside a
+// transaction the change is held until Commit.
 func (m *Manager) Apply(mutate func(*canvas.Canvas) error) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	snapshot := m.canvas.Snapshot()
-	if err := mutate(m.canvas); err != nil {
+	m.canvas.BeginChange()
+	err := mutate(m.canvas)
+	change := m.canvas.EndChange()
+	if err != nil {
+		_ = m.canvas.RevertChange(change)
 		return err
 	}
-	m.undo = append(m.undo, snapshot)
-	m.redo = nil
+	if change.Empty() {
+		return nil
+	}
+	if m.open {
+		m.pending = append(m.pending, change)
+		return nil
+	}
+	m.push(entry{change})
+	return nil
+}
+
+// Begin opens a transaction: changes applied until Commit become a single undo
+// step, and Rollback reverts them all.
+func (m *Manager) Begin() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.open {
+		return Error{Code: "in_transaction", Message: "a transaction is already open"}
+	}
+	m.open = true
+	return nil
+}
+
+// Commit closes the open transaction, recording its changes as one undo step.
+func (m *Manager) Commit() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.open {
+		return Error{Code: "no_transaction", Message: "no transaction is open"}
+	}
+	m.commit()
+	return nil
+}
+
+// Rollback closes the open transaction, reverting every change made in it.
+func (m *Manager) Rollback() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.open {
+		return Error{Code: "no_transaction", Message: "no transaction is open"}
+	}
+	err := m.rollback(0)
+	m.open = false
+	return err
+}
+
+// InTransaction reports whether a transaction is open.
+func (m *Manager) InTransaction() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.open
+}
+
+// Atomic runs fn so that the changes it applies through Apply form a single undo
+// step, reverting them all if fn fails. Inside an open transaction the changes
+// join it instead, and only those made by fn are reverted on failure. fn must
+// not call Begin, Commit, Rollback, Undo, Redo or Load.
+func (m *Manager) Atomic(fn func() error) error {
+	m.mu.Lock()
+	nested := m.open
+	m.open = true
+	mark := len(m.pending)
+	m.mu.Unlock()
+
+	err := fn()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if rollbackErr := m.rollback(mark); rollbackErr != nil {
+			err = rollbackErr
+		}
+		m.open = nested
+		return err
+	}
+	if !nested {
+		m.commit()
+	}
 	return nil
 }
 
-// Undo restores the previous snapshot, if available.
+// commit records the pending changes as one entry and closes the transaction;
+// callers must hold the lock.
+func (m *Manager) commit() {
+	if len(m.pending) > 0 {
+		m.push(entry(m.pending))
+	}
+	m.pending = nil
+	m.open = false
+}
+
+// rollback reverts pending changes from index mark onward, newest first;
+// callers must hold the lock.
+func (m *Manager) rollback(mark int) error {
+	err := entry(m.pending[mark:]).revert(m.canvas)
+	clear(m.pending[mark:])
+	m.pending = m.pending[:mark]
+	return err
+}
+
+// push records a new undo entry, dropping the redo stack; callers must hold the lock.
+func (m *Manager) push(e entry) {
+	m.undo = append(m.undo, e)
+	m.bytes += e.size()
+	for _, dropped := range m.redo {
+		m.bytes -= dropped.size()
+	}
+	m.redo = nil
+	m.enforce()
+}
+
+// Undo reverts the most recent change, if available.
 func (m *Manager) Undo() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkClosed("undo"); err != nil {
+		return err
+	}
 	if len(m.undo) == 0 {
 		return Error{Code: "no_history", Message: "nothing to undo"}
 	}
-	current := m.canvas.Snapshot()
-	previous := m.undo[len(m.undo)-1]
-	if err := m.canvas.Restore(previous); err != nil {
+	e := m.undo[len(m.undo)-1]
+	if err := e.revert(m.canvas); err != nil {
 		return err
 	}
 	m.undo = m.undo[:len(m.undo)-1]
-	m.redo = append(m.redo, current)
+	m.redo = append(m.redo, e)
 	return nil
 }
 
-// Redo reapplies a previously undone snapshot, if available.
+// Redo reapplies the most recently undone change, if available.
 func (m *Manager) Redo() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.checkClosed("redo"); err != nil {
+		return err
+	}
 	if len(m.redo) == 0 {
 		return Error{Code: "no_history", Message: "nothing to redo"}
 	}
-	current := m.canvas.Snapshot()
-	next := m.redo[len(m.redo)-1]
-	if err := m.canvas.Restore(next); err != nil {
+	e := m.redo[len(m.redo)-1]
+	if err := e.reapply(m.canvas); err != nil {
 		return err
 	}
 	m.redo = m.redo[:len(m.redo)-1]
-	m.undo = append(m.undo, current)
+	m.undo = append(m.undo, e)
+	return nil
+}
+
+// checkClosed rejects operations that cannot run inside a transaction; callers
+// must hold the lock.
+func (m *Manager) checkClosed(operation string) error {
+	if m.open {
+		return Error{Code: "in_transaction", Message: operation + " is not allowed while a transaction is open"}
+	}
+	return nil
+}
+
+// Export returns the current canvas state together with the state before each
+// undo entry and after each redo entry, oldest first, captured under a single lock.
+func (m *Manager) Export() (current canvas.Snapshot, undo, redo []canvas.Snapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current = m.canvas.Snapshot()
+	// An open transaction is exported as if it had been committed.
+	undo = replay(current, m.undo, entry.revert)
+	if len(m.pending) > 0 {
+		undo = replay(current, append(slices.Clone(m.undo), entry(m.pending)), entry.revert)
+	}
+	redo = replay(current, m.redo, entry.reapply)
+	return current, undo, redo
+}
+
+// replay steps a scratch copy of current through changes, newest first, and
+// returns the state after each step in stack order. Should a change fail to
+// apply, only the states reachable before it are returned.
+func replay(current canvas.Snapshot, changes []entry, step func(entry, *canvas.Canvas) error) []canvas.Snapshot {
+	if len(changes) == 0 {
+		return nil
+	}
+	scratch, err := canvas.New(1, 1)
+	if err != nil {
+		return nil
+	}
+	if err := scratch.Load(current); err != nil {
+		return nil
+	}
+	states := make([]canvas.Snapshot, len(changes))
+	for i := len(changes) - 1; i >= 0; i-- {
+		if err := step(changes[i], scratch); err != nil {
+			return states[i+1:]
+		}
+		states[i] = scratch.Snapshot()
+	}
+	return states
+}
+
+// Load replaces the canvas state, including its dimensions, and the undo and
+// redo stacks. Undo snapshots are the states before each entry and redo
+// snapshots the states after each, oldest first, as returned by Export.
+func (m *Manager) Load(current canvas.Snapshot, undo, redo []canvas.Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkClosed("loading a document"); err != nil {
+		return err
+	}
+	if err := m.canvas.Load(current); err != nil {
+		return err
+	}
+	m.undo = make([]entry, len(undo))
+	for i, before := range undo {
+		after := current
+		if i+1 < len(undo) {
+			after = undo[i+1]
+		}
+		m.undo[i] = entry{canvas.NewChange(before, after)}
+	}
+	m.redo = make([]entry, len(redo))
+	for i, after := range redo {
+		before := current
+		if i+1 < len(redo) {
+			before = redo[i+1]
+		}
+		m.redo[i] = entry{canvas.NewChange(before, after)}
+	}
+	m.bytes = 0
+	for _, e := range m.undo {
+		m.bytes += e.size()
+	}
+	for _, e := range m.redo {
+		m.bytes += e.size()
+	}
+	m.evicted = 0
+	m.enforce()
 	return nil
 }
+
+// enforce evicts the oldest undo entries, then the furthest redo entries, until
+// the history fits its limits; callers must hold the lock.
+func (m *Manager) enforce() {
+	for m.overLimit() {
+		stack := &m.undo
+		if len(m.undo) == 0 {
+			stack = &m.redo
+		}
+		m.bytes -= (*stack)[0].size()
+		// Clear the slot so the backing array does not keep the entry alive.
+		(*stack)[0] = nil
+		*stack = (*stack)[1:]
+		m.evicted++
+	}
+}
+
+func (m *Manager) overLimit() bool {
+	if len(m.undo)+len(m.redo) == 0 {
+		return false
+	}
+	if m.limits.MaxEntries > 0 && len(m.undo)+len(m.redo) > m.limits.MaxEntries {
+		return true
+	}
+	return m.limits.MaxBytes > 0 && m.bytes > m.limits.MaxBytes
+}
@@ -2,6 +2,7 @@ package history
 
 import (
 	"image/color"
+	"reflect"
 	"testing"
 
 	"pxcli/internal/canvas"
@@ -116,3 +117,507 @@ func TestHistoryRedoClearedOnNewMutation(t *testing.T) {
 		t.Fatalf("expected green at (1,0), got %v", got)
 	}
 }
+
+func TestHistoryExportLoadReplacesStateAndStacks(t *testing.T) {
+	source, err := canvas.New(3, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved := New(source)
+	red := color.RGBA{R: 255, A: 255}
+	if err := saved.Apply(func(target *canvas.Canvas) error {
+		return target.SetPixel(2, 0, red)
+	}); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	current, undo, redo := saved.Export()
+
+	c, err := canvas.New(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	if err := manager.Load(current, undo, redo); err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if c.Width() != 3 || c.Height() != 1 {
+		t.Fatalf("expected load to resize canvas to 3x1, got %dx%d", c.Width(), c.Height())
+	}
+	if got, err := c.GetPixel(2, 0); err != nil || got != red {
+		t.Fatalf("expected loaded red pixel, got %v (%v)", got, err)
+	}
+
+	if err := manager.Undo(); err != nil {
+		t.Fatalf("expected loaded undo history, got %v", err)
+	}
+	if got, err := c.GetPixel(2, 0); err != nil || got != (color.RGBA{}) {
+		t.Fatalf("expected undo to clear pixel, got %v (%v)", got, err)
+	}
+
+	if err := manager.Load(canvas.Snapshot{}, nil, nil); err == nil {
+		t.Fatalf("expected error loading an empty snapshot")
+	}
+	if c.Width() != 3 {
+		t.Fatalf("expected failed load to keep canvas size, got width %d", c.Width())
+	}
+}
+
+func TestHistoryEvictsOldestEntriesOverEntryLimit(t *testing.T) {
+	c, err := canvas.New(4, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	manager.SetLimits(Limits{MaxEntries: 2})
+	red := color.RGBA{R: 255, A: 255}
+
+	for x := range 4 {
+		if err := manager.Apply(func(target *canvas.Canvas) error {
+			return target.SetPixel(x, 0, red)
+		}); err != nil {
+			t.Fatalf("unexpected apply error: %v", err)
+		}
+	}
+	stats := manager.Stats()
+	if stats.UndoEntries != 2 || stats.Evicted != 2 {
+		t.Fatalf("expected 2 undo entries and 2 evicted, got %+v", stats)
+	}
+
+	for range 2 {
+		if err := manager.Undo(); err != nil {
+			t.Fatalf("unexpected undo error: %v", err)
+		}
+	}
+	if err := manager.Undo(); err == nil {
+		t.Fatalf("expected evicted entries to be gone")
+	}
+	for x, want := range []color.RGBA{red, red, {}, {}} {
+		if got, err := c.GetPixel(x, 0); err != nil || got != want {
+			t.Fatalf("pixel %d: expected %v, got %v (%v)", x, want, got, err)
+		}
+	}
+}
+
+func TestHistoryEvictsOldestEntriesOverByteLimit(t *testing.T) {
+	c, err := canvas.New(64, 64)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	red := color.RGBA{R: 255, A: 255}
+	if err := manager.Apply(func(target *canvas.Canvas) error {
+		return target.Clear(red)
+	}); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	clearBytes := manager.Stats().Bytes
+
+	manager.SetLimits(Limits{MaxBytes: clearBytes - 1})
+	stats := manager.Stats()
+	if stats.UndoEntries != 0 || stats.Bytes != 0 || stats.Evicted != 1 {
+		t.Fatalf("expected the oversized entry to be evicted, got %+v", stats)
+	}
+
+	manager.SetLimits(Limits{MaxBytes: clearBytes})
+	for range 3 {
+		if err := manager.Apply(func(target *canvas.Canvas) error {
+			return target.SetPixel(0, 0, color.RGBA{G: 255, A: 255})
+		}); err != nil {
+			t.Fatalf("unexpected apply error: %v", err)
+		}
+		if err := manager.Apply(func(target *canvas.Canvas) error {
+			return target.SetPixel(0, 0, red)
+		}); err != nil {
+			t.Fatalf("unexpected apply error: %v", err)
+		}
+	}
+	if stats := manager.Stats(); stats.Bytes > clearBytes || stats.UndoEntries != 6 {
+		t.Fatalf("expected small entries to fit under the byte limit, got %+v", stats)
+	}
+}
+
+func TestHistorySkipsNoOpChanges(t *testing.T) {
+	c, err := canvas.New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	red := color.RGBA{R: 255, A: 255}
+	if err := manager.Apply(func(target *canvas.Canvas) error {
+		return target.SetPixel(0, 0, red)
+	}); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	if err := manager.Undo(); err != nil {
+		t.Fatalf("unexpected undo error: %v", err)
+	}
+
+	if err := manager.Apply(func(target *canvas.Canvas) error {
+		return target.SetPixel(1, 0, color.RGBA{})
+	}); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	if stats := manager.Stats(); stats.UndoEntries != 0 || stats.RedoEntries != 1 {
+		t.Fatalf("expected a no-op to leave history untouched, got %+v", stats)
+	}
+}
+
+func TestHistoryRollsBackFailedMutation(t *testing.T) {
+	c, err := canvas.New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	red := color.RGBA{R: 255, A: 255}
+
+	err = manager.Apply(func(target *canvas.Canvas) error {
+		if err := target.SetPixel(0, 0, red); err != nil {
+			return err
+		}
+		return target.SetPixel(5, 0, red)
+	})
+	if err == nil {
+		t.Fatalf("expected out_of_bounds error")
+	}
+	if got, err := c.GetPixel(0, 0); err != nil || got != (color.RGBA{}) {
+		t.Fatalf("expected partial edit to be rolled back, got %v (%v)", got, err)
+	}
+	if stats := manager.Stats(); stats.UndoEntries != 0 {
+		t.Fatalf("expected failed mutation not to be recorded, got %+v", stats)
+	}
+}
+
+func TestHistoryPixelEditsStaySmall(t *testing.T) {
+	c, err := canvas.New(512, 512)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	for i := range 1000 {
+		if err := manager.Apply(func(target *canvas.Canvas) error {
+			return target.SetPixel(i%512, i/512, color.RGBA{R: uint8(i), A: 255})
+		}); err != nil {
+			t.Fatalf("unexpected apply error: %v", err)
+		}
+	}
+	stats := manager.Stats()
+	if stats.UndoEntries != 1000 {
+		t.Fatalf("expected 1000 undo entries, got %d", stats.UndoEntries)
+	}
+	// A single snapshot of this canvas is 1 MiB; all of the deltas together
+	// should stay well below that.
+	if stats.Bytes > 256<<10 {
+		t.Fatalf("expected pixel deltas to stay under 256 KiB, got %d bytes", stats.Bytes)
+	}
+}
+
+func TestHistoryExportReplaysLayerAndPixelChanges(t *testing.T) {
+	c, err := canvas.New(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	states := []canvas.Snapshot{c.Snapshot()}
+	steps := []func(*canvas.Canvas) error{
+		func(target *canvas.Canvas) error { return target.SetPixel(0, 0, color.RGBA{R: 255, A: 255}) },
+		func(target *canvas.Canvas) error { _, err := target.AddLayer("ink"); return err },
+		func(target *canvas.Canvas) error { return target.FillRect(0, 0, 2, 2, color.RGBA{B: 255, A: 255}) },
+		func(target *canvas.Canvas) error { return target.SetLayerOpacity("ink", 50) },
+	}
+	for _, step := range steps {
+		if err := manager.Apply(step); err != nil {
+			t.Fatalf("unexpected apply error: %v", err)
+		}
+		states = append(states, c.Snapshot())
+	}
+	if err := manager.Undo(); err != nil {
+		t.Fatalf("unexpected undo error: %v", err)
+	}
+
+	current, undo, redo := manager.Export()
+	if !reflect.DeepEqual(current, states[3]) {
+		t.Fatalf("expected current state after one undo")
+	}
+	if !reflect.DeepEqual(undo, states[:3]) {
+		t.Fatalf("expected undo states to match the recorded states")
+	}
+	if !reflect.DeepEqual(redo, states[4:]) {
+		t.Fatalf("expected redo state to match the last recorded state")
+	}
+}
+
+func TestHistoryTransactionCommitsOneUndoStep(t *testing.T) {
+	c, err := canvas.New(3, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	red := color.RGBA{R: 255, A: 255}
+
+	if err := manager.Begin(); err != nil {
+		t.Fatalf("unexpected begin error: %v", err)
+	}
+	if err := manager.Begin(); err == nil {
+		t.Fatalf("expected nested begin to fail")
+	} else if historyErr, ok := err.(Error); !ok || historyErr.Code != "in_transaction" {
+		t.Fatalf("expected in_transaction, got %v", err)
+	}
+	for x := range 3 {
+		if err := manager.Apply(func(target *canvas.Canvas) error {
+			return target.SetPixel(x, 0, red)
+		}); err != nil {
+			t.Fatalf("unexpected apply error: %v", err)
+		}
+	}
+	if err := manager.Undo(); err == nil {
+		t.Fatalf("expected undo inside a transaction to fail")
+	}
+	if err := manager.Commit(); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if stats := manager.Stats(); stats.UndoEntries != 1 {
+		t.Fatalf("expected one undo entry, got %+v", stats)
+	}
+
+	if err := manager.Undo(); err != nil {
+		t.Fatalf("unexpected undo error: %v", err)
+	}
+	for x := range 3 {
+		if got, err := c.GetPixel(x, 0); err != nil || got != (color.RGBA{}) {
+			t.Fatalf("expected undo to clear pixel %d, got %v (%v)", x, got, err)
+		}
+	}
+	if err := manager.Commit(); err == nil {
+		t.Fatalf("expected commit without a transaction to fail")
+	} else if historyErr, ok := err.(Error); !ok || historyErr.Code != "no_transaction" {
+		t.Fatalf("expected no_transaction, got %v", err)
+	}
+}
+
+func TestHistoryTransactionRollback(t *testing.T) {
+	c, err := canvas.New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	red := color.RGBA{R: 255, A: 255}
+	if err := manager.Apply(func(target *canvas.Canvas) error {
+		return target.SetPixel(0, 0, red)
+	}); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	if err := manager.Undo(); err != nil {
+		t.Fatalf("unexpected undo error: %v", err)
+	}
+
+	if err := manager.Begin(); err != nil {
+		t.Fatalf("unexpected begin error: %v", err)
+	}
+	if err := manager.Apply(func(target *canvas.Canvas) error {
+		if _, err := target.AddLayer("ink"); err != nil {
+			return err
+		}
+		return target.SetPixel(1, 0, red)
+	}); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	if err := manager.Rollback(); err != nil {
+		t.Fatalf("unexpected rollback error: %v", err)
+	}
+	if got := len(c.Layers()); got != 1 {
+		t.Fatalf("expected rollback to remove the added layer, got %d layers", got)
+	}
+	if got, err := c.GetPixel(1, 0); err != nil || got != (color.RGBA{}) {
+		t.Fatalf("expected rollback to clear pixel, got %v (%v)", got, err)
+	}
+	if err := manager.Redo(); err != nil {
+		t.Fatalf("expected rollback to keep the redo stack, got %v", err)
+	}
+}
+
+func TestHistoryAtomicRevertsOnFailure(t *testing.T) {
+	c, err := canvas.New(2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager := New(c)
+	red := color.RGBA{R: 255, A: 255}
+	set := func(x int) error {
+		return manager.Apply(func(target *canvas.Canvas) error {
+			return target.SetPixel(x, 0, red)
+		})
+	}
+
+	if err := manager.Atomic(func() error {
+		if err := set(0); err != nil {
+			return err
+		}
+		return set(9)
+	}); err == nil {
+		t.Fatalf("expected atomic run to fail")
+	}
+	if got, _ := c.GetPixel(0, 0); got != (color.RGBA{}) {
+		t.Fatalf("expected failed atomic run to be reverted, got %v", got)
+	}
+	if manager.InTransaction() {
+		t.Fatalf("expected no transaction after a failed atomic run")
+	}
+
+	// Inside a transaction a failed atomic run only reverts its own changes.
+	if err := manager.Begin(); err != nil {
+		t.Fatalf("unexpected begin error: %v", err)
+	}
+	if err := set(0); err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+	if err := manager.Atomic(func() error {
+		if err := set(1); err != nil {
+			return err
+		}
+		return set(9)
+	}); err == nil {
+		t.Fatalf("expected atomic run to fail")
+	}
+	if !manager.InTransaction() {
+		t.Fatalf("expected the outer transaction to stay open")
+	}
+	if err := manager.Commit(); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	for x, want := range []color.RGBA{red, {}} {
+		if got, _ := c.GetPixel(x, 0); got != want {
+			t.Fatalf("pixel %d: expected %v, got %v", x, want, got)
+		}
+	}
+	if stats := manager.Stats(); stats.UndoEntries != 1 {
+		t.Fatalf("expected one undo entry, got %+v", stats)
+	}
+}
+
+// snapshotHistory reproduces the previous full-snapshot undo stack as a
+// baseline for the memory benchmarks. It keeps a ring of recent snapshots so
+// that long benchmark runs do not exhaust memory.
+type snapshotHistory struct {
+	canvas *canvas.Canvas
+	undo   []canvas.Snapshot
+	next   int
+}
+
+func (h *snapshotHistory) Apply(mutate func(*canvas.Canvas) error) error {
+	snapshot := h.canvas.Snapshot()
+	if err := mutate(h.canvas); err != nil {
+		return err
+	}
+	if len(h.undo) < cap(h.undo) {
+		h.undo = append(h.undo, snapshot)
+	} else {
+		h.undo[h.next] = snapshot
+		h.next = (h.next + 1) % len(h.undo)
+	}
+	return nil
+}
+
+// bytesPerEntry returns the pixel memory the kept snapshots hold, per snapshot.
+func (h *snapshotHistory) bytesPerEntry() float64 {
+	if len(h.undo) == 0 {
+		return 0
+	}
+	var total int64
+	for _, snapshot := range h.undo {
+		for _, l := range snapshot.Document().Layers {
+			for _, cel := range l.Cels {
+				total += int64(len(cel)) * 4
+			}
+		}
+	}
+	return float64(total) / float64(len(h.undo))
+}
+
+// BenchmarkHistorySetPixel compares memory per recorded set_pixel on a 512x512
+// canvas. B/op is what each entry allocates; history-B/op is what it retains.
+func BenchmarkHistorySetPixel(b *testing.B) {
+	const size = 512
+	set := func(i int) func(*canvas.Canvas) error {
+		return func(target *canvas.Canvas) error {
+			return target.SetPixel(i%size, (i/size)%size, color.RGBA{R: uint8(i), A: 255})
+		}
+	}
+
+	b.Run("diff", func(b *testing.B) {
+		c, err := canvas.New(size, size)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		manager := New(c)
+		manager.SetLimits(Limits{})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := manager.Apply(set(i)); err != nil {
+				b.Fatalf("unexpected apply error: %v", err)
+			}
+		}
+		b.ReportMetric(float64(manager.Stats().Bytes)/float64(b.N), "history-B/op")
+	})
+
+	b.Run("snapshot", func(b *testing.B) {
+		c, err := canvas.New(size, size)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		manager := &snapshotHistory{canvas: c, undo: make([]canvas.Snapshot, 0, 64)}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := manager.Apply(set(i)); err != nil {
+				b.Fatalf("unexpected apply error: %v", err)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(manager.bytesPerEntry(), "history-B/op")
+	})
+}
+
+// BenchmarkHistoryFillRect compares memory per recorded 64x64 fill on a 512x512 canvas.
+func BenchmarkHistoryFillRect(b *testing.B) {
+	const size = 512
+	fill := func(i int) func(*canvas.Canvas) error {
+		return func(target *canvas.Canvas) error {
+			return target.FillRect((i*64)%(size-64), (i*32)%(size-64), 64, 64, color.RGBA{G: uint8(i), A: 255})
+		}
+	}
+
+	b.Run("diff", func(b *testing.B) {
+		c, err := canvas.New(size, size)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		manager := New(c)
+		manager.SetLimits(Limits{})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := manager.Apply(fill(i)); err != nil {
+				b.Fatalf("unexpected apply error: %v", err)
+			}
+		}
+		b.ReportMetric(float64(manager.Stats().Bytes)/float64(b.N), "history-B/op")
+	})
+
+	b.Run("snapshot", func(b *testing.B) {
+		c, err := canvas.New(size, size)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		manager := &snapshotHistory{canvas: c, undo: make([]canvas.Snapshot, 0, 64)}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := manager.Apply(fill(i)); err != nil {
+				b.Fatalf("unexpected apply error: %v", err)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(manager.bytesPerEntry(), "history-B/op")
+	})
+}
@@ -0,0 +1,161 @@
+package palette
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"math"
+	"unicode/utf16"
+
+	pxcolor "pxcli/internal/color"
+)
+
+// Adobe Swatch Exchange files are big-endian: the "ASEF" signature, a
+// version, a block count, then blocks of a type, a length and a payload.
+// Color blocks hold a UTF-16 name, a color model and float channels; group
+// blocks only organize colors and are skipped.
+const (
+	aseSignature   = "ASEF"
+	aseColorBlock  = 0x0001
+	aseGroupStart  = 0xc001
+	aseGroupEnd    = 0xc002
+	aseNormalColor = 2
+	aseMaxBlocks   = 1 << 16
+)
+
+func readASE(r io.Reader) (pxcolor.Palette, error) {
+	var header struct {
+		Signature    [4]byte
+		Major, Minor uint16
+		Blocks       uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, aseReadError(err)
+	}
+	if string(header.Signature[:]) != aseSignature {
+		return nil, invalidf("missing ASEF signature")
+	}
+	if header.Major != 1 {
+		return nil, invalidf("unsupported ASE version %d.%d", header.Major, header.Minor)
+	}
+	if header.Blocks > aseMaxBlocks {
+		return nil, invalidf("too many blocks (%d)", header.Blocks)
+	}
+	var p pxcolor.Palette
+	for range header.Blocks {
+		var block struct {
+			Type   uint16
+			Length uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &block); err != nil {
+			return nil, aseReadError(err)
+		}
+		if block.Length > 1<<20 {
+			return nil, invalidf("block of %d bytes is too large", block.Length)
+		}
+		payload := make([]byte, block.Length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, aseReadError(err)
+		}
+		switch block.Type {
+		case aseColorBlock:
+			swatch, err := decodeASEColor(payload)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, swatch)
+		case aseGroupStart, aseGroupEnd:
+		default:
+			return nil, invalidf("unknown block type %#04x", block.Type)
+		}
+	}
+	return p, nil
+}
+
+func decodeASEColor(payload []byte) (pxcolor.Swatch, error) {
+	r := bytes.NewReader(payload)
+	name, err := readASEName(r)
+	if err != nil {
+		return pxcolor.Swatch{}, err
+	}
+	var model [4]byte
+	if _, err := io.ReadFull(r, model[:]); err != nil {
+		return pxcolor.Swatch{}, aseReadError(err)
+	}
+	channels := map[string]int{"RGB ": 3, "LAB ": 3, "CMYK": 4, "Gray": 1}[string(model[:])]
+	if channels == 0 {
+		return pxcolor.Swatch{}, invalidf("unknown color model %q", model[:])
+	}
+	values := make([]float32, channels)
+	if err := binary.Read(r, binary.BigEndian, values); err != nil {
+		return pxcolor.Swatch{}, aseReadError(err)
+	}
+
+	var c color.RGBA
+	switch string(model[:]) {
+	case "RGB ":
+		c = color.RGBA{R: unit(values[0]), G: unit(values[1]), B: unit(values[2]), A: 255}
+	case "LAB ":
+		c = pxcolor.FromLab(pxcolor.Lab{L: float64(values[0]) * 100, A: float64(values[1]), B: float64(values[2])})
+	case "CMYK":
+		k := 1 - values[3]
+		c = color.RGBA{R: unit((1 - values[0]) * k), G: unit((1 - values[1]) * k), B: unit((1 - values[2]) * k), A: 255}
+	case "Gray":
+		v := unit(values[0])
+		c = color.RGBA{R: v, G: v, B: v, A: 255}
+	}
+	return pxcolor.Swatch{Name: name, Color: c}, nil
+}
+
+// readASEName reads a length-prefixed, NUL-terminated UTF-16 name.
+func readASEName(r io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", aseReadError(err)
+	}
+	units := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return "", aseReadError(err)
+	}
+	if length > 0 && units[length-1] == 0 {
+		units = units[:length-1]
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func writeASE(w io.Writer, p pxcolor.Palette) error {
+	var out bytes.Buffer
+	out.WriteString(aseSignature)
+	_ = binary.Write(&out, binary.BigEndian, []uint16{1, 0})
+	_ = binary.Write(&out, binary.BigEndian, uint32(len(p)))
+	for _, swatch := range p {
+		var block bytes.Buffer
+		units := append(utf16.Encode([]rune(swatch.Name)), 0)
+		_ = binary.Write(&block, binary.BigEndian, uint16(len(units)))
+		_ = binary.Write(&block, binary.BigEndian, units)
+		block.WriteString("RGB ")
+		c := swatch.Color
+		_ = binary.Write(&block, binary.BigEndian, []float32{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255})
+		_ = binary.Write(&block, binary.BigEndian, uint16(aseNormalColor))
+
+		_ = binary.Write(&out, binary.BigEndian, uint16(aseColorBlock))
+		_ = binary.Write(&out, binary.BigEndian, uint32(block.Len()))
+		out.Write(block.Bytes())
+	}
+	_, err := w.Write(out.Bytes())
+	return writeError(err)
+}
+
+// unit converts a 0-1 channel to 0-255, clamping out-of-range values.
+func unit(v float32) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, float64(v))) * 255))
+}
+
+func aseReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return invalidf("truncated ASE file")
+	}
+	return Error{Code: "io", Message: err.Error()}
+}
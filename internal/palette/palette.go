@@ -0,0 +1,149 @@
+// Package palette reads and writes swatch files: GIMP .gpl, Lospec .hex, JASC
+// .pal and Adobe .ase. The format follows the file extension.
+//
+// Swatch names survive in .gpl and .ase files; .hex and .pal keep only colors.
+// Alpha survives only in .hex, as two extra digits; the other formats store
+// opaque colors.
+package palette
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pxcolor "pxcli/internal/color"
+)
+
+// Error represents a swatch file error with a code and message.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Format is a swatch file format, named by its file extension.
+type Format string
+
+const (
+	FormatGPL Format = "gpl"
+	FormatHex Format = "hex"
+	FormatPAL Format = "pal"
+	FormatASE Format = "ase"
+)
+
+// Formats lists the supported formats.
+func Formats() []Format {
+	return []Format{FormatGPL, FormatHex, FormatPAL, FormatASE}
+}
+
+// FormatOf returns the format of a swatch file from its extension.
+func FormatOf(path string) (Format, error) {
+	ext := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")))
+	for _, format := range Formats() {
+		if ext == format {
+			return format, nil
+		}
+	}
+	return "", Error{Code: "invalid_args", Message: fmt.Sprintf("unsupported palette file %q: use .gpl, .hex, .pal or .ase", filepath.Base(path))}
+}
+
+// Read decodes a palette in the given format. Names that cannot serve as
+// swatch references, such as duplicates, are dropped.
+func Read(r io.Reader, format Format) (pxcolor.Palette, error) {
+	var (
+		p   pxcolor.Palette
+		err error
+	)
+	switch format {
+	case FormatGPL:
+		p, err = readGPL(r)
+	case FormatHex:
+		p, err = readHex(r)
+	case FormatPAL:
+		p, err = readPAL(r)
+	case FormatASE:
+		p, err = readASE(r)
+	default:
+		return nil, Error{Code: "invalid_args", Message: fmt.Sprintf("unsupported palette format %q", format)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.Sanitize(), nil
+}
+
+// Write encodes a palette in the given format.
+func Write(w io.Writer, format Format, p pxcolor.Palette) error {
+	switch format {
+	case FormatGPL:
+		return writeGPL(w, p)
+	case FormatHex:
+		return writeHex(w, p)
+	case FormatPAL:
+		return writePAL(w, p)
+	case FormatASE:
+		return writeASE(w, p)
+	}
+	return Error{Code: "invalid_args", Message: fmt.Sprintf("unsupported palette format %q", format)}
+}
+
+// LoadFile reads a palette from path in the format its extension names.
+func LoadFile(path string) (pxcolor.Palette, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, Error{Code: "io", Message: err.Error()}
+	}
+	defer file.Close()
+	return Read(bufio.NewReader(file), format)
+}
+
+// SaveFile writes a palette to path in the format its extension names,
+// replacing any existing file.
+func SaveFile(path string, p pxcolor.Palette) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	w := bufio.NewWriter(file)
+	if err := Write(w, format, p); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return Error{Code: "io", Message: err.Error()}
+	}
+	if err := file.Close(); err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	return nil
+}
+
+func invalidf(format string, args ...any) error {
+	return Error{Code: "invalid_palette", Message: fmt.Sprintf(format, args...)}
+}
+
+// writeError wraps an error from the underlying writer.
+func writeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return Error{Code: "io", Message: err.Error()}
+}
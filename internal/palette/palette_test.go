@@ -0,0 +1,186 @@
+package palette
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	pxcolor "pxcli/internal/color"
+)
+
+func TestReadTextFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   pxcolor.Palette
+	}{
+		{
+			name:   "gpl",
+			format: FormatGPL,
+			input:  "GIMP Palette\nName: Test\nColumns: 4\n#\n# comment\n  0   0   0\tUntitled\n255 128  64\tskin dark\n 10  20  30\tskin dark\n",
+			want: pxcolor.Palette{
+				{Color: color.RGBA{A: 255}},
+				{Name: "skin dark", Color: color.RGBA{R: 255, G: 128, B: 64, A: 255}},
+				{Color: color.RGBA{R: 10, G: 20, B: 30, A: 255}},
+			},
+		},
+		{
+			name:   "hex",
+			format: FormatHex,
+			input:  "ff0000\r\n#00FF00\n\n0000ff80\n",
+			want: pxcolor.Palette{
+				{Color: color.RGBA{R: 255, A: 255}},
+				{Color: color.RGBA{G: 255, A: 255}},
+				{Color: color.RGBA{B: 255, A: 128}},
+			},
+		},
+		{
+			name:   "pal",
+			format: FormatPAL,
+			input:  "JASC-PAL\r\n0100\r\n2\r\n255 0 0\r\n0 0 255\r\n",
+			want: pxcolor.Palette{
+				{Color: color.RGBA{R: 255, A: 255}},
+				{Color: color.RGBA{B: 255, A: 255}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadASE(t *testing.T) {
+	var out bytes.Buffer
+	out.WriteString("ASEF")
+	_ = binary.Write(&out, binary.BigEndian, []uint16{1, 0})
+	_ = binary.Write(&out, binary.BigEndian, uint32(5))
+	writeBlock := func(kind uint16, payload []byte) {
+		_ = binary.Write(&out, binary.BigEndian, kind)
+		_ = binary.Write(&out, binary.BigEndian, uint32(len(payload)))
+		out.Write(payload)
+	}
+	colorBlock := func(name, model string, values ...float32) []byte {
+		var block bytes.Buffer
+		units := append(utf16.Encode([]rune(name)), 0)
+		_ = binary.Write(&block, binary.BigEndian, uint16(len(units)))
+		_ = binary.Write(&block, binary.BigEndian, units)
+		block.WriteString(model)
+		_ = binary.Write(&block, binary.BigEndian, values)
+		_ = binary.Write(&block, binary.BigEndian, uint16(2))
+		return block.Bytes()
+	}
+	groupName := []byte{0, 2, 0, 'g', 0, 0}
+	writeBlock(aseGroupStart, groupName)
+	writeBlock(aseColorBlock, colorBlock("ember", "RGB ", 1, 0.5, 0))
+	writeBlock(aseColorBlock, colorBlock("ink", "CMYK", 0, 0, 0, 1))
+	writeBlock(aseColorBlock, colorBlock("", "Gray", 1))
+	writeBlock(aseGroupEnd, nil)
+
+	got, err := Read(&out, FormatASE)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := pxcolor.Palette{
+		{Name: "ember", Color: color.RGBA{R: 255, G: 128, A: 255}},
+		{Name: "ink", Color: color.RGBA{A: 255}},
+		{Color: color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	p := pxcolor.Palette{
+		{Name: "night sky", Color: color.RGBA{R: 16, G: 24, B: 64, A: 255}},
+		{Color: color.RGBA{R: 250, G: 240, B: 10, A: 255}},
+		{Name: "ember", Color: color.RGBA{R: 255, G: 99, B: 0, A: 255}},
+	}
+	unnamed := pxcolor.Palette{{Color: p[0].Color}, {Color: p[1].Color}, {Color: p[2].Color}}
+	want := map[Format]pxcolor.Palette{FormatGPL: p, FormatASE: p, FormatHex: unnamed, FormatPAL: unnamed}
+
+	dir := t.TempDir()
+	for _, format := range Formats() {
+		path := filepath.Join(dir, "swatches."+strings.ToUpper(string(format)))
+		if err := SaveFile(path, p); err != nil {
+			t.Fatalf("%s: unexpected save error: %v", format, err)
+		}
+		got, err := LoadFile(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected load error: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, want[format]) {
+			t.Fatalf("%s: expected %v, got %v", format, want[format], got)
+		}
+	}
+}
+
+func TestHexKeepsAlpha(t *testing.T) {
+	var out bytes.Buffer
+	p := pxcolor.Palette{{Color: color.RGBA{R: 1, G: 2, B: 3, A: 255}}, {Color: color.RGBA{R: 4, G: 5, B: 6, A: 7}}}
+	if err := Write(&out, FormatHex, p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := out.String(); got != "010203\n04050607\n" {
+		t.Fatalf("unexpected hex output %q", got)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		input    string
+		wantCode string
+	}{
+		{name: "gpl header", format: FormatGPL, input: "0 0 0\n", wantCode: "invalid_palette"},
+		{name: "gpl channel", format: FormatGPL, input: "GIMP Palette\n0 0 256\n", wantCode: "invalid_palette"},
+		{name: "hex digits", format: FormatHex, input: "ff00\n", wantCode: "invalid_palette"},
+		{name: "hex characters", format: FormatHex, input: "gg0000\n", wantCode: "invalid_palette"},
+		{name: "pal count", format: FormatPAL, input: "JASC-PAL\n0100\n3\n0 0 0\n", wantCode: "invalid_palette"},
+		{name: "ase signature", format: FormatASE, input: "ASEX\x00\x01\x00\x00\x00\x00\x00\x00", wantCode: "invalid_palette"},
+		{name: "ase truncated", format: FormatASE, input: "ASEF\x00\x01\x00\x00\x00\x00\x00\x01\x00\x01", wantCode: "invalid_palette"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input), tt.format)
+			var perr Error
+			if !errors.As(err, &perr) || perr.Code != tt.wantCode {
+				t.Fatalf("expected %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	var perr Error
+	if _, err := LoadFile(filepath.Join(dir, "colors.txt")); !errors.As(err, &perr) || perr.Code != "invalid_args" {
+		t.Fatalf("expected invalid_args for an unknown extension, got %v", err)
+	}
+	if _, err := LoadFile(filepath.Join(dir, "missing.gpl")); !errors.As(err, &perr) || perr.Code != "io" {
+		t.Fatalf("expected io for a missing file, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty.hex"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if p, err := LoadFile(filepath.Join(dir, "empty.hex")); err != nil || len(p) != 0 {
+		t.Fatalf("expected an empty palette, got %v, %v", p, err)
+	}
+}
@@ -0,0 +1,178 @@
+package palette
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	pxcolor "pxcli/internal/color"
+)
+
+// gplUnnamed is the name GIMP gives swatches without one.
+const gplUnnamed = "Untitled"
+
+// readGPL decodes a GIMP palette: a "GIMP Palette" line, optional "Key: value"
+// headers and "#" comments, then one "R G B [name]" line per swatch.
+func readGPL(r io.Reader) (pxcolor.Palette, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || lines[0].text != "GIMP Palette" {
+		return nil, invalidf("missing GIMP Palette header")
+	}
+	var p pxcolor.Palette
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line.text, "#") || isGPLHeader(line.text) {
+			continue
+		}
+		fields := strings.Fields(line.text)
+		if len(fields) < 3 {
+			return nil, invalidf("line %d: expected R G B [name]", line.number)
+		}
+		c, err := parseRGB(fields[:3], line.number)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.Join(fields[3:], " ")
+		if name == gplUnnamed {
+			name = ""
+		}
+		p = append(p, pxcolor.Swatch{Name: name, Color: c})
+	}
+	return p, nil
+}
+
+func isGPLHeader(line string) bool {
+	key, _, ok := strings.Cut(line, ":")
+	return ok && !strings.ContainsAny(key, " \t") && key != ""
+}
+
+func writeGPL(w io.Writer, p pxcolor.Palette) error {
+	var b strings.Builder
+	b.WriteString("GIMP Palette\nName: pxcli\n#\n")
+	for _, swatch := range p {
+		name := swatch.Name
+		if name == "" {
+			name = gplUnnamed
+		}
+		fmt.Fprintf(&b, "%3d %3d %3d\t%s\n", swatch.Color.R, swatch.Color.G, swatch.Color.B, name)
+	}
+	_, err := io.WriteString(w, b.String())
+	return writeError(err)
+}
+
+// readHex decodes a Lospec hex palette: one RRGGBB color per line, with an
+// optional "#" and an optional alpha byte.
+func readHex(r io.Reader) (pxcolor.Palette, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	var p pxcolor.Palette
+	for _, line := range lines {
+		digits := strings.TrimPrefix(line.text, "#")
+		if len(digits) != 6 && len(digits) != 8 {
+			return nil, invalidf("line %d: expected RRGGBB, got %q", line.number, line.text)
+		}
+		c, err := pxcolor.Parse("#" + digits)
+		if err != nil {
+			return nil, invalidf("line %d: expected RRGGBB, got %q", line.number, line.text)
+		}
+		p = append(p, pxcolor.Swatch{Color: c})
+	}
+	return p, nil
+}
+
+func writeHex(w io.Writer, p pxcolor.Palette) error {
+	var b strings.Builder
+	for _, swatch := range p {
+		c := swatch.Color
+		fmt.Fprintf(&b, "%02x%02x%02x", c.R, c.G, c.B)
+		if c.A != 255 {
+			fmt.Fprintf(&b, "%02x", c.A)
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return writeError(err)
+}
+
+// readPAL decodes a JASC palette: "JASC-PAL", the version "0100", the swatch
+// count, then one "R G B" line per swatch.
+func readPAL(r io.Reader) (pxcolor.Palette, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 3 || lines[0].text != "JASC-PAL" || lines[1].text != "0100" {
+		return nil, invalidf("missing JASC-PAL 0100 header")
+	}
+	count, err := strconv.Atoi(lines[2].text)
+	if err != nil || count < 0 {
+		return nil, invalidf("line %d: invalid color count %q", lines[2].number, lines[2].text)
+	}
+	entries := lines[3:]
+	if len(entries) != count {
+		return nil, invalidf("header announces %d colors, found %d", count, len(entries))
+	}
+	p := make(pxcolor.Palette, 0, count)
+	for _, line := range entries {
+		fields := strings.Fields(line.text)
+		if len(fields) != 3 {
+			return nil, invalidf("line %d: expected R G B", line.number)
+		}
+		c, err := parseRGB(fields, line.number)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, pxcolor.Swatch{Color: c})
+	}
+	return p, nil
+}
+
+func writePAL(w io.Writer, p pxcolor.Palette) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "JASC-PAL\r\n0100\r\n%d\r\n", len(p))
+	for _, swatch := range p {
+		fmt.Fprintf(&b, "%d %d %d\r\n", swatch.Color.R, swatch.Color.G, swatch.Color.B)
+	}
+	_, err := io.WriteString(w, b.String())
+	return writeError(err)
+}
+
+type textLine struct {
+	number int
+	text   string
+}
+
+// readLines returns the trimmed, non-blank lines of r with their line numbers.
+func readLines(r io.Reader) ([]textLine, error) {
+	var lines []textLine
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text != "" {
+			lines = append(lines, textLine{number: number, text: text})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, Error{Code: "io", Message: err.Error()}
+	}
+	return lines, nil
+}
+
+func parseRGB(fields []string, line int) (color.RGBA, error) {
+	var channels [3]uint8
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil || v < 0 || v > 255 {
+			return color.RGBA{}, invalidf("line %d: color channel %q must be 0-255", line, field)
+		}
+		channels[i] = uint8(v)
+	}
+	return color.RGBA{R: channels[0], G: channels[1], B: channels[2], A: 255}, nil
+}
@@ -0,0 +1,419 @@
+package project
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"slices"
+
+	"pxcli/internal/canvas"
+	pxcolor "pxcli/internal/color"
+)
+
+const magic = "\x89PXP\r\n\x1a\n"
+
+// Section tags. Every section may appear at most once except UNDO and REDO,
+// which repeat once per history entry, oldest first.
+const (
+	tagMeta     = "META"
+	tagDocument = "DOCU"
+	tagUndo     = "UNDO"
+	tagRedo     = "REDO"
+	tagPalette  = "PALT"
+	tagEnd      = "END "
+)
+
+// maxDimension bounds decoded canvas sizes so corrupt files cannot force huge allocations.
+const maxDimension = 1 << 14
+
+var byteOrder = binary.LittleEndian
+
+// Write encodes the project to w.
+func Write(w io.Writer, p Project) error {
+	var out bytes.Buffer
+	out.WriteString(magic)
+	_ = binary.Write(&out, byteOrder, uint16(Version))
+
+	if len(p.Metadata) > 0 {
+		var meta bytes.Buffer
+		keys := make([]string, 0, len(p.Metadata))
+		for key := range p.Metadata {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		writeUint32(&meta, len(keys))
+		for _, key := range keys {
+			writeString(&meta, key)
+			writeString(&meta, p.Metadata[key])
+		}
+		writeSection(&out, tagMeta, meta.Bytes())
+	}
+	if p.Palette != nil {
+		writeSection(&out, tagPalette, encodePalette(*p.Palette))
+	}
+
+	docs := []struct {
+		tag  string
+		docs []canvas.Document
+	}{
+		{tag: tagDocument, docs: []canvas.Document{p.Document}},
+		{tag: tagUndo, docs: p.Undo},
+		{tag: tagRedo, docs: p.Redo},
+	}
+	for _, group := range docs {
+		for _, doc := range group.docs {
+			payload, err := encodeDocument(doc)
+			if err != nil {
+				return err
+			}
+			writeSection(&out, group.tag, payload)
+		}
+	}
+	writeSection(&out, tagEnd, nil)
+
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	return nil
+}
+
+// Read decodes a project from r, skipping sections it does not recognize.
+func Read(r io.Reader) (Project, error) {
+	header := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Project{}, readError(err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return Project{}, invalidf("not a pxcli project file")
+	}
+	if version := byteOrder.Uint16(header[len(magic):]); version > Version {
+		return Project{}, Error{
+			Code:    "unsupported_version",
+			Message: fmt.Sprintf("project format version %d is newer than supported version %d", version, Version),
+		}
+	}
+
+	var (
+		p       Project
+		haveDoc bool
+	)
+	for {
+		tag, payload, err := readSection(r)
+		if err != nil {
+			return Project{}, err
+		}
+		switch tag {
+		case tagEnd:
+			if !haveDoc {
+				return Project{}, invalidf("missing document section")
+			}
+			for _, doc := range slices.Concat(p.Undo, p.Redo) {
+				if doc.Width != p.Document.Width || doc.Height != p.Document.Height {
+					return Project{}, invalidf("history entry size does not match document")
+				}
+			}
+			return p, nil
+		case tagMeta:
+			if p.Metadata != nil {
+				return Project{}, invalidf("duplicate metadata section")
+			}
+			if p.Metadata, err = decodeMetadata(payload); err != nil {
+				return Project{}, err
+			}
+		case tagPalette:
+			if p.Palette != nil {
+				return Project{}, invalidf("duplicate palette section")
+			}
+			palette, err := decodePalette(payload)
+			if err != nil {
+				return Project{}, err
+			}
+			p.Palette = &palette
+		case tagDocument:
+			if haveDoc {
+				return Project{}, invalidf("duplicate document section")
+			}
+			if p.Document, err = decodeDocument(payload); err != nil {
+				return Project{}, err
+			}
+			haveDoc = true
+		case tagUndo, tagRedo:
+			doc, err := decodeDocument(payload)
+			if err != nil {
+				return Project{}, err
+			}
+			if tag == tagUndo {
+				p.Undo = append(p.Undo, doc)
+			} else {
+				p.Redo = append(p.Redo, doc)
+			}
+		}
+	}
+}
+
+func writeSection(out *bytes.Buffer, tag string, payload []byte) {
+	out.WriteString(tag)
+	writeUint32(out, len(payload))
+	out.Write(payload)
+}
+
+func readSection(r io.Reader) (string, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", nil, readError(err)
+	}
+	tag := string(header[:4])
+	payload := make([]byte, 0, min(byteOrder.Uint32(header[4:]), 1<<20))
+	buf := bytes.NewBuffer(payload)
+	if _, err := io.CopyN(buf, r, int64(byteOrder.Uint32(header[4:]))); err != nil {
+		return "", nil, readError(err)
+	}
+	return tag, buf.Bytes(), nil
+}
+
+// encodeDocument writes the canvas header, frame durations and layers; each
+// layer's cels are concatenated as RGBA bytes and zlib-compressed.
+func encodeDocument(doc canvas.Document) ([]byte, error) {
+	var out bytes.Buffer
+	writeUint32(&out, doc.Width)
+	writeUint32(&out, doc.Height)
+	writeUint32(&out, doc.ActiveLayer)
+	writeUint32(&out, doc.CurrentFrame)
+	writeUint32(&out, len(doc.Durations))
+	for _, duration := range doc.Durations {
+		writeUint32(&out, duration)
+	}
+	writeUint32(&out, len(doc.Layers))
+	for _, l := range doc.Layers {
+		writeString(&out, l.Name)
+		out.WriteByte(boolByte(l.Visible))
+		out.WriteByte(byte(l.Opacity))
+		out.WriteByte(boolByte(l.Locked))
+		writeString(&out, string(l.Blend))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		for _, cel := range l.Cels {
+			for _, value := range cel {
+				_, _ = zw.Write([]byte{value.R, value.G, value.B, value.A})
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, Error{Code: "io", Message: err.Error()}
+		}
+		writeUint32(&out, compressed.Len())
+		out.Write(compressed.Bytes())
+	}
+	return out.Bytes(), nil
+}
+
+func decodeDocument(payload []byte) (canvas.Document, error) {
+	r := &reader{buf: payload}
+	doc := canvas.Document{
+		Width:        r.uint32(),
+		Height:       r.uint32(),
+		ActiveLayer:  r.uint32(),
+		CurrentFrame: r.uint32(),
+	}
+	if r.err != nil {
+		return canvas.Document{}, r.err
+	}
+	if doc.Width <= 0 || doc.Height <= 0 || doc.Width > maxDimension || doc.Height > maxDimension {
+		return canvas.Document{}, invalidf("canvas size %dx%d out of range", doc.Width, doc.Height)
+	}
+	doc.Durations = make([]int, r.count(4))
+	for i := range doc.Durations {
+		doc.Durations[i] = r.uint32()
+	}
+	doc.Layers = make([]canvas.LayerDocument, r.count(1))
+	celSize := doc.Width * doc.Height
+	for i := range doc.Layers {
+		l := canvas.LayerDocument{
+			Name:    r.string(),
+			Visible: r.byte() != 0,
+			Opacity: int(r.byte()),
+			Locked:  r.byte() != 0,
+			Blend:   canvas.BlendMode(r.string()),
+		}
+		compressed := r.bytes(r.uint32())
+		if r.err != nil {
+			break
+		}
+		raw, err := inflate(compressed, len(doc.Durations)*celSize*4)
+		if err != nil {
+			return canvas.Document{}, err
+		}
+		l.Cels = make([][]color.RGBA, len(doc.Durations))
+		for f := range l.Cels {
+			cel := make([]color.RGBA, celSize)
+			for p := range cel {
+				offset := (f*celSize + p) * 4
+				cel[p] = color.RGBA{R: raw[offset], G: raw[offset+1], B: raw[offset+2], A: raw[offset+3]}
+			}
+			l.Cels[f] = cel
+		}
+		doc.Layers[i] = l
+	}
+	if r.err != nil {
+		return canvas.Document{}, r.err
+	}
+	if _, err := doc.Snapshot(); err != nil {
+		var canvasErr canvas.Error
+		if errors.As(err, &canvasErr) {
+			return canvas.Document{}, invalidf("%s", canvasErr.Message)
+		}
+		return canvas.Document{}, invalidf("%v", err)
+	}
+	return doc, nil
+}
+
+func decodeMetadata(payload []byte) (map[string]string, error) {
+	r := &reader{buf: payload}
+	count := r.count(8)
+	meta := make(map[string]string, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		key := r.string()
+		meta[key] = r.string()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return meta, nil
+}
+
+// encodePalette writes the lock mode and each swatch's name and RGBA bytes.
+func encodePalette(p Palette) []byte {
+	var out bytes.Buffer
+	writeString(&out, p.Lock)
+	writeUint32(&out, len(p.Swatches))
+	for _, swatch := range p.Swatches {
+		writeString(&out, swatch.Name)
+		out.Write([]byte{swatch.Color.R, swatch.Color.G, swatch.Color.B, swatch.Color.A})
+	}
+	return out.Bytes()
+}
+
+func decodePalette(payload []byte) (Palette, error) {
+	r := &reader{buf: payload}
+	p := Palette{Lock: r.string()}
+	count := r.count(8)
+	p.Swatches = make(pxcolor.Palette, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		name := r.string()
+		if value := r.bytes(4); value != nil {
+			p.Swatches = append(p.Swatches, pxcolor.Swatch{Name: name, Color: color.RGBA{R: value[0], G: value[1], B: value[2], A: value[3]}})
+		}
+	}
+	if r.err != nil {
+		return Palette{}, r.err
+	}
+	return p, nil
+}
+
+// maxInflateRatio is above zlib's best compression ratio; larger claimed sizes
+// mean a corrupt header rather than highly repetitive pixels.
+const maxInflateRatio = 1100
+
+func inflate(compressed []byte, size int) ([]byte, error) {
+	if size > len(compressed)*maxInflateRatio {
+		return nil, invalidf("pixel data too short for %d bytes", size)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, invalidf("corrupt pixel data: %v", err)
+	}
+	defer zr.Close()
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(zr, raw); err != nil {
+		return nil, invalidf("corrupt pixel data: %v", err)
+	}
+	// The checksum is only verified once the stream reaches EOF.
+	n, err := zr.Read(make([]byte, 1))
+	if n != 0 {
+		return nil, invalidf("corrupt pixel data: more pixels than the canvas holds")
+	}
+	if err != io.EOF {
+		return nil, invalidf("corrupt pixel data: %v", err)
+	}
+	return raw, nil
+}
+
+// reader decodes little-endian fields from a section payload, recording the
+// first out-of-range read instead of failing at every call site.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = invalidf("truncated section")
+		return nil
+	}
+	out := r.buf[:n]
+	r.buf = r.buf[n:]
+	return out
+}
+
+func (r *reader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint32() int {
+	if b := r.bytes(4); b != nil {
+		return int(byteOrder.Uint32(b))
+	}
+	return 0
+}
+
+// count reads an element count and rejects values that cannot fit in the rest
+// of the payload given each element's minimum encoded size.
+func (r *reader) count(minSize int) int {
+	n := r.uint32()
+	if r.err == nil && n*minSize > len(r.buf) {
+		r.err = invalidf("truncated section")
+		return 0
+	}
+	return n
+}
+
+func (r *reader) string() string {
+	return string(r.bytes(r.uint32()))
+}
+
+func writeUint32(out *bytes.Buffer, value int) {
+	_ = binary.Write(out, byteOrder, uint32(value))
+}
+
+func writeString(out *bytes.Buffer, value string) {
+	writeUint32(out, len(value))
+	out.WriteString(value)
+}
+
+func boolByte(value bool) byte {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func readError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return invalidf("unexpected end of file")
+	}
+	return Error{Code: "io", Message: err.Error()}
+}
+
+func invalidf(format string, args ...any) error {
+	return Error{Code: "invalid_project", Message: fmt.Sprintf(format, args...)}
+}
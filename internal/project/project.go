@@ -0,0 +1,86 @@
+// Package project reads and writes pxcli project files (.pxp), which persist a
+// whole editing session: canvas state, metadata and optionally undo/redo history.
+//
+// A project file is an 8-byte magic, a little-endian uint16 format version and a
+// sequence of sections. Each section is a 4-byte tag, a uint32 payload length and
+// the payload, so readers skip sections they do not recognize. The version only
+// changes when existing sections change incompatibly; new optional sections keep it.
+package project
+
+import (
+	"bufio"
+	"os"
+
+	"pxcli/internal/canvas"
+	pxcolor "pxcli/internal/color"
+)
+
+// Version is the newest format version this package reads and the one it writes.
+const Version = 1
+
+// Error represents a project file error with a code and message.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Project is the content of a project file.
+type Project struct {
+	// Metadata holds free-form key/value pairs such as the writer version.
+	Metadata map[string]string
+	// Document is the canvas state at save time.
+	Document canvas.Document
+	// Undo and Redo are the history stacks, oldest first. Both are empty when
+	// history was not saved.
+	Undo []canvas.Document
+	Redo []canvas.Document
+	// Palette is the palette in use at save time, or nil in files written
+	// before palettes were saved.
+	Palette *Palette
+}
+
+// Palette is the saved palette and how it constrains drawing colors.
+type Palette struct {
+	Swatches pxcolor.Palette
+	// Lock is the palette lock mode, such as "strict", or empty when drawing
+	// colors are free.
+	Lock string
+}
+
+// SaveFile writes the project to path, replacing any existing file.
+func SaveFile(path string, p Project) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	w := bufio.NewWriter(file)
+	if err := Write(w, p); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return Error{Code: "io", Message: err.Error()}
+	}
+	if err := file.Close(); err != nil {
+		return Error{Code: "io", Message: err.Error()}
+	}
+	return nil
+}
+
+// LoadFile reads a project from path.
+func LoadFile(path string) (Project, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Project{}, Error{Code: "io", Message: err.Error()}
+	}
+	defer file.Close()
+	return Read(bufio.NewReader(file))
+}
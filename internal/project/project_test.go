@@ -0,0 +1,164 @@
+package project
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pxcli/internal/canvas"
+	pxcolor "pxcli/internal/color"
+)
+
+func TestProjectRoundTrip(t *testing.T) {
+	p := Project{
+		Metadata: map[string]string{"generator": "pxcli test", "title": "hero walk"},
+		Document: sampleDocument(t),
+		Undo:     []canvas.Document{blankDocument(t), sampleDocument(t)},
+		Redo:     []canvas.Document{blankDocument(t)},
+		Palette: &Palette{
+			Swatches: pxcolor.Palette{{Name: "outline", Color: color.RGBA{R: 20, G: 20, B: 40, A: 255}}, {Color: color.RGBA{R: 255, A: 128}}},
+			Lock:     "snap",
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "hero.pxp")
+	if err := SaveFile(path, p); err != nil {
+		t.Fatalf("unexpected save error: %v", err)
+	}
+	got, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got, p)
+	}
+}
+
+func TestProjectRoundTripWithoutHistory(t *testing.T) {
+	p := Project{Document: sampleDocument(t)}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if got.Metadata != nil || got.Undo != nil || got.Redo != nil || got.Palette != nil {
+		t.Fatalf("expected no metadata, history or palette, got %+v", got)
+	}
+	if !reflect.DeepEqual(got.Document, p.Document) {
+		t.Fatalf("document mismatch:\n got %+v\nwant %+v", got.Document, p.Document)
+	}
+}
+
+func TestProjectSkipsUnknownSections(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Project{Document: sampleDocument(t)}); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	data := buf.Bytes()
+	header := len(magic) + 2
+
+	var future bytes.Buffer
+	future.Write(data[:header])
+	future.WriteString("XTRA")
+	_ = binary.Write(&future, byteOrder, uint32(5))
+	future.WriteString("later")
+	future.Write(data[header:])
+
+	got, err := Read(&future)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if got.Document.Width != 3 {
+		t.Fatalf("expected document to survive unknown section, got %+v", got.Document)
+	}
+}
+
+func TestProjectReadErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Project{Document: sampleDocument(t)}); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	valid := buf.Bytes()
+
+	newer := bytes.Clone(valid)
+	byteOrder.PutUint16(newer[len(magic):], Version+1)
+
+	corrupt := bytes.Clone(valid)
+	corrupt[len(corrupt)-20] ^= 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		code string
+	}{
+		{name: "bad magic", data: []byte("PNG not a project"), code: "invalid_project"},
+		{name: "newer version", data: newer, code: "unsupported_version"},
+		{name: "truncated", data: valid[:len(valid)-12], code: "invalid_project"},
+		{name: "missing end", data: valid[:len(valid)-8], code: "invalid_project"},
+		{name: "corrupt pixels", data: corrupt, code: "invalid_project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatalf("expected %s error", tt.code)
+			}
+			if projErr, ok := err.(Error); !ok || projErr.Code != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestLoadFileMissingIsIO(t *testing.T) {
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.pxp"))
+	if projErr, ok := err.(Error); !ok || projErr.Code != "io" {
+		t.Fatalf("expected io error, got %v", err)
+	}
+}
+
+// sampleDocument builds a 3x2 document with two layers and two frames.
+func sampleDocument(t *testing.T) canvas.Document {
+	t.Helper()
+	c, err := canvas.New(3, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetPixel(0, 0, color.RGBA{R: 255, A: 255}); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if _, err := c.AddLayer("ink"); err != nil {
+		t.Fatalf("unexpected layer error: %v", err)
+	}
+	if err := c.SetLayerBlend("ink", canvas.BlendMultiply); err != nil {
+		t.Fatalf("unexpected blend error: %v", err)
+	}
+	if err := c.SetLayerOpacity("ink", 40); err != nil {
+		t.Fatalf("unexpected opacity error: %v", err)
+	}
+	c.AddFrame()
+	if err := c.SetFrameDuration(1, 250); err != nil {
+		t.Fatalf("unexpected duration error: %v", err)
+	}
+	if err := c.SetPixel(2, 1, color.RGBA{G: 128, B: 64, A: 200}); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	return c.Snapshot().Document()
+}
+
+func blankDocument(t *testing.T) canvas.Document {
+	t.Helper()
+	c, err := canvas.New(3, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c.Snapshot().Document()
+}
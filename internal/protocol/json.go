@@ -0,0 +1,163 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Connection modes, switched with a "mode line" or "mode json" request.
+const (
+	ModeLine = "line"
+	ModeJSON = "json"
+)
+
+// Response statuses in JSON mode.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// JSONRequest is a request in JSON mode. Args may be strings, integers or
+// booleans; a batch request lists its operations in Batch, leaving Args to
+// options such as "--doc=name".
+type JSONRequest struct {
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Command string            `json:"command"`
+	Args    []json.RawMessage `json:"args,omitempty"`
+	Batch   []JSONRequest     `json:"batch,omitempty"`
+}
+
+// JSONResponse is a response in JSON mode. It echoes the request id; Result
+// holds the typed payload of a successful request, and the per-operation
+// results of a batch whether or not it succeeded.
+type JSONResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Status string          `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *JSONError      `json:"error,omitempty"`
+}
+
+// JSONError is the structured error of a failed JSON request.
+type JSONError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewJSONRequest builds a JSON request from a command and string arguments.
+func NewJSONRequest(command string, args ...string) JSONRequest {
+	request := JSONRequest{Command: command}
+	for _, arg := range args {
+		raw, _ := json.Marshal(arg)
+		request.Args = append(request.Args, raw)
+	}
+	return request
+}
+
+// ParseJSON parses a JSON request line, returning the request and its id. An
+// error is returned together with whatever id could be read.
+func ParseJSON(line string) (Request, json.RawMessage, error) {
+	var in JSONRequest
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&in); err != nil {
+		return Request{}, nil, Error{Code: "invalid_request", Message: fmt.Sprintf("malformed JSON request: %v", err)}
+	}
+	request, err := in.request()
+	if err != nil {
+		return Request{}, in.ID, err
+	}
+	if len(in.Batch) > 0 && request.Command != "batch" {
+		return Request{}, in.ID, Error{Code: "invalid_args", Message: "only batch requests take a batch list"}
+	}
+	if request.Command == "batch" {
+		for _, arg := range request.Args {
+			if !strings.HasPrefix(arg, "--") {
+				return Request{}, in.ID, Error{Code: "invalid_args", Message: "batch lists its operations in batch; args hold only options"}
+			}
+		}
+		if len(in.Batch) > MaxBatchLines {
+			return Request{}, in.ID, Error{Code: "invalid_args", Message: fmt.Sprintf("batch may hold at most %d operations", MaxBatchLines)}
+		}
+		request.Args = append([]string{strconv.Itoa(len(in.Batch))}, request.Args...)
+		request.Body = make([]string, len(in.Batch))
+		for i, op := range in.Batch {
+			line, err := op.line()
+			if err != nil {
+				var perr Error
+				errors.As(err, &perr)
+				return Request{}, in.ID, Error{Code: perr.Code, Message: fmt.Sprintf("batch operation %d: %s", i+1, perr.Message)}
+			}
+			request.Body[i] = line
+		}
+	}
+	return request, in.ID, nil
+}
+
+func (r JSONRequest) request() (Request, error) {
+	if strings.TrimSpace(r.Command) == "" || strings.ContainsFunc(r.Command, isWhitespaceRune) {
+		return Request{}, Error{Code: "invalid_command", Message: "command is required"}
+	}
+	args := make([]string, len(r.Args))
+	for i, raw := range r.Args {
+		arg, err := jsonArg(raw)
+		if err != nil {
+			return Request{}, Error{Code: "invalid_args", Message: fmt.Sprintf("argument %d must be a string, integer or boolean", i+1)}
+		}
+		args[i] = arg
+	}
+	return Request{Command: r.Command, Args: args}, nil
+}
+
+// line renders a batch operation as a request line.
+func (r JSONRequest) line() (string, error) {
+	if len(r.Batch) > 0 || len(r.ID) > 0 {
+		return "", Error{Code: "invalid_args", Message: "operations take only a command and args"}
+	}
+	request, err := r.request()
+	if err != nil {
+		return "", err
+	}
+	return FormatLine(request.Command, request.Args...), nil
+}
+
+func jsonArg(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return strconv.FormatBool(b), nil
+	}
+	if n, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+		return strconv.FormatInt(n, 10), nil
+	}
+	return "", fmt.Errorf("unsupported argument %s", raw)
+}
+
+func isWhitespaceRune(r rune) bool {
+	return r < 0x80 && isASCIIWhitespace(byte(r))
+}
+
+// FormatJSON encodes a JSON response as a single line.
+func FormatJSON(response JSONResponse) string {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		encoded, _ = json.Marshal(JSONResponse{
+			ID:     response.ID,
+			Status: StatusError,
+			Error:  &JSONError{Code: "error", Message: err.Error()},
+		})
+	}
+	return string(encoded)
+}
+
+// JSONErrorResponse builds a failed JSON response.
+func JSONErrorResponse(id json.RawMessage, code, message string) JSONResponse {
+	return JSONResponse{ID: id, Status: StatusError, Error: &JSONError{Code: code, Message: message}}
+}
@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   Request
+		wantID string
+	}{
+		{
+			name:   "typed args",
+			line:   `{"id":7,"command":"set_pixel","args":[1,2,"red"]}`,
+			want:   Request{Command: "set_pixel", Args: []string{"1", "2", "red"}},
+			wantID: "7",
+		},
+		{
+			name:   "booleans and string ids",
+			line:   `{"id":"a","command":"export","args":["/tmp/my art.png",true]}`,
+			want:   Request{Command: "export", Args: []string{"/tmp/my art.png", "true"}},
+			wantID: `"a"`,
+		},
+		{
+			name: "batch operations become body lines",
+			line: `{"command":"batch","batch":[{"command":"set_pixel","args":[0,0,"red"]},{"command":"export","args":["/tmp/a b.png"]}]}`,
+			want: Request{Command: "batch", Args: []string{"2"}, Body: []string{"set_pixel 0 0 red", `export "/tmp/a b.png"`}},
+		},
+		{
+			name: "batch options follow the count",
+			line: `{"command":"batch","args":["--doc=tiles"],"batch":[{"command":"clear"}]}`,
+			want: Request{Command: "batch", Args: []string{"1", "--doc=tiles"}, Body: []string{"clear"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, id, err := ParseJSON(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+			if string(id) != tt.wantID {
+				t.Fatalf("expected id %q, got %q", tt.wantID, id)
+			}
+		})
+	}
+}
+
+func TestParseJSONErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantCode string
+		wantID   string
+	}{
+		{name: "malformed", line: `{"command":`, wantCode: "invalid_request"},
+		{name: "unknown field", line: `{"command":"clear","color":"red"}`, wantCode: "invalid_request"},
+		{name: "missing command", line: `{"id":1,"args":[1]}`, wantCode: "invalid_command", wantID: "1"},
+		{name: "fractional arg", line: `{"id":2,"command":"set_pixel","args":[1.5,2,"red"]}`, wantCode: "invalid_args", wantID: "2"},
+		{name: "object arg", line: `{"command":"set_pixel","args":[{},2,"red"]}`, wantCode: "invalid_args"},
+		{name: "batch list on another command", line: `{"command":"clear","batch":[{"command":"clear"}]}`, wantCode: "invalid_args"},
+		{name: "batch operation in args", line: `{"command":"batch","args":["clear"],"batch":[{"command":"clear"}]}`, wantCode: "invalid_args"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, id, err := ParseJSON(tt.line)
+			perr, ok := err.(Error)
+			if !ok || perr.Code != tt.wantCode {
+				t.Fatalf("expected %s error, got %v", tt.wantCode, err)
+			}
+			if string(id) != tt.wantID {
+				t.Fatalf("expected id %q, got %q", tt.wantID, id)
+			}
+		})
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	response := JSONErrorResponse(json.RawMessage("3"), "out_of_bounds", "pixel (9,9) outside canvas")
+	want := `{"id":3,"status":"error","error":{"code":"out_of_bounds","message":"pixel (9,9) outside canvas"}}`
+	if got := FormatJSON(response); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	ok := JSONResponse{Status: StatusOK, Result: json.RawMessage(`"#ff0000ff"`)}
+	if got := FormatJSON(ok); got != `{"status":"ok","result":"#ff0000ff"}` {
+		t.Fatalf("unexpected encoding %s", got)
+	}
+}
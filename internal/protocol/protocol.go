@@ -1,11 +1,28 @@
 package protocol
 
-import "strings"
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
 
-// Request represents a parsed protocol request line.
+// Version is the protocol version spoken by this build. It is reported by the
+// hello handshake and bumped whenever a daemon must support something new for
+// clients of this build to work.
+const Version = 1
+
+// MaxBatchLines bounds the number of operations a single batch request may carry.
+const MaxBatchLines = 100000
+
+// Request represents a parsed protocol request line. Body holds the operation
+// lines that follow a batch header.
 type Request struct {
 	Command string
 	Args    []string
+	Body    []string
 }
 
 // Error is a structured protocol error with a code and message.
@@ -21,15 +38,86 @@ func (e Error) Error() string {
 	return e.Code + ": " + e.Message
 }
 
-// ParseLine parses a request line into a command and arguments.
+// ParseLine parses a request line into a command and arguments, split as
+// described by SplitArgs.
 func ParseLine(line string) (Request, error) {
-	fields := fieldsASCII(line)
+	fields, err := SplitArgs(line)
+	if err != nil {
+		return Request{}, err
+	}
 	if len(fields) == 0 {
 		return Request{}, Error{Code: "invalid_command", Message: "command is required"}
 	}
 	return Request{Command: fields[0], Args: fields[1:]}, nil
 }
 
+// FormatLine encodes a command and its arguments as a request line that
+// ParseLine splits back into the same arguments.
+func FormatLine(command string, args ...string) string {
+	var b strings.Builder
+	b.WriteString(command)
+	for _, arg := range args {
+		b.WriteByte(' ')
+		b.WriteString(QuoteArg(arg))
+	}
+	return b.String()
+}
+
+// BatchSize returns the operation count announced by a "batch <n>" header.
+// Any further header arguments are options for the batch command to check.
+func BatchSize(request Request) (int, error) {
+	if len(request.Args) == 0 {
+		return 0, Error{Code: "invalid_args", Message: "batch expects a line count"}
+	}
+	n, err := strconv.Atoi(request.Args[0])
+	if err != nil || n < 0 || n > MaxBatchLines {
+		return 0, Error{Code: "invalid_args", Message: fmt.Sprintf("batch line count must be an integer between 0 and %d", MaxBatchLines)}
+	}
+	return n, nil
+}
+
+// ReadBatchBody reads the operation lines announced by a batch header into the
+// request body. Input that ends early is an invalid_args error; other read
+// errors are returned unchanged.
+func ReadBatchBody(reader *bufio.Reader, request Request) (Request, error) {
+	n, err := BatchSize(request)
+	if err != nil {
+		return request, err
+	}
+	request.Body = make([]string, 0, n)
+	for range n {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return request, err
+		}
+		if err != nil && line == "" {
+			return request, Error{Code: "invalid_args", Message: fmt.Sprintf("batch ended after %d of %d lines", len(request.Body), n)}
+		}
+		request.Body = append(request.Body, strings.TrimRight(line, "\r\n"))
+	}
+	return request, nil
+}
+
+// FormatBatchRequest frames operation lines as a batch request: a "batch <n>"
+// header carrying any options, followed by one line per operation.
+func FormatBatchRequest(operations []string, options ...string) string {
+	header := FormatLine("batch", append([]string{strconv.Itoa(len(operations))}, options...)...)
+	lines := append([]string{header}, operations...)
+	return strings.Join(lines, "\n")
+}
+
+// FormatBatch formats a batch response: the header followed by one result line
+// per operation.
+func FormatBatch(header string, results []string) string {
+	return strings.Join(append([]string{header}, results...), "\n")
+}
+
+// HasBatchResults reports whether a batch response header is followed by
+// per-operation result lines: every "ok" header is, and so is a batch_failed error.
+func HasBatchResults(header string) bool {
+	return header == "ok" || strings.HasPrefix(header, "ok ") || strings.HasPrefix(header, "err batch_failed ")
+}
+
 // FormatOK formats a success response with an optional payload.
 func FormatOK(payload string) string {
 	trimmed := strings.TrimSpace(payload)
@@ -52,24 +140,105 @@ func FormatError(code, message string) string {
 	return "err " + trimmedCode + " " + trimmedMessage
 }
 
-func fieldsASCII(s string) []string {
-	if s == "" {
-		return nil
-	}
-	fields := make([]string, 0, 4)
-	for i := 0; i < len(s); {
-		for i < len(s) && isASCIIWhitespace(s[i]) {
-			i++
+// SplitArgs splits s into arguments at runs of ASCII whitespace. A double
+// quote starts a quoted section, running to the next unescaped double quote,
+// in which whitespace is kept and \", \\, \n, \r and \t are escapes; quoted
+// and unquoted text next to each other join into one argument, and "" is an
+// empty argument. Outside quotes a backslash is an ordinary character.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		fields []string
+		field  strings.Builder
+		inArg  bool
+	)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case isASCIIWhitespace(ch):
+			if inArg {
+				fields = append(fields, field.String())
+				field.Reset()
+				inArg = false
+			}
+		case ch == '"':
+			inArg = true
+			end, err := unquote(s, i+1, &field)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		default:
+			inArg = true
+			field.WriteByte(ch)
 		}
-		start := i
-		for i < len(s) && !isASCIIWhitespace(s[i]) {
+	}
+	if inArg {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// unquote appends the quoted section of s starting at start to field and
+// returns the index of its closing quote.
+func unquote(s string, start int, field *strings.Builder) (int, error) {
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 == len(s) {
+				return 0, Error{Code: "invalid_args", Message: "unterminated quoted argument"}
+			}
 			i++
+			switch s[i] {
+			case '"', '\\':
+				field.WriteByte(s[i])
+			case 'n':
+				field.WriteByte('\n')
+			case 'r':
+				field.WriteByte('\r')
+			case 't':
+				field.WriteByte('\t')
+			default:
+				return 0, Error{Code: "invalid_args", Message: fmt.Sprintf("invalid escape \\%c in quoted argument", s[i])}
+			}
+		default:
+			field.WriteByte(s[i])
 		}
-		if start < i {
-			fields = append(fields, s[start:i])
+	}
+	return 0, Error{Code: "invalid_args", Message: "unterminated quoted argument"}
+}
+
+// QuoteArg returns arg unchanged when SplitArgs would read it back as a single
+// argument, and as a quoted, escaped argument otherwise.
+func QuoteArg(arg string) string {
+	if arg != "" && !strings.ContainsFunc(arg, needsQuoting) {
+		return arg
+	}
+	var b strings.Builder
+	b.Grow(len(arg) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(arg); i++ {
+		switch ch := arg[i]; ch {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(ch)
 		}
 	}
-	return fields
+	b.WriteByte('"')
+	return b.String()
+}
+
+func needsQuoting(r rune) bool {
+	return r == '"' || isWhitespaceRune(r)
 }
 
 func isASCIIWhitespace(b byte) bool {
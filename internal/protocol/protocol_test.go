@@ -1,8 +1,10 @@
 package protocol
 
 import (
+	"bufio"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -48,6 +50,105 @@ func TestParseLineEmpty(t *testing.T) {
 	}
 }
 
+func TestParseLineQuoting(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{name: "quoted path", line: `export "/tmp/my art/out file.png"`, want: []string{"/tmp/my art/out file.png"}},
+		{name: "escapes", line: `text "say \"hi\"\tnow\n" "back\\slash"`, want: []string{"say \"hi\"\tnow\n", `back\slash`}},
+		{name: "empty argument", line: `layer rename "" ink`, want: []string{"rename", "", "ink"}},
+		{name: "adjacent sections join", line: `open /tmp/"my art".pxp`, want: []string{"/tmp/my art.pxp"}},
+		{name: "quoted option", line: `get_pixel 1 2 "--layer=line art"`, want: []string{"1", "2", "--layer=line art"}},
+		{name: "backslash outside quotes is literal", line: `open C:\art\a.pxp`, want: []string{`C:\art\a.pxp`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := ParseLine(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(request.Args, tt.want) {
+				t.Fatalf("expected args %q, got %q", tt.want, request.Args)
+			}
+		})
+	}
+}
+
+func TestParseLineQuotingErrors(t *testing.T) {
+	for _, line := range []string{`export "/tmp/a b.png`, `export "a\`, `export "a\qb"`} {
+		_, err := ParseLine(line)
+		var perr Error
+		if !errors.As(err, &perr) || perr.Code != "invalid_args" {
+			t.Fatalf("%q: expected invalid_args, got %v", line, err)
+		}
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"1", "2", "#ff0000"}, want: "set_pixel 1 2 #ff0000"},
+		{args: []string{"/tmp/my art.png"}, want: `set_pixel "/tmp/my art.png"`},
+		{args: []string{"", `a"b`, "c\\d", "line\nbreak"}, want: `set_pixel "" "a\"b" c\d "line\nbreak"`},
+	}
+	for _, tt := range tests {
+		if got := FormatLine("set_pixel", tt.args...); got != tt.want {
+			t.Fatalf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func FuzzParseLine(f *testing.F) {
+	for _, seed := range []string{
+		"set_pixel 1 2 red",
+		`export "/tmp/my art.png"`,
+		`text "a \"b\" \\ \n"`,
+		`x "" y"z"`,
+		`bad "unterminated`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		request, err := ParseLine(line)
+		if err != nil {
+			return
+		}
+		// Re-encoding a parsed line must parse back to the same request.
+		again, err := ParseLine(FormatLine(QuoteArg(request.Command), request.Args...))
+		if err != nil {
+			t.Fatalf("re-encoded %q failed to parse: %v", line, err)
+		}
+		if again.Command != request.Command || !reflect.DeepEqual(again.Args, request.Args) {
+			t.Fatalf("round trip of %q: got %q %q, want %q %q", line, again.Command, again.Args, request.Command, request.Args)
+		}
+	})
+}
+
+func FuzzFormatLine(f *testing.F) {
+	f.Add("1", "/tmp/my art.png", "")
+	f.Add(`a"b`, "c\\d", "\t\r\n")
+	f.Add("--layer=line art", "\v\f", `\"`)
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		args := []string{a, b, c}
+		line := FormatLine("cmd", args...)
+		if strings.ContainsAny(line, "\r\n") {
+			t.Fatalf("encoded line %q contains a line break", line)
+		}
+		request, err := ParseLine(line)
+		if err != nil {
+			t.Fatalf("encoded line %q failed to parse: %v", line, err)
+		}
+		if request.Command != "cmd" || !reflect.DeepEqual(request.Args, args) {
+			t.Fatalf("round trip of %q: got %q", args, request.Args)
+		}
+	})
+}
+
 func TestFormatOK(t *testing.T) {
 	if got := FormatOK(""); got != "ok" {
 		t.Fatalf("expected ok, got %q", got)
@@ -63,3 +164,58 @@ func TestFormatError(t *testing.T) {
 		t.Fatalf("expected err invalid_command bad request, got %q", got)
 	}
 }
+
+func TestReadBatchBody(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("set_pixel 0 0 red\r\nclear\nleftover\n"))
+	request, err := ReadBatchBody(reader, Request{Command: "batch", Args: []string{"2", "--doc=tiles"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"set_pixel 0 0 red", "clear"}
+	if !reflect.DeepEqual(request.Body, want) {
+		t.Fatalf("expected body %v, got %v", want, request.Body)
+	}
+}
+
+func TestReadBatchBodyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{name: "missing count", input: "clear\n"},
+		{name: "negative count", args: []string{"-1"}, input: ""},
+		{name: "count too large", args: []string{"100001"}, input: ""},
+		{name: "truncated body", args: []string{"3"}, input: "clear\nclear\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadBatchBody(bufio.NewReader(strings.NewReader(tt.input)), Request{Command: "batch", Args: tt.args})
+			var perr Error
+			if !errors.As(err, &perr) || perr.Code != "invalid_args" {
+				t.Fatalf("expected invalid_args, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFormatBatch(t *testing.T) {
+	if got := FormatBatchRequest([]string{"clear", "undo"}); got != "batch 2\nclear\nundo" {
+		t.Fatalf("unexpected batch request %q", got)
+	}
+	if got := FormatBatchRequest([]string{"clear"}, "--doc=my tiles"); got != "batch 1 \"--doc=my tiles\"\nclear" {
+		t.Fatalf("unexpected batch request with options %q", got)
+	}
+	if got := FormatBatch("ok 2", []string{"ok", "ok #ff0000ff"}); got != "ok 2\nok\nok #ff0000ff" {
+		t.Fatalf("unexpected batch response %q", got)
+	}
+	for header, want := range map[string]bool{
+		"ok 2":                           true,
+		"err batch_failed line 1 failed": true,
+		"err invalid_args bad count":     false,
+	} {
+		if got := HasBatchResults(header); got != want {
+			t.Fatalf("HasBatchResults(%q) = %t, want %t", header, got, want)
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // TempDir returns a temporary directory with a short path on macOS.
-func TempDir(t *testing.T) string {
+func TempDir(t testing.TB) string {
 	t.Helper()
 	if runtime.GOOS == "darwin" {
 		dir, err := os.MkdirTemp("/tmp", "pxcli-test-")